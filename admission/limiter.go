@@ -0,0 +1,112 @@
+// Package admission bounds requests doing DB work concurrently by a limit adapted to their latency,
+// so when the database slows down excess requests are rejected at once instead of piling up on
+// the sql.DB pool and making every request late
+package admission
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"tomshop/metrics"
+)
+
+const (
+	defaultInitialLimit   = 20
+	defaultMinLimit       = 2
+	defaultMaxLimit       = 500
+	defaultBackoff        = 0.9
+	defaultSheddableShare = 0.5
+)
+
+// Priority of a class of requests, Sheddable ones are rejected first
+type Priority int
+
+const (
+	// Critical requests, e.g. orders, may use the whole limit
+	Critical Priority = iota
+	// Sheddable requests, e.g. reads, may use SheddableShare of the limit only
+	Sheddable
+)
+
+// Limiter adapts its limit by AIMD: a request slower than Target or overloaded cuts the limit by
+// Backoff, others raise it by one per limit requests while at least half of it is used
+type Limiter struct {
+	// Target latency of requests
+	Target time.Duration
+	// MinLimit and MaxLimit bound the limit, default 2 and 500
+	MinLimit int
+	MaxLimit int
+	// Backoff multiplies the limit on slow or overloaded requests, default 0.9
+	Backoff float64
+	// SheddableShare of the limit Sheddable requests may use, default 0.5
+	SheddableShare float64
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	now      func() time.Time
+}
+
+// NewLimiter for target latency starting at a limit of 20 requests
+func NewLimiter(target time.Duration) *Limiter {
+	return &Limiter{
+		Target:         target,
+		MinLimit:       defaultMinLimit,
+		MaxLimit:       defaultMaxLimit,
+		Backoff:        defaultBackoff,
+		SheddableShare: defaultSheddableShare,
+		limit:          defaultInitialLimit,
+		now:            time.Now,
+	}
+}
+
+// Acquire admits a request of priority p if in flight requests are under its share of the limit,
+// done must be called when the request finishes with whether it failed by overload, e.g. timed out
+func (l *Limiter) Acquire(p Priority) (done func(overloaded bool), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := int(l.limit)
+	if p == Sheddable {
+		// at least one so sheddable requests still probe the latency when the limit is small
+		limit = int(math.Max(1, math.Floor(l.limit*l.SheddableShare)))
+	}
+
+	if l.inFlight >= limit {
+		return nil, false
+	}
+
+	l.inFlight++
+	metrics.AdmissionInFlight.Set(int64(l.inFlight))
+	start := l.now()
+
+	return func(overloaded bool) {
+		l.release(l.now().Sub(start), overloaded)
+	}, true
+}
+
+// Limit currently adapted
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+func (l *Limiter) release(latency time.Duration, overloaded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case overloaded || latency > l.Target:
+		l.limit = math.Max(float64(l.MinLimit), l.limit*l.Backoff)
+	case float64(l.inFlight) >= l.limit/2:
+		// an idle server doesn't tell whether it can take more
+		l.limit = math.Min(float64(l.MaxLimit), l.limit+1/l.limit)
+	}
+
+	l.inFlight--
+	metrics.AdmissionInFlight.Set(int64(l.inFlight))
+	metrics.AdmissionLimit.Set(int64(l.limit))
+}
@@ -0,0 +1,99 @@
+package admission
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestLimiter at limit with a clock moved by tests
+func newTestLimiter(limit float64) (*Limiter, *time.Time) {
+	now := time.Date(2019, 5, 6, 10, 0, 0, 0, time.UTC)
+	l := NewLimiter(100 * time.Millisecond)
+	l.limit = limit
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	t.Run("expecting requests over the limit rejected until one is done", func(tt *testing.T) {
+		l, _ := newTestLimiter(2)
+		done, _ := l.Acquire(Critical)
+		l.Acquire(Critical)
+		if _, ok := l.Acquire(Critical); ok {
+			tt.Error("expecting third request rejected")
+		}
+
+		done(false)
+		if _, ok := l.Acquire(Critical); !ok {
+			tt.Error("expecting request admitted after one is done")
+		}
+	})
+
+	t.Run("expecting sheddable requests rejected at their share of the limit", func(tt *testing.T) {
+		l, _ := newTestLimiter(4)
+		l.Acquire(Sheddable)
+		l.Acquire(Sheddable)
+		if _, ok := l.Acquire(Sheddable); ok {
+			tt.Error("expecting sheddable request rejected")
+		}
+
+		if _, ok := l.Acquire(Critical); !ok {
+			tt.Error("expecting critical request admitted")
+		}
+	})
+
+	t.Run("expecting a sheddable request admitted at the smallest limit", func(tt *testing.T) {
+		l, _ := newTestLimiter(1)
+		if _, ok := l.Acquire(Sheddable); !ok {
+			tt.Error("expecting sheddable request admitted")
+		}
+	})
+}
+
+func TestLimiter_Limit(t *testing.T) {
+	t.Run("expecting limit cut by slow request", func(tt *testing.T) {
+		l, now := newTestLimiter(20)
+		done, _ := l.Acquire(Critical)
+		*now = now.Add(time.Second)
+		done(false)
+		if l.Limit() != 18 {
+			tt.Error("expecting limit 18, got", l.Limit())
+		}
+	})
+
+	t.Run("expecting limit cut by overloaded request", func(tt *testing.T) {
+		l, _ := newTestLimiter(20)
+		done, _ := l.Acquire(Critical)
+		done(true)
+		if l.Limit() != 18 {
+			tt.Error("expecting limit 18, got", l.Limit())
+		}
+	})
+
+	t.Run("expecting limit not cut below minimum", func(tt *testing.T) {
+		l, _ := newTestLimiter(2)
+		done, _ := l.Acquire(Critical)
+		done(true)
+		if l.Limit() != 2 {
+			tt.Error("expecting limit 2, got", l.Limit())
+		}
+	})
+
+	t.Run("expecting limit raised by fast requests while in use", func(tt *testing.T) {
+		l, _ := newTestLimiter(2)
+		done, _ := l.Acquire(Critical)
+		done(false)
+		if l.limit != 2.5 {
+			tt.Error("expecting limit 2.5, got", l.limit)
+		}
+	})
+
+	t.Run("expecting limit kept by fast requests when mostly idle", func(tt *testing.T) {
+		l, _ := newTestLimiter(20)
+		done, _ := l.Acquire(Critical)
+		done(false)
+		if l.limit != 20 {
+			tt.Error("expecting limit 20, got", l.limit)
+		}
+	})
+}
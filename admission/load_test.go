@@ -0,0 +1,114 @@
+package admission
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// loadParallelism goroutines per CPU send requests back to back
+	loadParallelism = 32
+	// simulatedConns of the database, requests beyond them wait as they do on the sql.DB pool
+	simulatedConns = 8
+	// simulatedQuery is how long a request holds a connection
+	simulatedQuery = 2 * time.Millisecond
+)
+
+// saturatedDB serves simulatedConns requests at once, the others queue
+type saturatedDB struct {
+	conns chan struct{}
+}
+
+func (db *saturatedDB) query() {
+	db.conns <- struct{}{}
+	time.Sleep(simulatedQuery)
+	<-db.conns
+}
+
+// latencies of admitted requests and count of rejected ones by priority
+type latencies struct {
+	mu       sync.Mutex
+	admitted map[Priority][]time.Duration
+	rejected map[Priority]int
+}
+
+func (l *latencies) add(p Priority, d time.Duration, admitted bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !admitted {
+		l.rejected[p]++
+		return
+	}
+	l.admitted[p] = append(l.admitted[p], d)
+}
+
+func (l *latencies) report(b *testing.B) {
+	for p, name := range map[Priority]string{Critical: "order", Sheddable: "read"} {
+		ds := l.admitted[p]
+		if len(ds) == 0 {
+			continue
+		}
+		sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+		b.ReportMetric(float64(ds[len(ds)*99/100].Microseconds())/1000, name+"-p99-ms")
+		b.ReportMetric(float64(l.rejected[p])*100/float64(len(ds)+l.rejected[p]), name+"-%rejected")
+	}
+}
+
+// loadSaturatedDB sends one order for every three reads, limited by l if not nil
+func loadSaturatedDB(b *testing.B, l *Limiter) {
+	db := &saturatedDB{conns: make(chan struct{}, simulatedConns)}
+	lat := &latencies{admitted: make(map[Priority][]time.Duration), rejected: make(map[Priority]int)}
+	var mu sync.Mutex
+	n := 0
+
+	b.SetParallelism(loadParallelism)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			p := Sheddable
+			if n%4 == 0 {
+				p = Critical
+			}
+			n++
+			mu.Unlock()
+
+			start := time.Now()
+			if l == nil {
+				db.query()
+				lat.add(p, time.Since(start), true)
+				continue
+			}
+
+			done, ok := l.Acquire(p)
+			if ok {
+				db.query()
+				done(false)
+			} else {
+				// rejected clients back off as gRPC clients retrying Unavailable do
+				time.Sleep(simulatedQuery)
+			}
+			lat.add(p, time.Since(start), ok)
+		}
+	})
+	b.StopTimer()
+
+	lat.report(b)
+}
+
+// BenchmarkSaturatedDB_Simulated shows the tail latency of requests when far more are sent than the
+// database serves, compare order-p99-ms and read-p99-ms of unlimited and limited. Reads are shed first
+func BenchmarkSaturatedDB_Simulated(b *testing.B) {
+	b.Run("unlimited", func(b *testing.B) {
+		loadSaturatedDB(b, nil)
+	})
+
+	b.Run("limited", func(b *testing.B) {
+		l := NewLimiter(2 * simulatedQuery)
+		loadSaturatedDB(b, l)
+		b.Logf("limit adapted to %d", l.Limit())
+	})
+}
@@ -0,0 +1,149 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"tomshop/repositories"
+)
+
+var testAlert = repositories.LowStock{ProductID: 1, Threshold: 5, StockCount: 4}
+
+func TestWebhookNotifier(t *testing.T) {
+	t.Run("expecting alert posted as JSON", postAlertToWebhook)
+	t.Run("expecting error when webhook fails", errorWhenWebhookFails)
+}
+
+func postAlertToWebhook(t *testing.T) {
+	var got repositories.LowStock
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error("unexpected body", err)
+		}
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL}
+	if err := n.Notify(context.Background(), testAlert); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if got != testAlert {
+		t.Error("expecting alert posted, got", got)
+	}
+}
+
+func errorWhenWebhookFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := &WebhookNotifier{URL: server.URL}
+	if err := n.Notify(context.Background(), testAlert); err == nil {
+		t.Error("expecting error")
+	}
+}
+
+func TestStreamNotifier(t *testing.T) {
+	s := &StreamNotifier{BufferSize: 1}
+	alerts, cancel := s.Subscribe()
+
+	// second alert is dropped instead of blocking
+	s.Notify(context.Background(), testAlert)
+	s.Notify(context.Background(), repositories.LowStock{ProductID: 2})
+
+	if got := <-alerts; got != testAlert {
+		t.Error("expecting first alert, got", got)
+	}
+
+	select {
+	case got := <-alerts:
+		t.Error("expecting second alert dropped, got", got)
+	default:
+	}
+
+	cancel()
+	s.Notify(context.Background(), testAlert)
+	select {
+	case got := <-alerts:
+		t.Error("expecting nothing after cancel, got", got)
+	default:
+	}
+}
+
+func TestChecker(t *testing.T) {
+	t.Run("expecting products checked", checkProducts)
+	t.Run("expecting low stock events notified", notifyLowStockEvents)
+	t.Run("expecting failed notification published again", failedNotificationPublishedAgain)
+}
+
+func checkProducts(t *testing.T) {
+	var checked []int64
+	c := &Checker{
+		Repo: mockRepo(func(_ context.Context, ids []int64) ([]repositories.LowStock, error) {
+			checked = ids
+			return nil, fmt.Errorf("dummy error")
+		}),
+	}
+	c.Check(context.Background(), 1, 2)
+
+	if !reflect.DeepEqual(checked, []int64{1, 2}) {
+		t.Error("expecting products checked, got", checked)
+	}
+
+	var nilChecker *Checker
+	nilChecker.Check(context.Background(), 1)
+}
+
+func notifyLowStockEvents(t *testing.T) {
+	notifier := &StreamNotifier{}
+	alerts, cancel := notifier.Subscribe()
+	defer cancel()
+
+	c := &Checker{Notifier: notifier}
+	payload, _ := json.Marshal(testAlert)
+	events := []repositories.OutboxEvent{
+		{Type: repositories.EventStockChanged, Payload: []byte(`{"productID":2}`)},
+		{Type: repositories.EventStockLow, Payload: payload},
+	}
+	for _, e := range events {
+		if err := c.Publish(context.Background(), e); err != nil {
+			t.Error("unexpected error", err)
+		}
+	}
+
+	if got := <-alerts; got != testAlert {
+		t.Error("expecting low stock event notified, got", got)
+	}
+
+	select {
+	case got := <-alerts:
+		t.Error("expecting other events ignored, got", got)
+	default:
+	}
+}
+
+func failedNotificationPublishedAgain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := &Checker{Notifier: &WebhookNotifier{URL: server.URL}}
+	payload, _ := json.Marshal(testAlert)
+	if err := c.Publish(context.Background(), repositories.OutboxEvent{Type: repositories.EventStockLow, Payload: payload}); err == nil {
+		t.Error("expecting error so the relay publishes again")
+	}
+}
+
+type mockRepo func(context.Context, []int64) ([]repositories.LowStock, error)
+
+func (m mockRepo) CheckStockThresholds(ctx context.Context, ids []int64) ([]repositories.LowStock, error) {
+	return m(ctx, ids)
+}
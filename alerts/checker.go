@@ -0,0 +1,48 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+
+	"tomshop/ctxlog"
+	"tomshop/repositories"
+)
+
+// Checker alerts products crossed below their threshold. Crossings are claimed and written to the outbox
+// in the transaction changing stock, thresholds just set are checked by Check. The outbox relay delivers
+// them to Publish at least once, so an alert isn't lost once its crossing is claimed
+type Checker struct {
+	Repo interface {
+		CheckStockThresholds(context.Context, []int64) ([]repositories.LowStock, error)
+	}
+	Notifier Notifier
+}
+
+// Check thresholds of products, it is safe to call on nil Checker.
+// A failed check is retried by the next check of the product.
+func (c *Checker) Check(ctx context.Context, productIDs ...int64) {
+	if c == nil || len(productIDs) == 0 {
+		return
+	}
+
+	if _, err := c.Repo.CheckStockThresholds(ctx, productIDs); err != nil {
+		ctxlog.Println(ctx, "cannot check stock thresholds:", err)
+	}
+}
+
+// Publish low stock events to Notifier, other events are ignored. It implements outbox.Publisher,
+// an error leaves the event to be published again
+func (c *Checker) Publish(ctx context.Context, e repositories.OutboxEvent) error {
+	if e.Type != repositories.EventStockLow {
+		return nil
+	}
+
+	var l repositories.LowStock
+	if err := json.Unmarshal(e.Payload, &l); err != nil {
+		// a malformed event would block its product forever
+		ctxlog.Println(ctx, "dropping malformed low stock event", e.ID, err)
+		return nil
+	}
+
+	return c.Notifier.Notify(ctx, l)
+}
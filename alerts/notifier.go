@@ -0,0 +1,127 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"tomshop/ctxlog"
+	"tomshop/repositories"
+)
+
+// Notifier tells someone a product has just crossed below its threshold
+type Notifier interface {
+	Notify(context.Context, repositories.LowStock) error
+}
+
+// Notifiers notify every Notifier, returns the first error
+type Notifiers []Notifier
+
+// Notify all
+func (ns Notifiers) Notify(ctx context.Context, l repositories.LowStock) error {
+	var first error
+	for _, n := range ns {
+		if err := n.Notify(ctx, l); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// LogNotifier writes alerts to standard logger
+type LogNotifier struct{}
+
+// Notify by logging
+func (LogNotifier) Notify(ctx context.Context, l repositories.LowStock) error {
+	ctxlog.Printf(ctx, "low stock: product %d has %d items, threshold %d", l.ProductID, l.StockCount, l.Threshold)
+	return nil
+}
+
+// WebhookNotifier posts alerts as JSON to URL
+type WebhookNotifier struct {
+	URL string
+	// Client default http.DefaultClient
+	Client *http.Client
+}
+
+// Notify by posting to webhook, non 2xx status is an error
+func (w *WebhookNotifier) Notify(ctx context.Context, l repositories.LowStock) error {
+	body, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// StreamNotifier fans alerts out to subscribers such as stream RPCs.
+// Alerts are dropped for a subscriber whose buffer is full, Notify never blocks
+type StreamNotifier struct {
+	// BufferSize per subscriber, default 16
+	BufferSize int
+
+	mu   sync.Mutex
+	subs map[chan repositories.LowStock]struct{}
+}
+
+// Subscribe to alerts until cancel is called
+func (s *StreamNotifier) Subscribe() (alerts <-chan repositories.LowStock, cancel func()) {
+	size := s.BufferSize
+	if size <= 0 {
+		size = 16
+	}
+	ch := make(chan repositories.LowStock, size)
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[chan repositories.LowStock]struct{})
+	}
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// Notify subscribers
+func (s *StreamNotifier) Notify(ctx context.Context, l repositories.LowStock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- l:
+		default:
+			ctxlog.Println(ctx, "low stock alert dropped for slow subscriber, product", l.ProductID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package allocation
+
+import (
+	"fmt"
+	"sort"
+
+	"tomshop/repositories"
+)
+
+// NotEnoughStockError when warehouses together cannot fulfil the order line of ProductID
+type NotEnoughStockError struct {
+	ProductID int64
+}
+
+func (e NotEnoughStockError) Error() string {
+	return fmt.Sprintf("not enough stock of product %d in warehouses", e.ProductID)
+}
+
+// Hint from the order request
+type Hint struct {
+	PreferredWarehouseID int64
+	Region               string
+}
+
+// Strategy fills Allocations of every order using stocks, orders must not be modified.
+// It fails with NotEnoughStockError of the first order cannot be allocated
+type Strategy interface {
+	Allocate(orders []repositories.Order, stocks []repositories.WarehouseStock, hint Hint) ([]repositories.Order, error)
+}
+
+// PreferredWarehouse ships from a single warehouse if possible, other warehouses only fill what it lacks.
+// WarehouseID is used when the request doesn't prefer any
+type PreferredWarehouse struct {
+	WarehouseID int64
+}
+
+// Allocate implements Strategy
+func (p PreferredWarehouse) Allocate(orders []repositories.Order, stocks []repositories.WarehouseStock, hint Hint) ([]repositories.Order, error) {
+	preferred := hint.PreferredWarehouseID
+	if preferred == 0 {
+		preferred = p.WarehouseID
+	}
+
+	return allocateInOrder(orders, stocks, func(warehouses []int64, _ map[int64]string) {
+		sort.SliceStable(warehouses, func(i, j int) bool {
+			return warehouses[i] == preferred && warehouses[j] != preferred
+		})
+	})
+}
+
+// NearestRegion ships from warehouses in the hinted region first, then from Neighbours of the region
+// in the listed order, then from the rest
+type NearestRegion struct {
+	Neighbours map[string][]string
+}
+
+// Allocate implements Strategy
+func (n NearestRegion) Allocate(orders []repositories.Order, stocks []repositories.WarehouseStock, hint Hint) ([]repositories.Order, error) {
+	rank := map[string]int{hint.Region: 0}
+	for i, region := range n.Neighbours[hint.Region] {
+		if _, ok := rank[region]; !ok {
+			rank[region] = i + 1
+		}
+	}
+
+	rankOf := func(region string) int {
+		if r, ok := rank[region]; ok {
+			return r
+		}
+		return len(rank) + 1
+	}
+
+	return allocateInOrder(orders, stocks, func(warehouses []int64, regions map[int64]string) {
+		sort.SliceStable(warehouses, func(i, j int) bool {
+			return rankOf(regions[warehouses[i]]) < rankOf(regions[warehouses[j]])
+		})
+	})
+}
+
+// FewestShipments picks warehouses which can ship most of the order lines completely, lines no warehouse
+// can ship alone are split starting from the warehouse has most stock
+type FewestShipments struct{}
+
+// Allocate implements Strategy
+func (FewestShipments) Allocate(orders []repositories.Order, stocks []repositories.WarehouseStock, hint Hint) ([]repositories.Order, error) {
+	available := stockMap(stocks)
+	warehouses := warehouseIDs(stocks)
+	result := copyOrders(orders)
+
+	remaining := make(map[int]bool, len(result))
+	for i := range result {
+		// fully backordered lines take nothing, every warehouse would cover them
+		if result[i].Quantity == 0 {
+			result[i].Allocations = nil
+			continue
+		}
+		remaining[i] = true
+	}
+
+	for len(remaining) > 0 {
+		best, bestCovered := int64(0), []int{}
+		for _, w := range warehouses {
+			covered := []int{}
+			for i := range result {
+				if remaining[i] && available[key{result[i].ProductID, w}] >= result[i].Quantity {
+					covered = append(covered, i)
+				}
+			}
+
+			if len(covered) > len(bestCovered) {
+				best, bestCovered = w, covered
+			}
+		}
+
+		if len(bestCovered) == 0 {
+			break
+		}
+
+		for _, i := range bestCovered {
+			o := &result[i]
+			o.Allocations = []repositories.Allocation{{ProductID: o.ProductID, WarehouseID: best, Quantity: o.Quantity}}
+			available[key{o.ProductID, best}] -= o.Quantity
+			delete(remaining, i)
+		}
+	}
+
+	for i := range result {
+		if !remaining[i] {
+			continue
+		}
+
+		candidates := append([]int64(nil), warehouses...)
+		productID := result[i].ProductID
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return available[key{productID, candidates[a]}] > available[key{productID, candidates[b]}]
+		})
+		if err := fill(&result[i], candidates, available); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+type key struct {
+	productID   int64
+	warehouseID int64
+}
+
+// allocateInOrder fills every line from warehouses ordered by sortFn
+func allocateInOrder(
+	orders []repositories.Order,
+	stocks []repositories.WarehouseStock,
+	sortFn func(warehouses []int64, regions map[int64]string),
+) ([]repositories.Order, error) {
+	available := stockMap(stocks)
+	warehouses := warehouseIDs(stocks)
+	regions := make(map[int64]string, len(warehouses))
+	for _, s := range stocks {
+		regions[s.WarehouseID] = s.Region
+	}
+	sortFn(warehouses, regions)
+
+	result := copyOrders(orders)
+	for i := range result {
+		if err := fill(&result[i], warehouses, available); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// fill allocates the whole order quantity from warehouses in order and takes allocated items out of available
+func fill(o *repositories.Order, warehouses []int64, available map[key]int64) error {
+	o.Allocations = nil
+	need := o.Quantity
+	for _, w := range warehouses {
+		if need == 0 {
+			break
+		}
+
+		k := key{o.ProductID, w}
+		qty := available[k]
+		if qty <= 0 {
+			continue
+		}
+
+		if qty > need {
+			qty = need
+		}
+		o.Allocations = append(o.Allocations, repositories.Allocation{
+			ProductID:   o.ProductID,
+			WarehouseID: w,
+			Quantity:    qty,
+		})
+		available[k] -= qty
+		need -= qty
+	}
+
+	if need > 0 {
+		return NotEnoughStockError{ProductID: o.ProductID}
+	}
+
+	return nil
+}
+
+func stockMap(stocks []repositories.WarehouseStock) map[key]int64 {
+	available := make(map[key]int64, len(stocks))
+	for _, s := range stocks {
+		available[key{s.ProductID, s.WarehouseID}] += s.StockCount
+	}
+
+	return available
+}
+
+// warehouseIDs sorted ascending
+func warehouseIDs(stocks []repositories.WarehouseStock) []int64 {
+	seen := map[int64]bool{}
+	ids := []int64{}
+	for _, s := range stocks {
+		if !seen[s.WarehouseID] {
+			seen[s.WarehouseID] = true
+			ids = append(ids, s.WarehouseID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+func copyOrders(orders []repositories.Order) []repositories.Order {
+	result := make([]repositories.Order, len(orders))
+	copy(result, orders)
+
+	return result
+}
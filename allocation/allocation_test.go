@@ -0,0 +1,131 @@
+package allocation
+
+import (
+	"reflect"
+	"testing"
+
+	"tomshop/repositories"
+)
+
+var testStocks = []repositories.WarehouseStock{
+	{ProductID: 1, WarehouseID: 1, Region: "eu", StockCount: 5},
+	{ProductID: 2, WarehouseID: 1, Region: "eu", StockCount: 1},
+	{ProductID: 1, WarehouseID: 2, Region: "us", StockCount: 2},
+	{ProductID: 2, WarehouseID: 2, Region: "us", StockCount: 3},
+	{ProductID: 1, WarehouseID: 3, Region: "ap", StockCount: 4},
+}
+
+var testOrders = []repositories.Order{
+	{ProductID: 1, Quantity: 2},
+	{ProductID: 2, Quantity: 2},
+}
+
+func TestStrategies(t *testing.T) {
+	cases := []struct {
+		name     string
+		strategy Strategy
+		hint     Hint
+		expected [][]repositories.Allocation
+	}{
+		{
+			name:     "preferred warehouse from config",
+			strategy: PreferredWarehouse{WarehouseID: 2},
+			expected: [][]repositories.Allocation{
+				{{ProductID: 1, WarehouseID: 2, Quantity: 2}},
+				{{ProductID: 2, WarehouseID: 2, Quantity: 2}},
+			},
+		},
+		{
+			name:     "preferred warehouse from hint fallback to others",
+			strategy: PreferredWarehouse{WarehouseID: 2},
+			hint:     Hint{PreferredWarehouseID: 1},
+			expected: [][]repositories.Allocation{
+				{{ProductID: 1, WarehouseID: 1, Quantity: 2}},
+				{{ProductID: 2, WarehouseID: 1, Quantity: 1}, {ProductID: 2, WarehouseID: 2, Quantity: 1}},
+			},
+		},
+		{
+			name:     "fewest shipments",
+			strategy: FewestShipments{},
+			expected: [][]repositories.Allocation{
+				{{ProductID: 1, WarehouseID: 2, Quantity: 2}},
+				{{ProductID: 2, WarehouseID: 2, Quantity: 2}},
+			},
+		},
+		{
+			name:     "nearest region",
+			strategy: NearestRegion{Neighbours: map[string][]string{"ap": {"us", "eu"}}},
+			hint:     Hint{Region: "ap"},
+			expected: [][]repositories.Allocation{
+				{{ProductID: 1, WarehouseID: 3, Quantity: 2}},
+				{{ProductID: 2, WarehouseID: 2, Quantity: 2}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		result, err := c.strategy.Allocate(testOrders, testStocks, c.hint)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+
+		for i := range result {
+			if !reflect.DeepEqual(result[i].Allocations, c.expected[i]) {
+				t.Errorf("%s: expecting allocations %v for line %d, got %v", c.name, c.expected[i], i, result[i].Allocations)
+			}
+		}
+
+		if testOrders[0].Allocations != nil {
+			t.Errorf("%s: input orders must not be modified", c.name)
+		}
+	}
+}
+
+func TestFewestShipmentsSplitLines(t *testing.T) {
+	result, err := FewestShipments{}.Allocate([]repositories.Order{
+		{ProductID: 1, Quantity: 10},
+		{ProductID: 2, Quantity: 1},
+	}, testStocks, Hint{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := []repositories.Allocation{
+		{ProductID: 1, WarehouseID: 1, Quantity: 5},
+		{ProductID: 1, WarehouseID: 3, Quantity: 4},
+		{ProductID: 1, WarehouseID: 2, Quantity: 1},
+	}
+	if !reflect.DeepEqual(result[0].Allocations, expected) {
+		t.Error("expecting line split by largest stock first, got", result[0].Allocations)
+	}
+}
+
+func TestZeroQuantityLines(t *testing.T) {
+	strategies := []Strategy{PreferredWarehouse{}, FewestShipments{}, NearestRegion{}}
+	for _, s := range strategies {
+		// product 3 is backordered and has no stock in any warehouse
+		result, err := s.Allocate([]repositories.Order{
+			{ProductID: 1, Quantity: 2},
+			{ProductID: 3, Backordered: 2},
+		}, testStocks, Hint{})
+		if err != nil {
+			t.Errorf("%T: unexpected error %v", s, err)
+			continue
+		}
+
+		if len(result[0].Allocations) == 0 || result[1].Allocations != nil {
+			t.Errorf("%T: expecting no allocation of backordered line, got %v", s, result[1].Allocations)
+		}
+	}
+}
+
+func TestNotEnoughStock(t *testing.T) {
+	strategies := []Strategy{PreferredWarehouse{}, FewestShipments{}, NearestRegion{}}
+	for _, s := range strategies {
+		_, err := s.Allocate([]repositories.Order{{ProductID: 2, Quantity: 5}}, testStocks, Hint{})
+		if err != (NotEnoughStockError{ProductID: 2}) {
+			t.Errorf("%T: expecting NotEnoughStockError of product 2, got %v", s, err)
+		}
+	}
+}
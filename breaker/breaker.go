@@ -0,0 +1,185 @@
+// Package breaker stops calling the database while it is down, so requests fail at once instead of
+// every one of them waiting for the driver timeout
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tomshop/metrics"
+	"tomshop/repositories"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultSuccessThreshold = 1
+	defaultOpenTimeout      = 5 * time.Second
+)
+
+// State of a Breaker
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open refuses every call until OpenTimeout passed
+	Open
+	// HalfOpen lets one trial call through at a time, the others are refused
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Breaker opens after FailureThreshold consecutive calls failed by the database being unavailable,
+// errors of requests, e.g. not enough stock, tell the database works and are counted as successes
+type Breaker struct {
+	// FailureThreshold consecutive failures open the breaker, default 5
+	FailureThreshold int
+	// SuccessThreshold consecutive successful trial calls close the breaker, default 1
+	SuccessThreshold int
+	// OpenTimeout before trial calls are let through, default 5s
+	OpenTimeout time.Duration
+	// Unavailable tells errors of the database being down, default every error but those of
+	// requests in repositories/errors.go
+	Unavailable func(error) bool
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	openedAt  time.Time
+	trialing  bool
+	// generation changes with the state, calls finishing in another generation than they started are ignored
+	generation int
+	now        func() time.Time
+}
+
+// NewBreaker closed
+func NewBreaker() *Breaker {
+	metrics.BreakerState.Set(Closed.String())
+	return &Breaker{
+		FailureThreshold: defaultFailureThreshold,
+		SuccessThreshold: defaultSuccessThreshold,
+		OpenTimeout:      defaultOpenTimeout,
+		Unavailable:      unavailable,
+		now:              time.Now,
+	}
+}
+
+type openError struct {
+	retryAfter time.Duration
+}
+
+func (e openError) Error() string {
+	return fmt.Sprintf("circuit breaker open, retry after %s", e.retryAfter)
+}
+
+func (e openError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// Allow a call or refuse it with a repositories.UnavailableError. done must be called with the
+// error of an allowed call, errors of done contexts are counted neither as failures nor successes
+func (b *Breaker) Allow() (done func(error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open {
+		if wait := b.openedAt.Add(b.OpenTimeout).Sub(b.now()); wait > 0 {
+			metrics.BreakerRejected.Add(1)
+			return nil, openError{retryAfter: wait}
+		}
+		b.transition(HalfOpen)
+	}
+
+	if b.state == HalfOpen {
+		if b.trialing {
+			metrics.BreakerRejected.Add(1)
+			return nil, openError{retryAfter: b.OpenTimeout}
+		}
+		b.trialing = true
+	}
+
+	generation := b.generation
+	return func(err error) {
+		b.finish(generation, err)
+	}, nil
+}
+
+// State of the breaker, a nil Breaker is always Closed
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) finish(generation int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	if b.state == HalfOpen {
+		b.trialing = false
+	}
+
+	switch {
+	case err == context.Canceled || err == context.DeadlineExceeded:
+		// the caller gave up, that tells nothing about the database
+	case err != nil && b.Unavailable(err):
+		b.failures++
+		b.successes = 0
+		if b.state == HalfOpen || b.failures >= b.FailureThreshold {
+			b.transition(Open)
+		}
+	default:
+		b.failures = 0
+		b.successes++
+		if b.state == HalfOpen && b.successes >= b.SuccessThreshold {
+			b.transition(Closed)
+		}
+	}
+}
+
+func (b *Breaker) transition(state State) {
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.successes = 0
+	b.trialing = false
+	if state == Open {
+		b.openedAt = b.now()
+	}
+	metrics.BreakerState.Set(state.String())
+}
+
+// unavailable is every error but those of requests
+func unavailable(err error) bool {
+	switch err.(type) {
+	case repositories.InventoryQuantityUpdateError,
+		repositories.PromotionRedemptionError,
+		repositories.RetriesExhaustedError:
+		return false
+	}
+
+	return true
+}
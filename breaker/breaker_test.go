@@ -0,0 +1,132 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+var downErr = errors.New("connection refused")
+
+type stockErr struct{ error }
+
+func (stockErr) ProductID() int64 { return 1 }
+
+// testBreaker opens after 2 failures for a second of the returned clock
+func testBreaker() (*Breaker, *time.Time) {
+	now := time.Unix(0, 0)
+	b := NewBreaker()
+	b.FailureThreshold = 2
+	b.OpenTimeout = time.Second
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func call(b *Breaker, err error) error {
+	done, openErr := b.Allow()
+	if openErr != nil {
+		return openErr
+	}
+	done(err)
+	return nil
+}
+
+func TestBreaker(t *testing.T) {
+	t.Run("expecting open after consecutive failures", func(tt *testing.T) {
+		b, _ := testBreaker()
+		call(b, downErr)
+		call(b, nil)
+		call(b, downErr)
+		if b.State() != Closed {
+			tt.Fatal("expecting closed as failures are not consecutive, got", b.State())
+		}
+
+		call(b, downErr)
+		if b.State() != Open {
+			tt.Fatal("expecting open, got", b.State())
+		}
+
+		err := call(b, nil)
+		if e, ok := err.(repositories.UnavailableError); !ok || e.RetryAfter() != time.Second {
+			tt.Error("expecting UnavailableError retrying after 1s, got", err)
+		}
+	})
+
+	t.Run("expecting errors of requests and cancellation not failing", func(tt *testing.T) {
+		b, _ := testBreaker()
+		for i := 0; i < 3; i++ {
+			call(b, stockErr{errors.New("not enough stock")})
+			call(b, context.Canceled)
+		}
+		if b.State() != Closed {
+			tt.Error("expecting closed, got", b.State())
+		}
+	})
+
+	t.Run("expecting one trial call when half open", func(tt *testing.T) {
+		b, now := testBreaker()
+		call(b, downErr)
+		call(b, downErr)
+		*now = now.Add(time.Second)
+
+		done, err := b.Allow()
+		if err != nil || b.State() != HalfOpen {
+			tt.Fatal("expecting trial call allowed when half open, got", b.State(), err)
+		}
+
+		if _, err := b.Allow(); err == nil {
+			tt.Error("expecting other calls refused during trial")
+		}
+
+		done(nil)
+		if b.State() != Closed {
+			tt.Error("expecting closed after successful trial, got", b.State())
+		}
+	})
+
+	t.Run("expecting open again after failed trial", func(tt *testing.T) {
+		b, now := testBreaker()
+		call(b, downErr)
+		call(b, downErr)
+		*now = now.Add(time.Second)
+
+		call(b, downErr)
+		if b.State() != Open {
+			tt.Fatal("expecting open, got", b.State())
+		}
+
+		*now = now.Add(time.Second / 2)
+		if err := call(b, nil); err == nil {
+			tt.Error("expecting refused until OpenTimeout passed since the failed trial")
+		}
+	})
+
+	t.Run("expecting calls started before opening ignored", func(tt *testing.T) {
+		b, now := testBreaker()
+		late, _ := b.Allow()
+		call(b, downErr)
+		call(b, downErr)
+		*now = now.Add(time.Second)
+
+		trial, _ := b.Allow()
+		late(nil)
+		if b.State() != HalfOpen {
+			tt.Error("expecting still half open, got", b.State())
+		}
+
+		trial(downErr)
+		if b.State() != Open {
+			tt.Error("expecting open, got", b.State())
+		}
+	})
+
+	t.Run("expecting nil breaker closed", func(tt *testing.T) {
+		var b *Breaker
+		if b.State() != Closed {
+			tt.Error("expecting closed, got", b.State())
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+
+	"tomshop/repositories"
+)
+
+// Repo places orders
+type Repo interface {
+	PlaceOrder(context.Context, repositories.OrderQuery, repositories.OrderPlan) error
+}
+
+// OrderRepo refuses orders without calling Repo while Breaker is open
+type OrderRepo struct {
+	Repo    Repo
+	Breaker *Breaker
+}
+
+// NewOrderRepo wraps r with b
+func NewOrderRepo(r Repo, b *Breaker) *OrderRepo {
+	return &OrderRepo{Repo: r, Breaker: b}
+}
+
+// PlaceOrder by Repo if Breaker allows, errors of plan mean the snapshot was read so they are successes
+func (r *OrderRepo) PlaceOrder(ctx context.Context, query repositories.OrderQuery, plan repositories.OrderPlan) error {
+	done, err := r.Breaker.Allow()
+	if err != nil {
+		return err
+	}
+
+	var planErr error
+	err = r.Repo.PlaceOrder(ctx, query, recordingPlan(plan, &planErr))
+	done(outcome(ctx, err, planErr))
+
+	return err
+}
+
+// BatchRepo places orders of a batch in one transaction
+type BatchRepo interface {
+	PlaceOrders(context.Context, []repositories.OrderPlacement) []error
+}
+
+// OrderBatchRepo refuses batches without calling Repo while Breaker is open
+type OrderBatchRepo struct {
+	Repo    BatchRepo
+	Breaker *Breaker
+}
+
+// NewOrderBatchRepo wraps r with b
+func NewOrderBatchRepo(r BatchRepo, b *Breaker) *OrderBatchRepo {
+	return &OrderBatchRepo{Repo: r, Breaker: b}
+}
+
+// PlaceOrders by Repo if Breaker allows, the batch fails if any order failed other than by its plan
+func (r *OrderBatchRepo) PlaceOrders(ctx context.Context, placements []repositories.OrderPlacement) []error {
+	errs := make([]error, len(placements))
+	done, err := r.Breaker.Allow()
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	planErrs := make([]error, len(placements))
+	recording := make([]repositories.OrderPlacement, len(placements))
+	for i, p := range placements {
+		recording[i] = repositories.OrderPlacement{Query: p.Query, Plan: recordingPlan(p.Plan, &planErrs[i])}
+	}
+
+	errs = r.Repo.PlaceOrders(ctx, recording)
+	var failed error
+	for i, err := range errs {
+		if o := outcome(ctx, err, planErrs[i]); o != nil {
+			failed = o
+			break
+		}
+	}
+	done(failed)
+
+	return errs
+}
+
+// recordingPlan is plan keeping its last error in planErr
+func recordingPlan(plan repositories.OrderPlan, planErr *error) repositories.OrderPlan {
+	return func(snapshot repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		orders, opts, err := plan(snapshot)
+		*planErr = err
+		return orders, opts, err
+	}
+}
+
+// outcome of a call told to the breaker, errors of plan mean the snapshot was read so they are successes.
+// Calls whose ctx is done are neither failures nor successes
+func outcome(ctx context.Context, err, planErr error) error {
+	switch {
+	case err == nil:
+		return nil
+	case planErr != nil && errors.Is(err, planErr):
+		return nil
+	case ctx.Err() != nil:
+		// the caller gave up, e.g. its deadline expired while retrying contention
+		return ctx.Err()
+	}
+
+	return err
+}
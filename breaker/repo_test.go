@@ -0,0 +1,166 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+type mockRepo struct {
+	placeOrder func(context.Context, repositories.OrderQuery, repositories.OrderPlan) error
+}
+
+func (r mockRepo) PlaceOrder(ctx context.Context, query repositories.OrderQuery, plan repositories.OrderPlan) error {
+	return r.placeOrder(ctx, query, plan)
+}
+
+// readingRepo calls plan with an empty snapshot
+var readingRepo = mockRepo{
+	placeOrder: func(_ context.Context, _ repositories.OrderQuery, plan repositories.OrderPlan) error {
+		_, _, err := plan(repositories.OrderSnapshot{})
+		return err
+	},
+}
+
+func TestOrderRepo_PlaceOrder(t *testing.T) {
+	t.Run("expecting errors of plan not failing", func(tt *testing.T) {
+		b, _ := testBreaker()
+		r := NewOrderRepo(readingRepo, b)
+		planErr := errors.New("out of stock")
+		for i := 0; i < 3; i++ {
+			err := r.PlaceOrder(context.Background(), repositories.OrderQuery{}, func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+				return nil, nil, planErr
+			})
+			if err != planErr {
+				tt.Fatal("expecting error of plan returned as is, got", err)
+			}
+		}
+
+		if b.State() != Closed {
+			tt.Error("expecting closed, got", b.State())
+		}
+	})
+
+	t.Run("expecting orders refused without calling repo when open", func(tt *testing.T) {
+		b, _ := testBreaker()
+		calls := 0
+		r := NewOrderRepo(mockRepo{
+			placeOrder: func(context.Context, repositories.OrderQuery, repositories.OrderPlan) error {
+				calls++
+				return downErr
+			},
+		}, b)
+
+		for i := 0; i < 3; i++ {
+			r.PlaceOrder(context.Background(), repositories.OrderQuery{}, nil)
+		}
+
+		if calls != 2 {
+			tt.Error("expecting repo called twice before opening, got", calls)
+		}
+	})
+
+	t.Run("expecting orders past their deadline not failing", func(tt *testing.T) {
+		b, _ := testBreaker()
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+		defer cancel()
+		r := NewOrderRepo(mockRepo{
+			placeOrder: func(ctx context.Context, _ repositories.OrderQuery, _ repositories.OrderPlan) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}, b)
+
+		for i := 0; i < 3; i++ {
+			if err := r.PlaceOrder(ctx, repositories.OrderQuery{}, nil); err != context.DeadlineExceeded {
+				tt.Fatal("expecting DeadlineExceeded, got", err)
+			}
+		}
+
+		if b.State() != Closed {
+			tt.Error("expecting closed, got", b.State())
+		}
+	})
+
+	t.Run("expecting orders canceled by client not failing", func(tt *testing.T) {
+		b, _ := testBreaker()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		r := NewOrderRepo(mockRepo{
+			placeOrder: func(context.Context, repositories.OrderQuery, repositories.OrderPlan) error {
+				return downErr
+			},
+		}, b)
+
+		for i := 0; i < 3; i++ {
+			r.PlaceOrder(ctx, repositories.OrderQuery{}, nil)
+		}
+
+		if b.State() != Closed {
+			tt.Error("expecting closed, got", b.State())
+		}
+	})
+}
+
+type mockBatchRepo struct {
+	placeOrders func(context.Context, []repositories.OrderPlacement) []error
+}
+
+func (r mockBatchRepo) PlaceOrders(ctx context.Context, placements []repositories.OrderPlacement) []error {
+	return r.placeOrders(ctx, placements)
+}
+
+func TestOrderBatchRepo_PlaceOrders(t *testing.T) {
+	planErr := errors.New("out of stock")
+	placements := []repositories.OrderPlacement{
+		{Plan: func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+			return nil, nil, nil
+		}},
+		{Plan: func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+			return nil, nil, planErr
+		}},
+	}
+
+	t.Run("expecting errors of plans not failing", func(tt *testing.T) {
+		b, _ := testBreaker()
+		r := NewOrderBatchRepo(mockBatchRepo{
+			placeOrders: func(_ context.Context, placements []repositories.OrderPlacement) []error {
+				errs := make([]error, len(placements))
+				for i, p := range placements {
+					_, _, errs[i] = p.Plan(repositories.OrderSnapshot{})
+				}
+				return errs
+			},
+		}, b)
+
+		for i := 0; i < 3; i++ {
+			if errs := r.PlaceOrders(context.Background(), placements); errs[0] != nil || errs[1] != planErr {
+				tt.Fatal("expecting errors of plans returned as is, got", errs)
+			}
+		}
+
+		if b.State() != Closed {
+			tt.Error("expecting closed, got", b.State())
+		}
+	})
+
+	t.Run("expecting every order refused when open", func(tt *testing.T) {
+		b, _ := testBreaker()
+		r := NewOrderBatchRepo(mockBatchRepo{
+			placeOrders: func(_ context.Context, placements []repositories.OrderPlacement) []error {
+				return []error{downErr, downErr}
+			},
+		}, b)
+
+		r.PlaceOrders(context.Background(), placements)
+		r.PlaceOrders(context.Background(), placements)
+		for _, err := range r.PlaceOrders(context.Background(), placements) {
+			if _, ok := err.(repositories.UnavailableError); !ok {
+				tt.Error("expecting UnavailableError, got", err)
+			}
+		}
+	})
+}
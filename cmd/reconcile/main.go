@@ -0,0 +1,43 @@
+// reconcile recomputes stock of every product from the stock movements ledger and reports drift,
+// it exits with status 1 if any product drifted
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	repo "tomshop/repositories/sql"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	db, err := sql.Open("postgres", os.Getenv("DATABASE_ADDR"))
+	if err != nil {
+		log.Fatal("error connecting to the database: ", err)
+	}
+	defer db.Close()
+
+	drifts, err := repo.NewCockroachRepo(db).ReconcileStock(context.Background())
+	if err != nil {
+		log.Fatal("error reconciling stock: ", err)
+	}
+
+	if len(drifts) == 0 {
+		log.Println("stock matches the ledger")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PRODUCT\tSTOCK\tLEDGER\tDRIFT")
+	for _, d := range drifts {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\n", d.ProductID, d.StockCount, d.LedgerCount, d.StockCount-d.LedgerCount)
+	}
+	w.Flush()
+
+	os.Exit(1)
+}
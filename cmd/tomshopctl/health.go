@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	health "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// checkHealth fails unless the server is serving, the service name is optional
+type checkHealth struct {
+	noFlags
+}
+
+func (checkHealth) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	in := &health.HealthCheckRequest{}
+	if len(args) > 0 {
+		in.Service = args[0]
+	}
+
+	resp, err := c.health().Check(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		_, err = fmt.Fprintf(out, "{\"status\":%q}\n", resp.Status)
+	} else {
+		_, err = fmt.Fprintln(out, resp.Status)
+	}
+	if err != nil {
+		return err
+	}
+
+	if resp.Status != health.HealthCheckResponse_SERVING {
+		return fmt.Errorf("server is %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pb "tomshop/grpc"
+)
+
+func parseIDs(args []string) ([]int64, error) {
+	if len(args) == 0 {
+		return nil, errors.New("missing product ID")
+	}
+
+	ids := make([]int64, len(args))
+	for i, a := range args {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid product ID %q", a)
+		}
+		ids[i] = id
+	}
+
+	return ids, nil
+}
+
+func printInventories(c *conn, out io.Writer, resp *pb.ListInventoriesResponse) error {
+	if c.output == "json" {
+		return printJSON(out, resp)
+	}
+
+	t := newTable(out, "PRODUCT", "STOCK")
+	for _, inv := range resp.Inventories {
+		t.row(inv.ProductID, inv.StockCount)
+	}
+	return t.flush()
+}
+
+type getInventory struct {
+	noFlags
+}
+
+func (getInventory) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: inventory get <productID>")
+	}
+
+	return listInventories{}.run(ctx, c, args, out)
+}
+
+type listInventories struct {
+	noFlags
+}
+
+func (listInventories) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.shop().ListInventories(ctx, &pb.ListInventoriesRequest{ProductIDs: ids})
+	if err != nil {
+		return err
+	}
+
+	return printInventories(c, out, resp)
+}
+
+type setInventory struct {
+	reason      string
+	reference   string
+	actor       string
+	warehouseID int64
+	backordered int64
+}
+
+func (s *setInventory) flags(fs *flag.FlagSet) {
+	fs.StringVar(&s.reason, "reason", "correction", `"correction" sets the stock, "restock" or "cancellation" adds to it`)
+	fs.StringVar(&s.reference, "reference", "", "reference of the movement, e.g. purchase order or order ID")
+	fs.StringVar(&s.actor, "actor", "tomshopctl", "who changes the stock")
+	fs.Int64Var(&s.warehouseID, "warehouse", 0, "warehouse of the items, required if server allocates orders to warehouses")
+	fs.Int64Var(&s.backordered, "backordered", 0, "backordered items of the cancelled order, released instead of returned")
+}
+
+func (s *setInventory) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("usage: inventory set <productID> <quantity>")
+	}
+
+	ids, err := parseIDs(args[:1])
+	if err != nil {
+		return err
+	}
+
+	qty, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %q", args[1])
+	}
+
+	reason, ok := pb.StockMovementReason_value[strings.ToUpper(s.reason)]
+	if !ok {
+		return fmt.Errorf("unknown reason %q", s.reason)
+	}
+
+	m, err := c.shop().ChangeStock(ctx, &pb.ChangeStockRequest{
+		ProductID:   ids[0],
+		Quantity:    qty,
+		Reason:      pb.StockMovementReason(reason),
+		Reference:   s.reference,
+		Actor:       s.actor,
+		WarehouseID: s.warehouseID,
+		Backordered: s.backordered,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printMovements(c, out, &pb.StockHistoryResponse{Movements: []*pb.StockMovement{m}})
+}
+
+type stockHistory struct {
+	limit int
+}
+
+func (h *stockHistory) flags(fs *flag.FlagSet) {
+	fs.IntVar(&h.limit, "limit", 0, "movements shown at most, server default if 0")
+}
+
+func (h *stockHistory) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: inventory history <productID>")
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.shop().GetStockHistory(ctx, &pb.StockHistoryRequest{ProductID: ids[0], Limit: int32(h.limit)})
+	if err != nil {
+		return err
+	}
+
+	return printMovements(c, out, resp)
+}
+
+func printMovements(c *conn, out io.Writer, resp *pb.StockHistoryResponse) error {
+	if c.output == "json" {
+		if len(resp.Movements) == 1 {
+			return printJSON(out, resp.Movements[0])
+		}
+		return printJSON(out, resp)
+	}
+
+	t := newTable(out, "ID", "PRODUCT", "DELTA", "BEFORE", "AFTER", "REASON", "REFERENCE", "ACTOR", "CREATED AT")
+	for _, m := range resp.Movements {
+		t.row(m.Id, m.ProductID, m.Delta, m.Before, m.After, m.Reason, m.Reference, m.Actor, m.CreatedAt)
+	}
+	return t.flush()
+}
@@ -0,0 +1,241 @@
+// tomshopctl calls the TomShop gRPC service from the command line, e.g.
+//
+//	tomshopctl order place --item 11:2 --item 12:1
+//	tomshopctl inventory list 11 12
+//	tomshopctl inventory set 11 20 --reason restock
+//	tomshopctl policy set 11 backorder --limit 50
+//	tomshopctl watch 11 12
+//	tomshopctl health
+//
+// Every command accepts the connection and output flags, see tomshopctl <command> -h
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	pb "tomshop/grpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	health "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const usage = `usage: tomshopctl <command> [flags] [args]
+
+commands:
+  order place --item <productID>:<quantity>...   make an order
+  inventory get <productID>                      show stock of a product
+  inventory list <productID>...                  show stock of products
+  inventory set <productID> <quantity>           correct, restock or return stock
+  inventory history <productID>                  show stock movements newest first
+  policy set <productID> <policy>                sell only stock, backorder or preorder a product
+  watch <productID>...                           stream stock of products as they change
+  watch --low-stock                              stream products falling below their threshold
+  health [service]                               check the server is serving
+`
+
+// command registers its own flags then runs with args left after flags, output is written to out
+type command interface {
+	flags(fs *flag.FlagSet)
+	run(ctx context.Context, c *conn, args []string, out io.Writer) error
+}
+
+// noFlags is embedded by commands taking only positional args
+type noFlags struct{}
+
+func (noFlags) flags(*flag.FlagSet) {}
+
+// conn to the server and flags shared by every command
+type conn struct {
+	addr       string
+	output     string
+	timeout    time.Duration
+	useTLS     bool
+	caCert     string
+	serverName string
+	insecure   bool
+	token      string
+
+	cc *grpc.ClientConn
+}
+
+func (c *conn) register(fs *flag.FlagSet) {
+	addr := os.Getenv("TOMSHOP_ADDR")
+	if addr == "" {
+		addr = "localhost:50051"
+	}
+	fs.StringVar(&c.addr, "addr", addr, "server address, $TOMSHOP_ADDR")
+	fs.StringVar(&c.output, "output", "table", `"table" or "json"`)
+	fs.DurationVar(&c.timeout, "timeout", 10*time.Second, "deadline of a call, 0 for none, watch is never bounded")
+	fs.BoolVar(&c.useTLS, "tls", false, "connect with TLS")
+	fs.StringVar(&c.caCert, "ca-cert", "", "PEM file of CA verifying the server, system CAs by default")
+	fs.StringVar(&c.serverName, "server-name", "", "name verified in the server certificate, host of -addr by default")
+	fs.BoolVar(&c.insecure, "insecure-skip-verify", false, "don't verify the server certificate")
+	fs.StringVar(&c.token, "token", os.Getenv("TOMSHOP_TOKEN"), "bearer token sent with every call, $TOMSHOP_TOKEN")
+}
+
+// dial creates the client, it connects on the first call so a server down fails the call
+func (c *conn) dial() error {
+	var opts []grpc.DialOption
+	if c.useTLS {
+		cfg := &tls.Config{ServerName: c.serverName, InsecureSkipVerify: c.insecure}
+		if c.caCert != "" {
+			pem, err := os.ReadFile(c.caCert)
+			if err != nil {
+				return err
+			}
+
+			cfg.RootCAs = x509.NewCertPool()
+			if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificate found in %s", c.caCert)
+			}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	if c.token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken{token: c.token, secure: c.useTLS}))
+	}
+
+	var err error
+	c.cc, err = grpc.NewClient(c.addr, opts...)
+	return err
+}
+
+func (c *conn) shop() pb.TomShopClient {
+	return pb.NewTomShopClient(c.cc)
+}
+
+func (c *conn) health() health.HealthClient {
+	return health.NewHealthClient(c.cc)
+}
+
+// bearerToken is sent in the authorization metadata
+type bearerToken struct {
+	token  string
+	secure bool
+}
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity is false without TLS so tokens of local servers can be sent in plain text
+func (t bearerToken) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+// commands by name and subcommand name, "" for commands without subcommands
+var commands = map[string]map[string]func() command{
+	"order": {
+		"place": func() command { return &placeOrder{} },
+	},
+	"inventory": {
+		"get":     func() command { return &getInventory{} },
+		"list":    func() command { return &listInventories{} },
+		"set":     func() command { return &setInventory{} },
+		"history": func() command { return &stockHistory{} },
+	},
+	"policy": {
+		"set": func() command { return &setPolicy{} },
+	},
+	"watch": {
+		"": func() command { return &watchInventory{} },
+	},
+	"health": {
+		"": func() command { return &checkHealth{} },
+	},
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "tomshopctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", usage)
+	}
+
+	subs, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+
+	name := args[0]
+	args = args[1:]
+	newCmd, ok := subs[""]
+	if !ok {
+		if len(args) == 0 {
+			return fmt.Errorf("missing %s subcommand\n%s", name, usage)
+		}
+
+		if newCmd, ok = subs[args[0]]; !ok {
+			return fmt.Errorf("unknown %s subcommand %q\n%s", name, args[0], usage)
+		}
+		name += " " + args[0]
+		args = args[1:]
+	}
+
+	c := &conn{}
+	cmd := newCmd()
+	fs := newFlagSet(cmd)
+	c.register(fs)
+	if err := parseInterspersed(fs, args); err != nil {
+		return err
+	}
+
+	if c.output != "table" && c.output != "json" {
+		return fmt.Errorf("unknown output %q", c.output)
+	}
+
+	if err := c.dial(); err != nil {
+		return fmt.Errorf("cannot connect to %s: %v", c.addr, err)
+	}
+	defer c.cc.Close()
+
+	if c.timeout > 0 && name != "watch" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	return cmd.run(ctx, c, fs.Args(), out)
+}
+
+func newFlagSet(cmd command) *flag.FlagSet {
+	fs := flag.NewFlagSet("tomshopctl", flag.ContinueOnError)
+	cmd.flags(fs)
+	return fs
+}
+
+// parseInterspersed flags and positional args of fs, flag stops at the first positional arg
+// but "inventory set 11 20 --reason restock" reads better with flags last
+func parseInterspersed(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	return fs.Parse(append([]string{"--"}, positional...))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	pb "tomshop/grpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// stubServer serves only what the tests call
+type stubServer struct {
+	pb.UnimplementedTomShopServer
+	makeOrder func(context.Context, *pb.OrderRequest) (*pb.OrderResponse, error)
+}
+
+func (s *stubServer) MakeOrder(ctx context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
+	return s.makeOrder(ctx, in)
+}
+
+func (s *stubServer) SetFulfillmentPolicy(_ context.Context, in *pb.FulfillmentPolicy) (*pb.FulfillmentPolicy, error) {
+	in.BackorderedCount = 2
+	return in, nil
+}
+
+func (s *stubServer) ListInventories(_ context.Context, in *pb.ListInventoriesRequest) (*pb.ListInventoriesResponse, error) {
+	resp := &pb.ListInventoriesResponse{}
+	for _, id := range in.ProductIDs {
+		resp.Inventories = append(resp.Inventories, &pb.Inventory{ProductID: id, StockCount: id * 10})
+	}
+	return resp, nil
+}
+
+func serve(t *testing.T, srv pb.TomShopServer) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := grpc.NewServer()
+	pb.RegisterTomShopServer(s, srv)
+	go s.Serve(lis)
+
+	return lis.Addr().String(), s.Stop
+}
+
+func TestItems(t *testing.T) {
+	t.Run("expecting repeated items", func(tt *testing.T) {
+		var i items
+		for _, v := range []string{"11:2", "12:1"} {
+			if err := i.Set(v); err != nil {
+				tt.Fatal(err)
+			}
+		}
+
+		if len(i) != 2 || i[0].ProductID != 11 || i[0].Quantity != 2 || i[1].ProductID != 12 || i[1].Quantity != 1 {
+			tt.Error("unexpected items", i.String())
+		}
+	})
+
+	t.Run("expecting error for malformed item", func(tt *testing.T) {
+		for _, v := range []string{"11", "a:1", "11:b", "0:1", "11:0", "11:-1"} {
+			var i items
+			if err := i.Set(v); err == nil {
+				tt.Error("expecting error for", v)
+			}
+		}
+	})
+}
+
+func TestParseInterspersed(t *testing.T) {
+	t.Run("expecting flags after positional args", func(tt *testing.T) {
+		s := &setInventory{}
+		fs := newFlagSet(s)
+		if err := parseInterspersed(fs, []string{"11", "20", "--reason", "restock", "--actor=bob"}); err != nil {
+			tt.Fatal(err)
+		}
+
+		if s.reason != "restock" || s.actor != "bob" || strings.Join(fs.Args(), " ") != "11 20" {
+			tt.Error("unexpected parse", s.reason, s.actor, fs.Args())
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("expecting order placed with token", func(tt *testing.T) {
+		addr, stop := serve(tt, &stubServer{
+			makeOrder: func(ctx context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
+				md, _ := metadata.FromIncomingContext(ctx)
+				if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer secret" {
+					tt.Error("unexpected authorization", auth)
+				}
+
+				if len(in.Purchases) != 2 || in.FulfillmentMode != pb.FulfillmentMode_BEST_EFFORT {
+					tt.Error("unexpected request", in)
+				}
+				return &pb.OrderResponse{Successful: true, OrderID: "order-1", Total: 300}, nil
+			},
+		})
+		defer stop()
+
+		out := &bytes.Buffer{}
+		err := run(context.Background(), []string{
+			"order", "place", "--addr", addr, "--token", "secret", "--output", "json",
+			"--item", "11:2", "--item", "12:1", "--mode", "best_effort",
+		}, out)
+		if err != nil {
+			tt.Fatal(err)
+		}
+
+		got := &pb.OrderResponse{}
+		if err := protojson.Unmarshal(out.Bytes(), got); err != nil {
+			tt.Fatal(err, out.String())
+		}
+
+		if !got.Successful || got.OrderID != "order-1" || got.Total != 300 {
+			tt.Error("unexpected output", out.String())
+		}
+	})
+
+	t.Run("expecting inventories in table", func(tt *testing.T) {
+		addr, stop := serve(tt, &stubServer{})
+		defer stop()
+
+		out := &bytes.Buffer{}
+		if err := run(context.Background(), []string{"inventory", "list", "11", "12", "--addr", addr}, out); err != nil {
+			tt.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3 || strings.Fields(lines[1])[1] != "110" || strings.Fields(lines[2])[1] != "120" {
+			tt.Error("unexpected output", out.String())
+		}
+	})
+
+	t.Run("expecting fulfillment policy set", func(tt *testing.T) {
+		addr, stop := serve(tt, &stubServer{})
+		defer stop()
+
+		out := &bytes.Buffer{}
+		err := run(context.Background(), []string{"policy", "set", "11", "backorder", "--limit", "50", "--addr", addr, "--output", "json"}, out)
+		if err != nil {
+			tt.Fatal(err)
+		}
+
+		got := &pb.FulfillmentPolicy{}
+		if err := protojson.Unmarshal(out.Bytes(), got); err != nil {
+			tt.Fatal(err, out.String())
+		}
+
+		if got.ProductID != 11 || got.Kind != pb.FulfillmentPolicyKind_BACKORDER || got.Limit != 50 || got.BackorderedCount != 2 {
+			tt.Error("unexpected output", out.String())
+		}
+	})
+
+	t.Run("expecting error for unknown command", func(tt *testing.T) {
+		if err := run(context.Background(), []string{"inventory", "drop"}, &bytes.Buffer{}); err == nil {
+			tt.Error("expecting error")
+		}
+	})
+}
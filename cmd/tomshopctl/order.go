@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	pb "tomshop/grpc"
+)
+
+// items of an order given as repeated --item <productID>:<quantity>
+type items []*pb.Order
+
+func (i *items) String() string {
+	parts := make([]string, len(*i))
+	for n, o := range *i {
+		parts[n] = fmt.Sprintf("%d:%d", o.ProductID, o.Quantity)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (i *items) Set(v string) error {
+	sep := strings.IndexByte(v, ':')
+	if sep < 0 {
+		return errors.New("item must be <productID>:<quantity>")
+	}
+
+	id, err := strconv.ParseInt(v[:sep], 10, 64)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid product ID %q", v[:sep])
+	}
+
+	qty, err := strconv.ParseInt(v[sep+1:], 10, 64)
+	if err != nil || qty <= 0 {
+		return fmt.Errorf("invalid quantity %q", v[sep+1:])
+	}
+
+	*i = append(*i, &pb.Order{ProductID: id, Quantity: qty})
+	return nil
+}
+
+type placeOrder struct {
+	items      items
+	customerID int64
+	coupons    string
+	mode       string
+	backorder  bool
+	region     string
+	warehouse  int64
+}
+
+func (p *placeOrder) flags(fs *flag.FlagSet) {
+	fs.Var(&p.items, "item", "<productID>:<quantity> ordered, repeat for every product")
+	fs.Int64Var(&p.customerID, "customer", 0, "customer ID, required by coupons with per customer limit")
+	fs.StringVar(&p.coupons, "coupons", "", "comma separated coupon codes")
+	fs.StringVar(&p.mode, "mode", "all_or_nothing", `fulfillment mode "all_or_nothing", "best_effort" or "per_line_minimum"`)
+	fs.BoolVar(&p.backorder, "backorder", false, "accept backordered or preordered lines")
+	fs.StringVar(&p.region, "region", "", "region hint of warehouse allocation")
+	fs.Int64Var(&p.warehouse, "warehouse", 0, "preferred warehouse ID")
+}
+
+func (p *placeOrder) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected args %v", args)
+	}
+
+	if len(p.items) == 0 {
+		return errors.New("missing --item")
+	}
+
+	mode, ok := pb.FulfillmentMode_value[strings.ToUpper(p.mode)]
+	if !ok {
+		return fmt.Errorf("unknown mode %q", p.mode)
+	}
+
+	in := &pb.OrderRequest{
+		Purchases:            p.items,
+		CustomerID:           p.customerID,
+		RegionHint:           p.region,
+		PreferredWarehouseID: p.warehouse,
+		AllowBackorder:       p.backorder,
+		FulfillmentMode:      pb.FulfillmentMode(mode),
+	}
+	if p.coupons != "" {
+		in.CouponCodes = strings.Split(p.coupons, ",")
+	}
+
+	resp, err := c.shop().MakeOrder(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return printJSON(out, resp)
+	}
+
+	fmt.Fprintf(out, "order %s: subtotal %d, discount %d, total %d\n", resp.OrderID, resp.Subtotal, resp.Discount, resp.Total)
+	t := newTable(out, "PRODUCT", "IMMEDIATE", "BACKORDERED", "PREORDER", "UNFULFILLED")
+	for _, l := range resp.Lines {
+		t.row(l.ProductID, l.Immediate, l.Backordered, l.Preorder, l.Unfulfilled)
+	}
+	if err := t.flush(); err != nil {
+		return err
+	}
+
+	if len(resp.Allocations) == 0 {
+		return nil
+	}
+
+	t = newTable(out, "PRODUCT", "WAREHOUSE", "QUANTITY")
+	for _, a := range resp.Allocations {
+		t.row(a.ProductID, a.WarehouseID, a.Quantity)
+	}
+	return t.flush()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "tomshop/grpc"
+)
+
+// setPolicy decides what happens when stock of a product is not enough
+type setPolicy struct {
+	limit     int64
+	releaseAt string
+}
+
+func (p *setPolicy) flags(fs *flag.FlagSet) {
+	fs.Int64Var(&p.limit, "limit", 0, "items can be backordered or preordered")
+	fs.StringVar(&p.releaseAt, "release-at", "", "release time of a preorder in RFC 3339 format, e.g. 2019-06-01T00:00:00Z")
+}
+
+func (p *setPolicy) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("usage: policy set <productID> <stock_only|backorder|preorder>")
+	}
+
+	ids, err := parseIDs(args[:1])
+	if err != nil {
+		return err
+	}
+
+	kind, ok := pb.FulfillmentPolicyKind_value[strings.ToUpper(args[1])]
+	if !ok {
+		return fmt.Errorf("unknown policy %q", args[1])
+	}
+
+	resp, err := c.shop().SetFulfillmentPolicy(ctx, &pb.FulfillmentPolicy{
+		ProductID: ids[0],
+		Kind:      pb.FulfillmentPolicyKind(kind),
+		Limit:     p.limit,
+		ReleaseAt: p.releaseAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.output == "json" {
+		return printJSON(out, resp)
+	}
+
+	t := newTable(out, "PRODUCT", "POLICY", "LIMIT", "BACKORDERED", "RELEASE AT")
+	t.row(resp.ProductID, resp.Kind, resp.Limit, resp.BackorderedCount, resp.ReleaseAt)
+	return t.flush()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+
+	pb "tomshop/grpc"
+)
+
+// watchInventory prints stock of products as it changes until interrupted,
+// with --low-stock it prints products falling below their threshold instead
+type watchInventory struct {
+	lowStock bool
+}
+
+func (w *watchInventory) flags(fs *flag.FlagSet) {
+	fs.BoolVar(&w.lowStock, "low-stock", false, "watch products falling below their threshold, takes no product IDs")
+}
+
+func (w *watchInventory) run(ctx context.Context, c *conn, args []string, out io.Writer) error {
+	if w.lowStock {
+		return w.watchLowStock(ctx, c, out)
+	}
+
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.shop().WatchInventory(ctx, &pb.WatchInventoryRequest{ProductIDs: ids})
+	if err != nil {
+		return err
+	}
+
+	// rows are flushed one by one so they show up as they come
+	if c.output == "table" {
+		t := newTable(out, "PRODUCT", "STOCK")
+		if err := t.flush(); err != nil {
+			return err
+		}
+	}
+	for {
+		inv, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if c.output == "json" {
+			err = printJSON(out, inv)
+		} else {
+			t := newTable(out)
+			t.row(inv.ProductID, inv.StockCount)
+			err = t.flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (w *watchInventory) watchLowStock(ctx context.Context, c *conn, out io.Writer) error {
+	stream, err := c.shop().WatchLowStock(ctx, &pb.WatchLowStockRequest{})
+	if err != nil {
+		return err
+	}
+
+	if c.output == "table" {
+		t := newTable(out, "PRODUCT", "STOCK", "THRESHOLD")
+		if err := t.flush(); err != nil {
+			return err
+		}
+	}
+	for {
+		low, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if c.output == "json" {
+			err = printJSON(out, low)
+		} else {
+			t := newTable(out)
+			t.row(low.ProductID, low.StockCount, low.Threshold)
+			err = t.flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
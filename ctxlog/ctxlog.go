@@ -0,0 +1,87 @@
+// Package ctxlog logs with the tags of the call in context, e.g. request ID, method and peer set by
+// interceptors, so lines logged deep in services and repositories can be told apart by call.
+// It doesn't depend on any transport
+package ctxlog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+type tagsKey struct{}
+
+// With tags of keys and values alternately added to those of ctx, later values of a key win
+func With(ctx context.Context, keyValues ...string) context.Context {
+	prev, _ := ctx.Value(tagsKey{}).(map[string]string)
+	tags := make(map[string]string, len(prev)+len(keyValues)/2)
+	for k, v := range prev {
+		tags[k] = v
+	}
+
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		tags[keyValues[i]] = keyValues[i+1]
+	}
+
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// Merge tags of every one of from into those of ctx, values a key has in several of from are joined by
+// comma, e.g. request IDs of calls served together by one transaction
+func Merge(ctx context.Context, from ...context.Context) context.Context {
+	seen := map[string]bool{}
+	joined := map[string]string{}
+	for _, c := range from {
+		tags, _ := c.Value(tagsKey{}).(map[string]string)
+		for k, v := range tags {
+			if seen[k+"="+v] {
+				continue
+			}
+
+			seen[k+"="+v] = true
+			if joined[k] != "" {
+				v = joined[k] + "," + v
+			}
+			joined[k] = v
+		}
+	}
+
+	keyValues := make([]string, 0, 2*len(joined))
+	for k, v := range joined {
+		keyValues = append(keyValues, k, v)
+	}
+
+	return With(ctx, keyValues...)
+}
+
+// Println logs v like log.Println prefixed by tags of ctx
+func Println(ctx context.Context, v ...interface{}) {
+	log.Output(2, Prefix(ctx)+fmt.Sprintln(v...))
+}
+
+// Printf logs like log.Printf prefixed by tags of ctx
+func Printf(ctx context.Context, format string, v ...interface{}) {
+	log.Output(2, Prefix(ctx)+fmt.Sprintf(format, v...))
+}
+
+// Prefix of tags of ctx sorted by key, e.g. "[grpc.method=MakeOrder request_id=42] ", empty if ctx has none
+func Prefix(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	values, _ := ctx.Value(tagsKey{}).(map[string]string)
+	if len(values) == 0 {
+		return ""
+	}
+
+	tags := make([]string, 0, len(values))
+	for k, v := range values {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+
+	return "[" + strings.Join(tags, " ") + "] "
+}
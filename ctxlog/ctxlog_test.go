@@ -0,0 +1,54 @@
+package ctxlog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrintln(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	t.Run("expecting tags sorted before message", func(tt *testing.T) {
+		out.Reset()
+		Println(With(context.Background(), "request_id", "42", "grpc.method", "MakeOrder"), "cannot take stock:", 1)
+		if out.String() != "[grpc.method=MakeOrder request_id=42] cannot take stock: 1\n" {
+			tt.Errorf("unexpected output %q", out.String())
+		}
+	})
+
+	t.Run("expecting tags of parent kept and overridden", func(tt *testing.T) {
+		out.Reset()
+		parent := With(context.Background(), "request_id", "42", "grpc.method", "MakeOrder")
+		Println(With(parent, "grpc.method", "PlaceOrder"), "placed")
+		Println(parent, "made")
+		if out.String() != "[grpc.method=PlaceOrder request_id=42] placed\n[grpc.method=MakeOrder request_id=42] made\n" {
+			tt.Errorf("unexpected output %q", out.String())
+		}
+	})
+
+	t.Run("expecting tags of merged calls joined", func(tt *testing.T) {
+		out.Reset()
+		first := With(context.Background(), "request_id", "41", "grpc.method", "MakeOrder")
+		second := With(context.Background(), "request_id", "42", "grpc.method", "MakeOrder")
+		Println(Merge(context.Background(), first, second), "placed together")
+		if out.String() != "[grpc.method=MakeOrder request_id=41,42] placed together\n" {
+			tt.Errorf("unexpected output %q", out.String())
+		}
+	})
+
+	t.Run("expecting plain message without tags", func(tt *testing.T) {
+		out.Reset()
+		Printf(context.Background(), "%s given up", "PlaceOrder")
+		if strings.TrimSpace(out.String()) != "PlaceOrder given up" {
+			tt.Errorf("unexpected output %q", out.String())
+		}
+	})
+}
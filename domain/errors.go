@@ -0,0 +1,44 @@
+package domain
+
+import "fmt"
+
+// OutOfStockError rejects an order cannot be fulfilled from stock or warehouses
+type OutOfStockError struct {
+	// ProductID of the line cannot be fulfilled, 0 if the order cannot as a whole
+	ProductID int64
+}
+
+func (e OutOfStockError) Error() string {
+	if e.ProductID == 0 {
+		return "not enough stock to fulfil order"
+	}
+
+	return fmt.Sprintf("not enough stock of product %d to fulfil order", e.ProductID)
+}
+
+// UnknownCouponError rejects an order with coupon codes don't exist
+type UnknownCouponError struct{}
+
+func (UnknownCouponError) Error() string {
+	return "invalid coupon code"
+}
+
+// CouponNotApplicableError rejects an order coupons cannot be applied to or redeemed for
+type CouponNotApplicableError struct {
+	Cause error
+}
+
+func (e CouponNotApplicableError) Error() string {
+	return "coupon cannot be applied to order: " + e.Cause.Error()
+}
+
+// InvalidOrderError is an order cannot be placed whatever stock there is
+type InvalidOrderError struct {
+	Reason string
+}
+
+func (e InvalidOrderError) Error() string {
+	return e.Reason
+}
+
+var invalidOrderQty = InvalidOrderError{Reason: "invalid quantity for order"}
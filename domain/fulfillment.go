@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+
+	"tomshop/repositories"
+)
+
+// splitLine decides how many items taken from stock and how many backordered, ok is false when
+// the line cannot be fulfilled at all
+func splitLine(
+	requestQty int64,
+	stock int64,
+	policy repositories.FulfillmentPolicy,
+	allowBackorder bool,
+	now time.Time,
+) (immediate int64, backordered int64, ok bool) {
+	if policy.Kind == repositories.Preorder && now.Before(policy.ReleaseAt) {
+		if !allowBackorder || policy.BackorderedCount+requestQty > policy.Limit {
+			return 0, 0, false
+		}
+		return 0, requestQty, true
+	}
+
+	if requestQty <= stock {
+		return requestQty, 0, true
+	}
+
+	if !allowBackorder || policy.Kind != repositories.Backorder {
+		return 0, 0, false
+	}
+
+	if stock < 0 {
+		stock = 0
+	}
+	backordered = requestQty - stock
+	if policy.BackorderedCount+backordered > policy.Limit {
+		return 0, 0, false
+	}
+
+	return stock, backordered, true
+}
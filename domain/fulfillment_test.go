@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+func TestSplitLine(t *testing.T) {
+	now := time.Date(2019, 4, 22, 0, 0, 0, 0, time.UTC)
+	backorder := repositories.FulfillmentPolicy{
+		Kind:             repositories.Backorder,
+		Limit:            5,
+		BackorderedCount: 2,
+	}
+	preorder := repositories.FulfillmentPolicy{
+		Kind:      repositories.Preorder,
+		Limit:     5,
+		ReleaseAt: now.Add(time.Hour),
+	}
+	released := preorder
+	released.ReleaseAt = now
+
+	cases := []struct {
+		name           string
+		requestQty     int64
+		stock          int64
+		policy         repositories.FulfillmentPolicy
+		allowBackorder bool
+		immediate      int64
+		backordered    int64
+		ok             bool
+	}{
+		{"enough stock", 3, 3, repositories.FulfillmentPolicy{}, false, 3, 0, true},
+		{"not enough stock without policy", 4, 3, repositories.FulfillmentPolicy{}, true, 0, 0, false},
+		{"backorder not allowed by client", 4, 3, backorder, false, 0, 0, false},
+		{"backorder within limit", 6, 3, backorder, true, 3, 3, true},
+		{"backorder over limit", 7, 3, backorder, true, 0, 0, false},
+		{"preorder before release", 5, 10, preorder, true, 0, 5, true},
+		{"preorder not allowed by client", 5, 10, preorder, false, 0, 0, false},
+		{"preorder over limit", 6, 10, preorder, true, 0, 0, false},
+		{"preorder product released", 5, 10, released, false, 5, 0, true},
+	}
+
+	for _, c := range cases {
+		immediate, backordered, ok := splitLine(c.requestQty, c.stock, c.policy, c.allowBackorder, now)
+		if immediate != c.immediate || backordered != c.backordered || ok != c.ok {
+			t.Errorf(
+				"%s: expecting (%d, %d, %v), got (%d, %d, %v)",
+				c.name, c.immediate, c.backordered, c.ok, immediate, backordered, ok,
+			)
+		}
+	}
+}
@@ -0,0 +1,431 @@
+// Package domain implements use cases of the shop independent of transports, adapters of gRPC,
+// REST or CLI map their types and errors to it
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"tomshop/allocation"
+	"tomshop/ctxlog"
+	"tomshop/promotions"
+	"tomshop/repositories"
+	"tomshop/stockcache"
+	"tomshop/watch"
+)
+
+// OrderRepo saves orders planned from a snapshot read in the same transaction
+type OrderRepo interface {
+	PlaceOrder(context.Context, repositories.OrderQuery, repositories.OrderPlan) error
+}
+
+// OrderBatchRepo saves orders of a batch in one transaction, every plan sees what previous ones took
+type OrderBatchRepo interface {
+	PlaceOrders(context.Context, []repositories.OrderPlacement) []error
+}
+
+// FulfillmentMode decides what happens to lines cannot be fully taken from stock
+type FulfillmentMode int
+
+const (
+	// AllOrNothing rejects the whole order if any line cannot be fulfilled
+	AllOrNothing FulfillmentMode = iota
+	// BestEffort takes whatever in stock for every line
+	BestEffort
+	// PerLineMinimum takes whatever in stock for lines can get at least their MinQuantity
+	PerLineMinimum
+)
+
+// OrderLine of a product
+type OrderLine struct {
+	ProductID int64
+	Quantity  int64
+	// MinQuantity used by PerLineMinimum mode, 0 means the whole quantity
+	MinQuantity int64
+}
+
+// OrderCommand asks to place an order
+type OrderCommand struct {
+	Lines []OrderLine
+	// CustomerID is required by coupons with per customer limit
+	CustomerID  int64
+	CouponCodes []string
+	// hints for the Allocator
+	RegionHint           string
+	PreferredWarehouseID int64
+	// AllowBackorder accepts backordered or preordered lines for products have such policy
+	AllowBackorder bool
+	// FulfillmentMode other than AllOrNothing doesn't backorder
+	FulfillmentMode FulfillmentMode
+}
+
+// LineResult of an order line
+type LineResult struct {
+	ProductID int64
+	Requested int64
+	// Immediate is taken from stock, Backordered ships when restocked or released
+	Immediate   int64
+	Backordered int64
+	Preorder    bool
+	// Unfulfilled is dropped from the order in non strict fulfillment mode
+	Unfulfilled int64
+	// Allocations of Immediate to warehouses, empty without Allocator
+	Allocations []repositories.Allocation
+}
+
+// OrderResult of a placed order, amounts are in the smallest currency unit
+type OrderResult struct {
+	// OrderID is the reference of the order in stock movements
+	OrderID  string
+	Subtotal int64
+	Discount int64
+	Total    int64
+	Lines    []LineResult
+}
+
+// OrderService places orders
+type OrderService struct {
+	Repo OrderRepo
+	// Batches saves PlaceOrders together, nil for placing them one by one by Repo
+	Batches OrderBatchRepo
+	// Allocator splits orders into warehouses, nil for not using warehouses
+	Allocator allocation.Strategy
+	// Broadcaster is told about stock left by successful orders, can be nil
+	Broadcaster *watch.Broadcaster
+	// Cache of stock invalidated by successful orders, can be nil. Orders never read it
+	Cache *stockcache.Cache
+}
+
+// PlaceOrder plans the order from stock read in the same transaction it is saved. Orders refused by
+// business rules fail with OutOfStockError, UnknownCouponError or CouponNotApplicableError, malformed
+// orders with InvalidOrderError, other errors are of the repository
+func (s *OrderService) PlaceOrder(ctx context.Context, cmd OrderCommand) (OrderResult, error) {
+	if cmd.FulfillmentMode != AllOrNothing {
+		if err := validatePartial(cmd); err != nil {
+			return OrderResult{}, err
+		}
+	}
+
+	p := s.newPlacement(ctx, cmd)
+	return s.finish(ctx, p, s.Repo.PlaceOrder(ctx, p.query, p.plan))
+}
+
+// PlaceOrders of a batch in request order, a result and an error per command as if every command was
+// placed by PlaceOrder. Orders are saved by Batches in one transaction if set
+func (s *OrderService) PlaceOrders(ctx context.Context, cmds []OrderCommand) ([]OrderResult, []error) {
+	results := make([]OrderResult, len(cmds))
+	errs := make([]error, len(cmds))
+
+	var placements []*placement
+	var batch []repositories.OrderPlacement
+	for i, cmd := range cmds {
+		if cmd.FulfillmentMode != AllOrNothing {
+			if errs[i] = validatePartial(cmd); errs[i] != nil {
+				continue
+			}
+		}
+
+		p := s.newPlacement(ctx, cmd)
+		p.index = i
+		placements = append(placements, p)
+		batch = append(batch, repositories.OrderPlacement{Query: p.query, Plan: p.plan})
+	}
+
+	var placeErrs []error
+	if s.Batches != nil {
+		placeErrs = s.Batches.PlaceOrders(ctx, batch)
+	} else {
+		placeErrs = make([]error, len(batch))
+		for i, b := range batch {
+			placeErrs[i] = s.Repo.PlaceOrder(ctx, b.Query, b.Plan)
+		}
+	}
+
+	for i, p := range placements {
+		results[p.index], errs[p.index] = s.finish(ctx, p, placeErrs[i])
+	}
+
+	return results, errs
+}
+
+// placement of an order, result and taken are set by plan, stocks once it is committed
+type placement struct {
+	index   int
+	orderID string
+	query   repositories.OrderQuery
+	plan    repositories.OrderPlan
+	result  OrderResult
+	taken   []repositories.Order
+	stocks  []repositories.Inventory
+}
+
+func (s *OrderService) newPlacement(ctx context.Context, cmd OrderCommand) *placement {
+	ids := make([]int64, len(cmd.Lines))
+	for i, line := range cmd.Lines {
+		ids[i] = line.ProductID
+	}
+
+	p := &placement{
+		orderID: newOrderID(),
+		query: repositories.OrderQuery{
+			ProductIDs:      ids,
+			CouponCodes:     uniqueCodes(cmd.CouponCodes),
+			WarehouseStocks: s.Allocator != nil,
+		},
+	}
+	p.plan = func(snapshot repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		var promos []repositories.Promotion
+		var err error
+		if cmd.FulfillmentMode == AllOrNothing {
+			p.result, p.taken, promos, err = s.plan(ctx, cmd, snapshot, time.Now())
+		} else {
+			p.result, p.taken, promos, err = s.planPartial(ctx, cmd, snapshot, time.Now())
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return p.taken, []repositories.AdjustOption{
+			repositories.WithRedemptions(promotions.Redemptions(promos, cmd.CustomerID)...),
+			repositories.WithReference(p.orderID, actorOf(cmd)),
+			repositories.OnCommitted(func(stocks []repositories.Inventory) {
+				p.stocks = stocks
+			}),
+		}, nil
+	}
+
+	return p
+}
+
+// finish maps errors of saving p and tells others about stock taken by it
+func (s *OrderService) finish(ctx context.Context, p *placement, err error) (OrderResult, error) {
+	switch e := err.(type) {
+	case nil:
+	case repositories.PromotionRedemptionError:
+		ctxlog.Println(ctx, "cannot redeem coupon:", err)
+		return OrderResult{}, CouponNotApplicableError{Cause: err}
+	case repositories.InventoryQuantityUpdateError:
+		ctxlog.Println(ctx, "cannot take stock:", err)
+		return OrderResult{}, OutOfStockError{ProductID: e.ProductID()}
+	default:
+		// decided by plan or failed by the repository
+		return OrderResult{}, err
+	}
+
+	changed := changedProducts(p.taken)
+	s.Cache.Invalidate(changed...)
+	s.Broadcaster.Publish(p.stocks...)
+
+	p.result.OrderID = p.orderID
+	return p.result, nil
+}
+
+// plan takes every line fully or backorders it, the order fails if any line cannot be fulfilled
+func (s *OrderService) plan(
+	ctx context.Context,
+	cmd OrderCommand,
+	snapshot repositories.OrderSnapshot,
+	now time.Time,
+) (OrderResult, []repositories.Order, []repositories.Promotion, error) {
+	purchaseMap := make(map[int64]int64, len(cmd.Lines))
+	for _, line := range cmd.Lines {
+		purchaseMap[line.ProductID] = line.Quantity
+	}
+
+	availableInventories := snapshot.Inventories
+	if len(availableInventories) != len(cmd.Lines) {
+		ctxlog.Println(ctx, "not enough products")
+		return OrderResult{}, nil, nil, OutOfStockError{ProductID: missingProduct(cmd.Lines, availableInventories)}
+	}
+
+	policyMap := make(map[int64]repositories.FulfillmentPolicy, len(snapshot.Policies))
+	for _, p := range snapshot.Policies {
+		policyMap[p.ProductID] = p
+	}
+
+	orders := make([]repositories.Order, len(availableInventories))
+	lines := make([]promotions.Line, len(availableInventories))
+
+	for i := range availableInventories {
+		productID := availableInventories[i].ProductID
+		requestQty := purchaseMap[productID]
+		if requestQty <= 0 {
+			return OrderResult{}, nil, nil, invalidOrderQty
+		}
+
+		immediate, backordered, ok := splitLine(
+			requestQty,
+			availableInventories[i].StockCount,
+			policyMap[productID],
+			cmd.AllowBackorder,
+			now,
+		)
+		if !ok {
+			ctxlog.Printf(
+				ctx,
+				"not enough items for product %d, reuested: %d, available: %d",
+				productID,
+				requestQty,
+				availableInventories[i].StockCount,
+			)
+			return OrderResult{}, nil, nil, OutOfStockError{ProductID: productID}
+		}
+
+		orders[i].ProductID = productID
+		orders[i].Quantity = immediate
+		orders[i].Backordered = backordered
+		lines[i] = promotions.Line{
+			ProductID: productID,
+			Quantity:  requestQty,
+			UnitPrice: availableInventories[i].Price,
+		}
+	}
+
+	promos, err := checkPromotions(snapshot.Promotions, cmd.CouponCodes)
+	if err != nil {
+		return OrderResult{}, nil, nil, err
+	}
+
+	total, err := promotions.Apply(lines, promos, cmd.CustomerID, now)
+	if err != nil {
+		ctxlog.Println(ctx, "cannot apply coupons:", err)
+		return OrderResult{}, nil, nil, CouponNotApplicableError{Cause: err}
+	}
+
+	orders, err = s.allocate(ctx, cmd, snapshot.WarehouseStocks, orders)
+	if err != nil {
+		return OrderResult{}, nil, nil, err
+	}
+
+	return OrderResult{
+		Subtotal: total.Subtotal,
+		Discount: total.Discount,
+		Total:    total.Total,
+		Lines:    toLineResults(orders, purchaseMap, policyMap, now),
+	}, orders, promos, nil
+}
+
+// missingProduct of lines is a product has no inventory, 0 if every product has
+func missingProduct(lines []OrderLine, inventories []repositories.Inventory) int64 {
+	found := make(map[int64]bool, len(inventories))
+	for _, inv := range inventories {
+		found[inv.ProductID] = true
+	}
+
+	for _, line := range lines {
+		if !found[line.ProductID] {
+			return line.ProductID
+		}
+	}
+
+	return 0
+}
+
+// changedProducts are products taken from stock
+func changedProducts(orders []repositories.Order) []int64 {
+	ids := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		if o.Quantity > 0 {
+			ids = append(ids, o.ProductID)
+		}
+	}
+
+	return ids
+}
+
+// newOrderID is a random UUID v4
+func newOrderID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func actorOf(cmd OrderCommand) string {
+	if cmd.CustomerID == 0 {
+		return "anonymous"
+	}
+
+	return fmt.Sprintf("customer:%d", cmd.CustomerID)
+}
+
+// allocate orders into warehouses if Allocator is set
+func (s *OrderService) allocate(
+	ctx context.Context,
+	cmd OrderCommand,
+	stocks []repositories.WarehouseStock,
+	orders []repositories.Order,
+) ([]repositories.Order, error) {
+	if s.Allocator == nil {
+		return orders, nil
+	}
+
+	allocated, err := s.Allocator.Allocate(orders, stocks, allocation.Hint{
+		PreferredWarehouseID: cmd.PreferredWarehouseID,
+		Region:               cmd.RegionHint,
+	})
+	if err != nil {
+		ctxlog.Println(ctx, "cannot allocate order:", err)
+		// 0 when a custom strategy doesn't tell the product
+		e, _ := err.(allocation.NotEnoughStockError)
+		return nil, OutOfStockError{ProductID: e.ProductID}
+	}
+
+	return allocated, nil
+}
+
+func toLineResults(
+	orders []repositories.Order,
+	requested map[int64]int64,
+	policies map[int64]repositories.FulfillmentPolicy,
+	now time.Time,
+) []LineResult {
+	lines := make([]LineResult, len(orders))
+	for i, o := range orders {
+		p := policies[o.ProductID]
+		lines[i] = LineResult{
+			ProductID:   o.ProductID,
+			Requested:   requested[o.ProductID],
+			Immediate:   o.Quantity,
+			Backordered: o.Backordered,
+			Preorder:    p.Kind == repositories.Preorder && now.Before(p.ReleaseAt),
+			Allocations: o.Allocations,
+		}
+	}
+
+	return lines
+}
+
+// uniqueCodes without empty codes
+func uniqueCodes(couponCodes []string) []string {
+	if len(couponCodes) == 0 {
+		return nil
+	}
+
+	unique := make([]string, 0, len(couponCodes))
+	seen := make(map[string]bool, len(couponCodes))
+	for _, c := range couponCodes {
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		unique = append(unique, c)
+	}
+
+	return unique
+}
+
+// checkPromotions makes sure every coupon code exists
+func checkPromotions(promos []repositories.Promotion, couponCodes []string) ([]repositories.Promotion, error) {
+	if len(promos) != len(uniqueCodes(couponCodes)) {
+		return nil, UnknownCouponError{}
+	}
+
+	return promos, nil
+}
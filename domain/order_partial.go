@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"tomshop/ctxlog"
+	"tomshop/promotions"
+	"tomshop/repositories"
+)
+
+// validatePartial quantities before planning
+func validatePartial(cmd OrderCommand) error {
+	for _, line := range cmd.Lines {
+		if line.Quantity <= 0 || line.MinQuantity < 0 || line.MinQuantity > line.Quantity {
+			return invalidOrderQty
+		}
+	}
+
+	return nil
+}
+
+// planPartial takes lines as much as possible, a line gets nothing if it cannot get at least its minimum.
+// The order only fails if nothing can be taken
+func (s *OrderService) planPartial(
+	ctx context.Context,
+	cmd OrderCommand,
+	snapshot repositories.OrderSnapshot,
+	now time.Time,
+) (OrderResult, []repositories.Order, []repositories.Promotion, error) {
+	stocks := make(map[int64]int64, len(snapshot.Inventories))
+	prices := make(map[int64]int64, len(snapshot.Inventories))
+	for _, inv := range snapshot.Inventories {
+		stocks[inv.ProductID] = inv.StockCount
+		prices[inv.ProductID] = inv.Price
+	}
+
+	requested := make([]repositories.Order, len(cmd.Lines))
+	resultLines := make([]LineResult, len(cmd.Lines))
+	var taken []repositories.Order
+	for i, order := range cmd.Lines {
+		requested[i] = repositories.Order{
+			ProductID: order.ProductID,
+			Quantity:  order.Quantity,
+		}
+
+		minQty := int64(0)
+		if cmd.FulfillmentMode == PerLineMinimum {
+			minQty = order.MinQuantity
+			if minQty == 0 {
+				minQty = order.Quantity
+			}
+		}
+
+		// stock left after previous lines of the same product
+		qty := order.Quantity
+		if qty > stocks[order.ProductID] {
+			qty = stocks[order.ProductID]
+		}
+
+		if qty <= 0 || qty < minQty {
+			qty = 0
+		}
+
+		resultLines[i] = LineResult{
+			ProductID:   order.ProductID,
+			Requested:   order.Quantity,
+			Immediate:   qty,
+			Unfulfilled: order.Quantity - qty,
+		}
+
+		if qty > 0 {
+			stocks[order.ProductID] -= qty
+			taken = append(taken, repositories.Order{
+				ProductID: order.ProductID,
+				Quantity:  qty,
+			})
+		}
+	}
+
+	promos, err := checkPromotions(snapshot.Promotions, cmd.CouponCodes)
+	if err != nil {
+		return OrderResult{}, nil, nil, err
+	}
+
+	if _, err := promotions.Apply(toLines(requested, prices), promos, cmd.CustomerID, now); err != nil {
+		ctxlog.Println(ctx, "cannot apply coupons:", err)
+		return OrderResult{}, nil, nil, CouponNotApplicableError{Cause: err}
+	}
+
+	if len(taken) == 0 {
+		ctxlog.Println(ctx, "cannot take any item for order")
+		return OrderResult{}, nil, nil, OutOfStockError{}
+	}
+
+	// coupons were checked with requested quantities, those no longer applying to the taken
+	// quantities are not redeemed so customers don't use them up for nothing
+	total, err := promotions.Apply(toLines(taken, prices), promos, cmd.CustomerID, now)
+	if err != nil {
+		ctxlog.Println(ctx, "coupons no longer apply to fulfilled lines:", err)
+		var applying []repositories.Promotion
+		for _, p := range promos {
+			if _, err := promotions.Apply(toLines(taken, prices), append(applying, p), cmd.CustomerID, now); err == nil {
+				applying = append(applying, p)
+			}
+		}
+		promos = applying
+		total, _ = promotions.Apply(toLines(taken, prices), promos, cmd.CustomerID, now)
+	}
+
+	taken, err = s.allocate(ctx, cmd, snapshot.WarehouseStocks, taken)
+	if err != nil {
+		return OrderResult{}, nil, nil, err
+	}
+
+	// taken are in the order of lines got any item
+	for i, j := 0, 0; i < len(resultLines) && j < len(taken); i++ {
+		if resultLines[i].Immediate > 0 {
+			resultLines[i].Allocations = taken[j].Allocations
+			j++
+		}
+	}
+
+	return OrderResult{
+		Subtotal: total.Subtotal,
+		Discount: total.Discount,
+		Total:    total.Total,
+		Lines:    resultLines,
+	}, taken, promos, nil
+}
+
+func toLines(orders []repositories.Order, prices map[int64]int64) []promotions.Line {
+	lines := make([]promotions.Line, len(orders))
+	for i, o := range orders {
+		lines[i] = promotions.Line{
+			ProductID: o.ProductID,
+			Quantity:  o.Quantity,
+			UnitPrice: prices[o.ProductID],
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,240 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"tomshop/allocation"
+	"tomshop/repositories"
+)
+
+// mockRepo plans with stock of queried products in snapshot and saves by adjust
+type mockRepo struct {
+	snapshot repositories.OrderSnapshot
+	adjust   func([]repositories.Order) error
+}
+
+func (r mockRepo) PlaceOrder(_ context.Context, q repositories.OrderQuery, plan repositories.OrderPlan) error {
+	queried := make(map[int64]bool, len(q.ProductIDs))
+	for _, id := range q.ProductIDs {
+		queried[id] = true
+	}
+
+	snapshot := repositories.OrderSnapshot{}
+	for _, inv := range r.snapshot.Inventories {
+		if queried[inv.ProductID] {
+			snapshot.Inventories = append(snapshot.Inventories, inv)
+		}
+	}
+	for _, ws := range r.snapshot.WarehouseStocks {
+		if queried[ws.ProductID] && q.WarehouseStocks {
+			snapshot.WarehouseStocks = append(snapshot.WarehouseStocks, ws)
+		}
+	}
+
+	orders, _, err := plan(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if r.adjust == nil {
+		return nil
+	}
+	return r.adjust(orders)
+}
+
+type mockUpdateError struct{}
+
+func (mockUpdateError) Error() string {
+	return "dummyUpdateError"
+}
+
+func (mockUpdateError) ProductID() int64 {
+	return 1
+}
+
+type mockRedeemError struct{}
+
+func (mockRedeemError) Error() string {
+	return "dummyRedeemError"
+}
+
+func (mockRedeemError) Code() string {
+	return "TEN"
+}
+
+func TestOrderService_PlaceOrder(t *testing.T) {
+	stock := repositories.OrderSnapshot{
+		Inventories: []repositories.Inventory{
+			{
+				ProductID:  1,
+				StockCount: 3,
+				Price:      10,
+			},
+			{
+				ProductID:  2,
+				StockCount: 1,
+				Price:      20,
+			},
+		},
+	}
+
+	t.Run("expecting result of every line", func(tt *testing.T) {
+		s := &OrderService{Repo: mockRepo{snapshot: stock}}
+		result, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines: []OrderLine{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}},
+		})
+		if err != nil {
+			tt.Fatal("unexpected error", err)
+		}
+
+		if result.OrderID == "" || result.Subtotal != 40 || result.Total != 40 {
+			tt.Error("unexpected result", result)
+		}
+
+		expected := []LineResult{
+			{ProductID: 1, Requested: 2, Immediate: 2},
+			{ProductID: 2, Requested: 1, Immediate: 1},
+		}
+		if !reflect.DeepEqual(result.Lines, expected) {
+			tt.Error("unexpected lines", result.Lines)
+		}
+	})
+
+	t.Run("expecting OutOfStockError of the line cannot be fulfilled", func(tt *testing.T) {
+		s := &OrderService{Repo: mockRepo{snapshot: stock}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines: []OrderLine{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 2}},
+		})
+
+		if err != (OutOfStockError{ProductID: 2}) {
+			tt.Error("expecting out of stock product 2, got", err)
+		}
+	})
+
+	t.Run("expecting OutOfStockError if stock taken meanwhile", func(tt *testing.T) {
+		s := &OrderService{Repo: mockRepo{
+			snapshot: stock,
+			adjust: func([]repositories.Order) error {
+				return mockUpdateError{}
+			},
+		}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines: []OrderLine{{ProductID: 1, Quantity: 1}},
+		})
+
+		if err != (OutOfStockError{ProductID: 1}) {
+			tt.Error("expecting out of stock product 1, got", err)
+		}
+	})
+
+	t.Run("expecting UnknownCouponError if coupon not found", func(tt *testing.T) {
+		s := &OrderService{Repo: mockRepo{snapshot: stock}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines:       []OrderLine{{ProductID: 1, Quantity: 1}},
+			CouponCodes: []string{"NOPE"},
+		})
+
+		if _, ok := err.(UnknownCouponError); !ok {
+			tt.Error("expecting UnknownCouponError, got", err)
+		}
+	})
+
+	t.Run("expecting CouponNotApplicableError if coupon cannot be redeemed", func(tt *testing.T) {
+		s := &OrderService{Repo: mockRepo{
+			snapshot: stock,
+			adjust: func([]repositories.Order) error {
+				return mockRedeemError{}
+			},
+		}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines: []OrderLine{{ProductID: 1, Quantity: 1}},
+		})
+
+		if _, ok := err.(CouponNotApplicableError); !ok {
+			tt.Error("expecting CouponNotApplicableError, got", err)
+		}
+	})
+
+	t.Run("expecting InvalidOrderError if minimum greater than quantity", func(tt *testing.T) {
+		s := &OrderService{Repo: mockRepo{snapshot: stock}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines:           []OrderLine{{ProductID: 1, Quantity: 1, MinQuantity: 2}},
+			FulfillmentMode: PerLineMinimum,
+		})
+
+		if _, ok := err.(InvalidOrderError); !ok {
+			tt.Error("expecting InvalidOrderError, got", err)
+		}
+	})
+
+	t.Run("expecting error of repository returned as is", func(tt *testing.T) {
+		repoErr := errors.New("dummyAdjustError")
+		s := &OrderService{Repo: mockRepo{
+			snapshot: stock,
+			adjust: func([]repositories.Order) error {
+				return repoErr
+			},
+		}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines: []OrderLine{{ProductID: 1, Quantity: 1}},
+		})
+
+		if err != repoErr {
+			tt.Error("expecting error of repository, got", err)
+		}
+	})
+
+	t.Run("expecting OutOfStockError of the line cannot be allocated", func(tt *testing.T) {
+		// product 2 is in stock but not in any warehouse
+		snapshot := stock
+		snapshot.WarehouseStocks = []repositories.WarehouseStock{
+			{ProductID: 1, WarehouseID: 7, StockCount: 3},
+		}
+		s := &OrderService{Repo: mockRepo{snapshot: snapshot}, Allocator: allocation.PreferredWarehouse{}}
+		_, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines: []OrderLine{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}},
+		})
+
+		if err != (OutOfStockError{ProductID: 2}) {
+			tt.Error("expecting OutOfStockError of product 2, got", err)
+		}
+	})
+
+	t.Run("expecting allocations of taken lines in BEST_EFFORT mode", func(tt *testing.T) {
+		snapshot := stock
+		snapshot.WarehouseStocks = []repositories.WarehouseStock{
+			{ProductID: 1, WarehouseID: 7, StockCount: 3},
+			{ProductID: 2, WarehouseID: 7, StockCount: 1},
+		}
+		s := &OrderService{Repo: mockRepo{snapshot: snapshot}, Allocator: allocation.PreferredWarehouse{}}
+		result, err := s.PlaceOrder(context.Background(), OrderCommand{
+			Lines:           []OrderLine{{ProductID: 2, Quantity: 5}, {ProductID: 1, Quantity: 2}},
+			FulfillmentMode: BestEffort,
+		})
+		if err != nil {
+			tt.Fatal("unexpected error", err)
+		}
+
+		expected := []LineResult{
+			{
+				ProductID:   2,
+				Requested:   5,
+				Immediate:   1,
+				Unfulfilled: 4,
+				Allocations: []repositories.Allocation{{ProductID: 2, WarehouseID: 7, Quantity: 1}},
+			},
+			{
+				ProductID:   1,
+				Requested:   2,
+				Immediate:   2,
+				Allocations: []repositories.Allocation{{ProductID: 1, WarehouseID: 7, Quantity: 2}},
+			},
+		}
+		if !reflect.DeepEqual(result.Lines, expected) {
+			tt.Error("unexpected lines", result.Lines)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+// Package gateway serves tomshop.v1.TomShop and tomshop.v2.TomShop as REST/JSON for clients that cannot speak gRPC,
+// every request is proxied to the gRPC server
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	pb "tomshop/grpc"
+	pbv2 "tomshop/grpc/v2"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NewHandler proxies REST/JSON requests to the gRPC server at grpcAddr until ctx is done
+func NewHandler(ctx context.Context, grpcAddr string, opts ...grpc.DialOption) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+		}),
+		runtime.WithErrorHandler(writeError),
+	)
+	if err := pb.RegisterTomShopHandlerFromEndpoint(ctx, mux, grpcAddr, opts); err != nil {
+		return nil, err
+	}
+
+	if err := pbv2.RegisterTomShopHandlerFromEndpoint(ctx, mux, grpcAddr, opts); err != nil {
+		return nil, err
+	}
+
+	return mux, nil
+}
+
+// marshalErrorFallback is written when the status of an error cannot be marshaled
+const marshalErrorFallback = `{"code": 13, "message": "failed to marshal error message"}`
+
+// writeError writes the status of err as google.rpc.Status, as the OpenAPI spec tells, so details like
+// field violations of invalid requests reach REST clients, with the standard HTTP status of its code
+func writeError(_ context.Context, _ *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	s := status.Convert(err)
+	body, merr := m.Marshal(s.Proto())
+
+	w.Header().Del("Trailer")
+	w.Header().Set("Content-Type", m.ContentType(s.Proto()))
+	if merr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, marshalErrorFallback)
+		return
+	}
+
+	w.WriteHeader(runtime.HTTPStatusFromCode(s.Code()))
+	w.Write(body)
+}
@@ -0,0 +1,177 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "tomshop/grpc"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// stubServer serves only what the tests call
+type stubServer struct {
+	pb.UnimplementedTomShopServer
+	makeOrder func(*pb.OrderRequest) (*pb.OrderResponse, error)
+}
+
+func (s *stubServer) MakeOrder(_ context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
+	return s.makeOrder(in)
+}
+
+func (s *stubServer) ListInventories(_ context.Context, in *pb.ListInventoriesRequest) (*pb.ListInventoriesResponse, error) {
+	resp := &pb.ListInventoriesResponse{}
+	for _, id := range in.ProductIDs {
+		resp.Inventories = append(resp.Inventories, &pb.Inventory{ProductID: id, StockCount: id * 10})
+	}
+	return resp, nil
+}
+
+func newGateway(t *testing.T, srv pb.TomShopServer) (*httptest.Server, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := grpc.NewServer()
+	pb.RegisterTomShopServer(s, srv)
+	go s.Serve(lis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h, err := NewHandler(ctx, lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := httptest.NewServer(h)
+
+	return gw, func() {
+		gw.Close()
+		cancel()
+		s.Stop()
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("expecting order posted as JSON", func(tt *testing.T) {
+		gw, stop := newGateway(tt, &stubServer{
+			makeOrder: func(in *pb.OrderRequest) (*pb.OrderResponse, error) {
+				if len(in.Purchases) != 1 || in.Purchases[0].ProductID != 1 || in.FulfillmentMode != pb.FulfillmentMode_BEST_EFFORT {
+					tt.Error("unexpected request", in)
+				}
+				return &pb.OrderResponse{Successful: true, OrderID: "order-1"}, nil
+			},
+		})
+		defer stop()
+
+		resp, err := http.Post(gw.URL+"/v1/orders", "application/json", strings.NewReader(
+			`{"purchases": [{"productID": "1", "quantity": "2"}], "fulfillmentMode": "BEST_EFFORT"}`,
+		))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body := map[string]interface{}{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != http.StatusOK || body["successful"] != true || body["orderID"] != "order-1" {
+			tt.Error("expecting successful order, got", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("expecting FailedPrecondition as 400 with status", func(tt *testing.T) {
+		gw, stop := newGateway(tt, &stubServer{
+			makeOrder: func(*pb.OrderRequest) (*pb.OrderResponse, error) {
+				return nil, status.Error(codes.FailedPrecondition, "not enough stock")
+			},
+		})
+		defer stop()
+
+		resp, err := http.Post(gw.URL+"/v1/orders", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		got := &spb.Status{}
+		if err := protojson.Unmarshal(b, got); err != nil {
+			tt.Fatal(err, string(b))
+		}
+
+		expected := &spb.Status{Code: int32(codes.FailedPrecondition), Message: "not enough stock"}
+		if resp.StatusCode != http.StatusBadRequest || !proto.Equal(got, expected) {
+			tt.Error("expecting 400 with status, got", resp.StatusCode, string(b))
+		}
+	})
+
+	t.Run("expecting details of status kept", func(tt *testing.T) {
+		br := &errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "purchases", Description: "value must contain at least 1 item(s)"},
+		}}
+		gw, stop := newGateway(tt, &stubServer{
+			makeOrder: func(*pb.OrderRequest) (*pb.OrderResponse, error) {
+				st, err := status.New(codes.InvalidArgument, "invalid order").WithDetails(br)
+				if err != nil {
+					tt.Fatal(err)
+				}
+				return nil, st.Err()
+			},
+		})
+		defer stop()
+
+		resp, err := http.Post(gw.URL+"/v1/orders", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		got := &spb.Status{}
+		if err := protojson.Unmarshal(b, got); err != nil {
+			tt.Fatal(err, string(b))
+		}
+
+		details := status.FromProto(got).Details()
+		if resp.StatusCode != http.StatusBadRequest || len(details) != 1 || !proto.Equal(details[0].(proto.Message), br) {
+			tt.Error("expecting 400 with field violations, got", resp.StatusCode, string(b))
+		}
+	})
+
+	t.Run("expecting query parameters listed", func(tt *testing.T) {
+		gw, stop := newGateway(tt, &stubServer{})
+		defer stop()
+
+		resp, err := http.Get(gw.URL + "/v1/inventories?productIDs=1&productIDs=2")
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		got := &pb.ListInventoriesResponse{}
+		if err := protojson.Unmarshal(b, got); err != nil {
+			tt.Fatal(err, string(b))
+		}
+
+		expected := &pb.ListInventoriesResponse{Inventories: []*pb.Inventory{
+			{ProductID: 1, StockCount: 10},
+			{ProductID: 2, StockCount: 20},
+		}}
+		if resp.StatusCode != http.StatusOK || !proto.Equal(got, expected) {
+			tt.Error("expecting inventories, got", resp.StatusCode, string(b))
+		}
+	})
+}
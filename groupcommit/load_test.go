@@ -0,0 +1,158 @@
+package groupcommit
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+	repo "tomshop/repositories/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// loadParallelism goroutines per CPU order the hot product concurrently
+const loadParallelism = 16
+
+// simulatedTxn is how long a transaction takes between reading and committing when simulating
+const simulatedTxn = time.Millisecond
+
+// hotRow simulates a serializable transaction on a single row: it commits only if no one else
+// committed since it read the row, otherwise it is retried, as CockroachDB does with 40001 errors
+type hotRow struct {
+	mu      sync.Mutex
+	version int
+	txns    int
+}
+
+func (r *hotRow) txn(fn func()) {
+	for {
+		r.mu.Lock()
+		read := r.version
+		r.mu.Unlock()
+
+		fn()
+		time.Sleep(simulatedTxn)
+
+		r.mu.Lock()
+		r.txns++
+		if r.version == read {
+			r.version++
+			r.mu.Unlock()
+			return
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *hotRow) PlaceOrder(_ context.Context, _ repositories.OrderQuery, plan repositories.OrderPlan) error {
+	var err error
+	r.txn(func() {
+		_, _, err = plan(repositories.OrderSnapshot{})
+	})
+	return err
+}
+
+func (r *hotRow) PlaceOrders(_ context.Context, placements []repositories.OrderPlacement) []error {
+	errs := make([]error, len(placements))
+	r.txn(func() {
+		for i, p := range placements {
+			_, _, errs[i] = p.Plan(repositories.OrderSnapshot{})
+		}
+	})
+	return errs
+}
+
+func takeOne(productID int64) repositories.OrderPlan {
+	return func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		return []repositories.Order{{ProductID: productID, Quantity: 1}}, nil, nil
+	}
+}
+
+type placer interface {
+	PlaceOrder(context.Context, repositories.OrderQuery, repositories.OrderPlan) error
+}
+
+func loadHotProduct(b *testing.B, p placer, productID int64) {
+	query := repositories.OrderQuery{ProductIDs: []int64{productID}}
+	b.SetParallelism(loadParallelism)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := p.PlaceOrder(context.Background(), query, takeOne(productID)); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+// BenchmarkHotProduct_Simulated shows how many transactions orders of a hot product cost,
+// compare ns/op and the logged transactions of direct and grouped
+func BenchmarkHotProduct_Simulated(b *testing.B) {
+	b.Run("direct", func(b *testing.B) {
+		r := &hotRow{}
+		loadHotProduct(b, r, 1)
+		b.Logf("%d orders took %d transactions", b.N, r.txns)
+	})
+
+	b.Run("grouped", func(b *testing.B) {
+		r := &hotRow{}
+		loadHotProduct(b, NewQueue(r, 1), 1)
+		b.Logf("%d orders took %d transactions", b.N, r.txns)
+	})
+}
+
+// BenchmarkHotProduct_CockroachDB orders a product concurrently against DATABASE_ADDR,
+// orders are saved so the product is restocked before each run
+func BenchmarkHotProduct_CockroachDB(b *testing.B) {
+	addr := os.Getenv("DATABASE_ADDR")
+	if addr == "" {
+		b.Skip("DATABASE_ADDR is not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	const productID = 910000
+	b.Cleanup(func() {
+		// events of orders are found by stock movements of the product, so movements are deleted after them
+		for _, stmt := range []string{
+			`DELETE FROM outbox WHERE aggregate_type = 'order'
+			AND aggregate_id IN (SELECT reference FROM stock_movements WHERE product_id = $1)`,
+			`DELETE FROM outbox_sequences WHERE aggregate_type = 'order'
+			AND aggregate_id IN (SELECT reference FROM stock_movements WHERE product_id = $1)`,
+			"DELETE FROM outbox WHERE aggregate_type = 'product' AND aggregate_id = $1::STRING",
+			"DELETE FROM outbox_sequences WHERE aggregate_type = 'product' AND aggregate_id = $1::STRING",
+			"DELETE FROM stock_movements WHERE product_id = $1",
+			"DELETE FROM inventories WHERE id = $1",
+		} {
+			if _, err := db.Exec(stmt, productID); err != nil {
+				b.Error("cannot delete fixture product", err)
+			}
+		}
+	})
+
+	restock := func(b *testing.B) {
+		_, err := db.Exec("UPSERT INTO inventories (id, stock_count, version) VALUES ($1, 100000000, 0)", productID)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	r := repo.NewCockroachRepo(db)
+	b.Run("direct", func(b *testing.B) {
+		restock(b)
+		loadHotProduct(b, r, productID)
+	})
+
+	b.Run("grouped", func(b *testing.B) {
+		restock(b)
+		loadHotProduct(b, NewQueue(r, productID), productID)
+	})
+}
@@ -0,0 +1,206 @@
+// Package groupcommit serializes orders of hot products in process and saves them in groups,
+// so concurrent orders of a flash sale don't fight over the same rows and retry each other
+package groupcommit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tomshop/ctxlog"
+	"tomshop/repositories"
+)
+
+// groupTimeout is how long a group transaction may last for an order without deadline
+const groupTimeout = 10 * time.Second
+
+// Repo places orders one by one or in groups
+type Repo interface {
+	PlaceOrder(context.Context, repositories.OrderQuery, repositories.OrderPlan) error
+	PlaceOrders(context.Context, []repositories.OrderPlacement) []error
+}
+
+// Queue places orders of hot products in groups, other orders go straight to Repo.
+// Orders are queued per product, an order of several hot products waits in the queue of the smallest ID
+type Queue struct {
+	Repo Repo
+	// MaxBatch orders are placed in one transaction at most
+	MaxBatch int
+	// MaxWait is how long the first order of a group waits for others, zero for only grouping
+	// orders queued while the previous group was saved
+	MaxWait time.Duration
+
+	hot   map[int64]bool
+	mu    sync.Mutex
+	lanes map[int64]chan *job
+}
+
+type job struct {
+	ctx       context.Context
+	placement repositories.OrderPlacement
+	done      chan error
+}
+
+// NewQueue for hotProductIDs, groups are up to 32 orders
+func NewQueue(r Repo, hotProductIDs ...int64) *Queue {
+	q := &Queue{
+		Repo:     r,
+		MaxBatch: 32,
+		hot:      make(map[int64]bool, len(hotProductIDs)),
+		lanes:    make(map[int64]chan *job),
+	}
+	for _, id := range hotProductIDs {
+		q.hot[id] = true
+	}
+
+	return q
+}
+
+// PlaceOrder waits for its group to be saved if the order has hot products
+func (q *Queue) PlaceOrder(ctx context.Context, query repositories.OrderQuery, plan repositories.OrderPlan) error {
+	lane, ok := q.laneOf(query.ProductIDs)
+	if !ok {
+		return q.Repo.PlaceOrder(ctx, query, plan)
+	}
+
+	j := &job{
+		ctx:       ctx,
+		placement: repositories.OrderPlacement{Query: query, Plan: plan},
+		done:      make(chan error, 1),
+	}
+	select {
+	case lane <- j:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// once queued the order may still be saved after ctx is done, as when a request gives up on a transaction
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) laneOf(productIDs []int64) (chan *job, bool) {
+	var key int64
+	found := false
+	for _, id := range productIDs {
+		if q.hot[id] && (!found || id < key) {
+			key = id
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	lane, ok := q.lanes[key]
+	if !ok {
+		lane = make(chan *job, q.maxBatch())
+		q.lanes[key] = lane
+		go q.run(lane)
+	}
+
+	return lane, true
+}
+
+func (q *Queue) maxBatch() int {
+	if q.MaxBatch < 1 {
+		return 1
+	}
+
+	return q.MaxBatch
+}
+
+// run saves groups of lane one after another
+func (q *Queue) run(lane chan *job) {
+	for first := range lane {
+		q.place(q.collect(first, lane))
+	}
+}
+
+// collect orders following first until the group is full or MaxWait passed
+func (q *Queue) collect(first *job, lane chan *job) []*job {
+	group := []*job{first}
+	var timeout <-chan time.Time
+	if q.MaxWait > 0 {
+		timer := time.NewTimer(q.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for len(group) < q.maxBatch() {
+		if timeout == nil {
+			select {
+			case j := <-lane:
+				group = append(group, j)
+				continue
+			default:
+				return group
+			}
+		}
+
+		select {
+		case j := <-lane:
+			group = append(group, j)
+		case <-timeout:
+			return group
+		}
+	}
+
+	return group
+}
+
+// place orders of group whose requests are not yet gone
+func (q *Queue) place(group []*job) {
+	waiting := group[:0]
+	for _, j := range group {
+		if err := j.ctx.Err(); err != nil {
+			j.done <- err
+			continue
+		}
+		waiting = append(waiting, j)
+	}
+
+	if len(waiting) == 0 {
+		return
+	}
+
+	placements := make([]repositories.OrderPlacement, len(waiting))
+	for i, j := range waiting {
+		placements[i] = j.placement
+	}
+
+	ctx, cancel := groupContext(waiting)
+	defer cancel()
+	errs := q.Repo.PlaceOrders(ctx, placements)
+	for i, j := range waiting {
+		j.done <- errs[i]
+	}
+}
+
+// groupContext of a group transaction, it lasts until the latest deadline of jobs and logs with tags of all of them
+func groupContext(jobs []*job) (context.Context, context.CancelFunc) {
+	var deadline time.Time
+	for _, j := range jobs {
+		d, ok := j.ctx.Deadline()
+		if !ok {
+			d = time.Now().Add(groupTimeout)
+		}
+		if d.After(deadline) {
+			deadline = d
+		}
+	}
+
+	ctxs := make([]context.Context, len(jobs))
+	for i, j := range jobs {
+		ctxs[i] = j.ctx
+	}
+
+	return context.WithDeadline(ctxlog.Merge(context.Background(), ctxs...), deadline)
+}
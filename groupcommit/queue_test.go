@@ -0,0 +1,201 @@
+package groupcommit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"tomshop/ctxlog"
+	"tomshop/repositories"
+)
+
+type mockRepo struct {
+	placeOrder  func(context.Context, repositories.OrderQuery, repositories.OrderPlan) error
+	placeOrders func(context.Context, []repositories.OrderPlacement) []error
+}
+
+func (r *mockRepo) PlaceOrder(ctx context.Context, q repositories.OrderQuery, plan repositories.OrderPlan) error {
+	return r.placeOrder(ctx, q, plan)
+}
+
+func (r *mockRepo) PlaceOrders(ctx context.Context, placements []repositories.OrderPlacement) []error {
+	return r.placeOrders(ctx, placements)
+}
+
+func noopPlan(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+	return nil, nil, nil
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("expecting orders without hot products placed directly", func(tt *testing.T) {
+		direct := 0
+		q := NewQueue(&mockRepo{
+			placeOrder: func(context.Context, repositories.OrderQuery, repositories.OrderPlan) error {
+				direct++
+				return nil
+			},
+			placeOrders: func(context.Context, []repositories.OrderPlacement) []error {
+				tt.Fatal("must not be grouped")
+				return nil
+			},
+		}, 1)
+
+		err := q.PlaceOrder(context.Background(), repositories.OrderQuery{ProductIDs: []int64{2, 3}}, noopPlan)
+		if err != nil || direct != 1 {
+			tt.Fatalf("must place directly, got %v %d", err, direct)
+		}
+	})
+
+	t.Run("expecting orders queued while a group is saved grouped together", func(tt *testing.T) {
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var sizes []int
+		q := NewQueue(&mockRepo{
+			placeOrders: func(_ context.Context, placements []repositories.OrderPlacement) []error {
+				mu.Lock()
+				first := len(sizes) == 0
+				sizes = append(sizes, len(placements))
+				mu.Unlock()
+				if first {
+					<-release
+				}
+
+				return make([]error, len(placements))
+			},
+		}, 1)
+
+		var wg sync.WaitGroup
+		place := func() {
+			defer wg.Done()
+			if err := q.PlaceOrder(context.Background(), repositories.OrderQuery{ProductIDs: []int64{2, 1}}, noopPlan); err != nil {
+				tt.Error(err)
+			}
+		}
+
+		wg.Add(1)
+		go place()
+		for {
+			mu.Lock()
+			started := len(sizes) == 1
+			mu.Unlock()
+			if started {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		wg.Add(5)
+		for i := 0; i < 5; i++ {
+			go place()
+		}
+		for {
+			q.mu.Lock()
+			queued := len(q.lanes[1])
+			q.mu.Unlock()
+			if queued == 5 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+		wg.Wait()
+
+		if len(sizes) != 2 || sizes[0] != 1 || sizes[1] != 5 {
+			tt.Fatalf("must place groups of [1 5], got %v", sizes)
+		}
+	})
+
+	t.Run("expecting errors returned to their orders", func(tt *testing.T) {
+		q := NewQueue(&mockRepo{
+			placeOrders: func(_ context.Context, placements []repositories.OrderPlacement) []error {
+				errs := make([]error, len(placements))
+				for i, p := range placements {
+					_, _, errs[i] = p.Plan(repositories.OrderSnapshot{})
+				}
+				return errs
+			},
+		}, 1)
+
+		err := q.PlaceOrder(context.Background(), repositories.OrderQuery{ProductIDs: []int64{1}},
+			func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+				return nil, nil, context.DeadlineExceeded
+			})
+		if err != context.DeadlineExceeded {
+			tt.Fatalf("must return error of the plan, got %v", err)
+		}
+	})
+
+	t.Run("expecting gone requests not placed", func(tt *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		q := NewQueue(&mockRepo{
+			placeOrders: func(context.Context, []repositories.OrderPlacement) []error {
+				tt.Fatal("must not place")
+				return nil
+			},
+		}, 1)
+		q.MaxBatch = 0
+
+		// lane has room, so the order may be queued before ctx is checked
+		err := q.PlaceOrder(ctx, repositories.OrderQuery{ProductIDs: []int64{1}}, noopPlan)
+		if err != context.Canceled {
+			tt.Fatalf("must return context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("expecting caller not waiting for its group after ctx is done", func(tt *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		q := NewQueue(&mockRepo{
+			placeOrders: func(_ context.Context, placements []repositories.OrderPlacement) []error {
+				<-release
+				return make([]error, len(placements))
+			},
+		}, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := q.PlaceOrder(ctx, repositories.OrderQuery{ProductIDs: []int64{1}}, noopPlan)
+		if err != context.DeadlineExceeded {
+			tt.Fatalf("must return context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("expecting group lasting until the latest deadline with tags of all orders", func(tt *testing.T) {
+		latest := time.Now().Add(time.Hour)
+		contexts := make(chan context.Context, 1)
+		q := NewQueue(&mockRepo{
+			placeOrders: func(ctx context.Context, placements []repositories.OrderPlacement) []error {
+				contexts <- ctx
+				return make([]error, len(placements))
+			},
+		}, 1)
+		q.MaxWait = time.Hour
+		q.MaxBatch = 2
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		for i, d := range []time.Time{latest.Add(-time.Minute), latest} {
+			ctx, cancel := context.WithDeadline(ctxlog.With(context.Background(), "request_id", strconv.Itoa(i)), d)
+			defer cancel()
+			go func() {
+				defer wg.Done()
+				if err := q.PlaceOrder(ctx, repositories.OrderQuery{ProductIDs: []int64{1}}, noopPlan); err != nil {
+					tt.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		ctx := <-contexts
+		if d, ok := ctx.Deadline(); !ok || !d.Equal(latest) {
+			tt.Error("expecting deadline of the group at the latest one, got", d, ok)
+		}
+
+		if p := ctxlog.Prefix(ctx); p != "[request_id=0,1] " && p != "[request_id=1,0] " {
+			tt.Errorf("expecting request IDs of both orders, got %q", p)
+		}
+	})
+}
@@ -1,36 +1,145 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	_ "expvar"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"tomshop/admission"
+	"tomshop/alerts"
+	"tomshop/allocation"
+	"tomshop/breaker"
+	"tomshop/domain"
+	"tomshop/gateway"
+	"tomshop/groupcommit"
 	pb "tomshop/grpc"
+	pbv2 "tomshop/grpc/v2"
+	"tomshop/interceptors"
+	"tomshop/outbox"
 	repo "tomshop/repositories/sql"
 	"tomshop/services"
+	"tomshop/stockcache"
+	"tomshop/watch"
+	"tomshop/webrpc"
 
-	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"buf.build/go/protovalidate"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	_ "github.com/lib/pq"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	health "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 var (
 	port = os.Getenv("PORT") // default ":50051"
+	// REST/JSON gateway proxying to PORT, default ":8080"
+	gatewayPort = os.Getenv("GATEWAY_PORT")
+	// native gRPC over h2c, gRPC-Web and Connect are served on this port if set, e.g. ":8081"
+	webPort = os.Getenv("WEB_PORT")
+	// comma separated origins allowed to call WEB_PORT from browsers, "*" for any, empty for none
+	corsAllowedOrigins = os.Getenv("CORS_ALLOWED_ORIGINS")
+	// comma separated request headers allowed besides the headers of gRPC-Web and Connect
+	corsAllowedHeaders = os.Getenv("CORS_ALLOWED_HEADERS")
+	// how long browsers cache preflight responses, browser default if empty
+	corsMaxAge = os.Getenv("CORS_MAX_AGE")
+	// one of "preferred", "fewest_shipments", "nearest", empty for not using warehouses
+	allocationStrategy   = os.Getenv("ALLOCATION_STRATEGY")
+	preferredWarehouseID = os.Getenv("PREFERRED_WAREHOUSE_ID")
+	// for "nearest", in format "region:neighbour1,neighbour2;region2:neighbour1"
+	regionNeighbours = os.Getenv("REGION_NEIGHBOURS")
+	// "stdout" or "file:<path>", empty for relaying outbox events to low stock alerts only
+	outboxPublisher = os.Getenv("OUTBOX_PUBLISHER")
+	// how long published events are kept, default "168h"
+	outboxRetention = os.Getenv("OUTBOX_RETENTION")
+	// low stock alerts are always logged and streamed, also posted to this URL if set
+	lowStockWebhook = os.Getenv("LOW_STOCK_WEBHOOK")
+	// comma separated IDs of products whose orders are saved in groups, e.g. for a flash sale
+	hotProducts = os.Getenv("HOT_PRODUCTS")
+	// how long an order of a hot product waits for others to be grouped with, default "0s"
+	hotMaxWait = os.Getenv("HOT_MAX_WAIT")
+	// deadline of unary RPCs when the client set none or a later one, default "10s", "0" for none
+	rpcTimeout = os.Getenv("RPC_TIMEOUT")
+	// latency RPCs doing DB work should keep, their concurrency is limited to keep it if set, e.g. "200ms"
+	admissionTargetLatency = os.Getenv("ADMISSION_TARGET_LATENCY")
+	// most RPCs doing DB work at once however fast they are, default 500
+	admissionMaxLimit = os.Getenv("ADMISSION_MAX_LIMIT")
+	// consecutive orders failed by the database being down before the circuit breaker refuses orders
+	// without trying it, e.g. "5", orders always try if unset
+	breakerFailures = os.Getenv("BREAKER_FAILURES")
+	// how long the circuit breaker refuses orders before trying the database again, default "5s"
+	breakerOpenTimeout = os.Getenv("BREAKER_OPEN_TIMEOUT")
+	// retries of transactions failed by contention, default 10, negative for never retrying
+	txnMaxRetries = os.Getenv("TXN_MAX_RETRIES")
+	// backoff before the first retry, doubled by every retry up to TXN_MAX_BACKOFF, default "10ms" and "1s"
+	txnInitialBackoff = os.Getenv("TXN_INITIAL_BACKOFF")
+	txnMaxBackoff     = os.Getenv("TXN_MAX_BACKOFF")
+	// "strong" or "exact", how reads allowing staleness are served, default "strong"
+	readPolicy = os.Getenv("READ_POLICY")
+	// how stale "exact" reads are, they are served as of exactly that long ago, default "10s"
+	readStaleness = os.Getenv("READ_STALENESS")
+	// read only DSN serving reads allowing staleness if set, not allowed with the "strong" policy
+	readDatabaseAddr = os.Getenv("READ_DATABASE_ADDR")
+	// how long stock listed by ListInventories is cached, empty for not caching
+	stockCacheTTL = os.Getenv("STOCK_CACHE_TTL")
+	// how many products are cached at most, default 10000
+	stockCacheSize = os.Getenv("STOCK_CACHE_SIZE")
+	// "true" registers gRPC server reflection for tools like grpcurl
+	reflectionEnabled = os.Getenv("REFLECTION")
+	// expvar counters are served at /debug/vars of this address if set, e.g. ":9090", it must not
+	// share the port of PORT, GATEWAY_PORT or WEB_PORT
+	metricsAddr = os.Getenv("METRICS_ADDR")
 )
 
 func main() {
 	if port == "" {
 		port = ":50051"
 	}
+	if gatewayPort == "" {
+		gatewayPort = ":8080"
+	}
+	if metricsAddr != "" {
+		for name, addr := range map[string]string{"PORT": port, "GATEWAY_PORT": gatewayPort, "WEB_PORT": webPort} {
+			if samePort(metricsAddr, addr) {
+				log.Fatalf("METRICS_ADDR %s shares the port of %s %s", metricsAddr, name, addr)
+			}
+		}
+	}
 
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer(grpc.UnaryInterceptor(grpc_recovery.UnaryServerInterceptor()))
+	validator, err := protovalidate.New()
+	if err != nil {
+		log.Fatal("error creating validator: ", err)
+	}
+
+	limiter := newAdmissionLimiter()
+	s := grpc.NewServer(
+		// request ID comes first so its tags are logged by later interceptors, including panics
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
+			interceptors.RequestID(),
+			interceptors.Recovery(),
+			// orders of batches are validated one by one by the service
+			interceptors.Validate(validator, pb.TomShop_MakeOrders_FullMethodName),
+			newAdmission(limiter),
+			interceptors.Deadline(parseDuration("RPC_TIMEOUT", rpcTimeout, 10*time.Second)),
+		)),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
+			interceptors.RequestIDStream(),
+			interceptors.RecoveryStream(),
+			interceptors.ValidateStream(validator, pb.TomShop_StreamOrders_FullMethodName),
+		)),
+	)
 
 	db, err := sql.Open("postgres", os.Getenv("DATABASE_ADDR"))
 	if err != nil {
@@ -38,14 +147,310 @@ func main() {
 	}
 
 	// manual dependencies injection still work
-	pb.RegisterTomShopServer(s, &services.OrderService{
-		Repo: repo.NewCockroachRepo(db),
-	})
+	r := repo.NewCockroachRepo(db)
+	r.Retry = newRetryPolicy()
+	r.Reads = newReadRouting()
+	b := watch.NewBroadcaster()
+	stream := &alerts.StreamNotifier{}
+	checker := &alerts.Checker{Repo: r, Notifier: newNotifier(stream)}
+	cache := newStockCache(r)
+	orders := &domain.OrderService{
+		Repo:        r,
+		Batches:     r,
+		Allocator:   newAllocator(),
+		Broadcaster: b,
+		Cache:       cache,
+	}
+	if q := newGroupCommitQueue(r); q != nil {
+		orders.Repo = q
+	}
+	cb := newBreaker()
+	if cb != nil {
+		orders.Repo = breaker.NewOrderRepo(orders.Repo, cb)
+		orders.Batches = breaker.NewOrderBatchRepo(r, cb)
+	}
+	shop := &services.TomShop{
+		OrderService: &services.OrderService{Orders: orders, Validator: validator, Admission: limiter},
+		InventoryService: &services.InventoryService{
+			Repo:        r,
+			Broadcaster: b,
+			Cache:       cache,
+			Warehouses:  orders.Allocator != nil,
+		},
+		AlertService: &services.AlertService{
+			Repo:   r,
+			Alerts: checker,
+			Stream: stream,
+		},
+	}
+	pb.RegisterTomShopServer(s, shop)
+	pbv2.RegisterTomShopServer(s, shop)
+
+	health.RegisterHealthServer(s, &services.HealthcheckService{Breaker: cb})
+	if reflectionEnabled == "true" {
+		reflection.Register(s)
+	}
 
-	health.RegisterHealthServer(s, &services.HealthcheckService{})
+	go newOutboxRelay(r, checker).Run(context.Background())
+
+	if metricsAddr != "" {
+		go func() {
+			log.Fatal(http.ListenAndServe(metricsAddr, nil))
+		}()
+	}
+
+	go serveGateway()
+	if webPort != "" {
+		go serveWeb(s)
+	}
 
 	log.Println("GRPC server listening on ", port)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+func serveGateway() {
+	grpcAddr := port
+	if strings.HasPrefix(grpcAddr, ":") {
+		grpcAddr = "localhost" + grpcAddr
+	}
+
+	h, err := gateway.NewHandler(
+		context.Background(),
+		grpcAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		log.Fatal("error creating gateway: ", err)
+	}
+
+	log.Println("REST gateway listening on ", gatewayPort)
+	log.Fatal(http.ListenAndServe(gatewayPort, h))
+}
+
+func serveWeb(s *grpc.Server) {
+	cors := webrpc.CORS{MaxAge: parseDuration("CORS_MAX_AGE", corsMaxAge, 0)}
+	if corsAllowedOrigins != "" {
+		cors.AllowedOrigins = strings.Split(corsAllowedOrigins, ",")
+	}
+	if corsAllowedHeaders != "" {
+		cors.AllowedHeaders = strings.Split(corsAllowedHeaders, ",")
+	}
+
+	log.Println("gRPC, gRPC-Web and Connect listening on ", webPort)
+	log.Fatal(http.ListenAndServe(webPort, webrpc.NewHandler(s, cors)))
+}
+
+func newAllocator() allocation.Strategy {
+	switch allocationStrategy {
+	case "":
+		return nil
+	case "preferred":
+		id, err := strconv.ParseInt(preferredWarehouseID, 10, 64)
+		if err != nil && preferredWarehouseID != "" {
+			log.Fatal("invalid PREFERRED_WAREHOUSE_ID: ", err)
+		}
+		return allocation.PreferredWarehouse{WarehouseID: id}
+	case "fewest_shipments":
+		return allocation.FewestShipments{}
+	case "nearest":
+		neighbours := map[string][]string{}
+		for _, entry := range strings.Split(regionNeighbours, ";") {
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) == 2 {
+				neighbours[parts[0]] = strings.Split(parts[1], ",")
+			}
+		}
+		return allocation.NearestRegion{Neighbours: neighbours}
+	}
+
+	log.Fatal("unknown ALLOCATION_STRATEGY: ", allocationStrategy)
+	return nil
+}
+
+func newGroupCommitQueue(r *repo.CockroachRepo) *groupcommit.Queue {
+	if hotProducts == "" {
+		return nil
+	}
+
+	var ids []int64
+	for _, s := range strings.Split(hotProducts, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			log.Fatal("invalid HOT_PRODUCTS: ", err)
+		}
+		ids = append(ids, id)
+	}
+
+	q := groupcommit.NewQueue(r, ids...)
+	q.MaxWait = parseDuration("HOT_MAX_WAIT", hotMaxWait, 0)
+
+	return q
+}
+
+func newBreaker() *breaker.Breaker {
+	if breakerFailures == "" {
+		return nil
+	}
+
+	b := breaker.NewBreaker()
+	b.Unavailable = repo.Unavailable
+	b.OpenTimeout = parseDuration("BREAKER_OPEN_TIMEOUT", breakerOpenTimeout, b.OpenTimeout)
+	var err error
+	if b.FailureThreshold, err = strconv.Atoi(breakerFailures); err != nil {
+		log.Fatal("invalid BREAKER_FAILURES: ", err)
+	}
+
+	return b
+}
+
+func newStockCache(r *repo.CockroachRepo) *stockcache.Cache {
+	if stockCacheTTL == "" {
+		return nil
+	}
+
+	c := stockcache.NewCache(r)
+	c.TTL = parseDuration("STOCK_CACHE_TTL", stockCacheTTL, 0)
+	if stockCacheSize != "" {
+		var err error
+		if c.MaxEntries, err = strconv.Atoi(stockCacheSize); err != nil {
+			log.Fatal("invalid STOCK_CACHE_SIZE: ", err)
+		}
+	}
+
+	return c
+}
+
+func newRetryPolicy() repo.RetryPolicy {
+	p := repo.RetryPolicy{
+		InitialBackoff: parseDuration("TXN_INITIAL_BACKOFF", txnInitialBackoff, 0),
+		MaxBackoff:     parseDuration("TXN_MAX_BACKOFF", txnMaxBackoff, 0),
+	}
+	if txnMaxRetries != "" {
+		var err error
+		if p.MaxRetries, err = strconv.Atoi(txnMaxRetries); err != nil {
+			log.Fatal("invalid TXN_MAX_RETRIES: ", err)
+		}
+	}
+
+	return p
+}
+
+func newReadRouting() repo.ReadRouting {
+	policy, err := repo.ParseReadPolicy(readPolicy)
+	if err != nil {
+		log.Fatal("invalid READ_POLICY: ", err)
+	}
+	if readDatabaseAddr != "" && policy == repo.ReadStrong {
+		log.Fatal("READ_DATABASE_ADDR is never used by the strong READ_POLICY, set \"exact\"")
+	}
+
+	routing := repo.ReadRouting{
+		Policy:    policy,
+		Staleness: parseDuration("READ_STALENESS", readStaleness, 0),
+	}
+	if readDatabaseAddr != "" {
+		if routing.Replica, err = sql.Open("postgres", readDatabaseAddr); err != nil {
+			log.Fatal("error connecting to the read database: ", err)
+		}
+	}
+
+	return routing
+}
+
+// newAdmissionLimiter if ADMISSION_TARGET_LATENCY is set, nil for not limiting
+func newAdmissionLimiter() *admission.Limiter {
+	if admissionTargetLatency == "" {
+		return nil
+	}
+
+	l := admission.NewLimiter(parseDuration("ADMISSION_TARGET_LATENCY", admissionTargetLatency, 0))
+	if admissionMaxLimit != "" {
+		var err error
+		if l.MaxLimit, err = strconv.Atoi(admissionMaxLimit); err != nil {
+			log.Fatal("invalid ADMISSION_MAX_LIMIT: ", err)
+		}
+	}
+
+	return l
+}
+
+// newAdmission limits RPCs doing DB work by l if set, reads are shed first. Batches of MakeOrders and
+// StreamOrders are admitted one by one by services.OrderService
+func newAdmission(l *admission.Limiter) grpc.UnaryServerInterceptor {
+	if l == nil {
+		return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(ctx, req)
+		}
+	}
+
+	return interceptors.Admission(l, map[string]admission.Priority{
+		pb.TomShop_MakeOrder_FullMethodName:            admission.Critical,
+		pbv2.TomShop_PlaceOrder_FullMethodName:         admission.Critical,
+		pb.TomShop_ChangeStock_FullMethodName:          admission.Critical,
+		pb.TomShop_SetStockThreshold_FullMethodName:    admission.Critical,
+		pb.TomShop_SetFulfillmentPolicy_FullMethodName: admission.Critical,
+		pb.TomShop_GetStockHistory_FullMethodName:      admission.Sheddable,
+		pb.TomShop_ListInventories_FullMethodName:      admission.Sheddable,
+		pb.TomShop_ListLowStock_FullMethodName:         admission.Sheddable,
+	})
+}
+
+// parseDuration of env variable name, def if not set
+func parseDuration(name, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", name, err)
+	}
+
+	return d
+}
+
+func newNotifier(stream *alerts.StreamNotifier) alerts.Notifier {
+	notifiers := alerts.Notifiers{alerts.LogNotifier{}, stream}
+	if lowStockWebhook != "" {
+		notifiers = append(notifiers, &alerts.WebhookNotifier{
+			URL:    lowStockWebhook,
+			Client: &http.Client{Timeout: 5 * time.Second},
+		})
+	}
+
+	return notifiers
+}
+
+// newOutboxRelay always relays to checker, which delivers low stock alerts
+func newOutboxRelay(r *repo.CockroachRepo, checker *alerts.Checker) *outbox.Relay {
+	publishers := outbox.Publishers{checker}
+	switch {
+	case outboxPublisher == "":
+	case outboxPublisher == "stdout":
+		publishers = append(publishers, outbox.NewWriterPublisher(os.Stdout))
+	case strings.HasPrefix(outboxPublisher, "file:"):
+		f, err := os.OpenFile(strings.TrimPrefix(outboxPublisher, "file:"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal("cannot open outbox file: ", err)
+		}
+		publishers = append(publishers, outbox.NewWriterPublisher(f))
+	default:
+		log.Fatal("unknown OUTBOX_PUBLISHER: ", outboxPublisher)
+	}
+
+	return &outbox.Relay{
+		Repo:      r,
+		Publisher: publishers,
+		Retention: parseDuration("OUTBOX_RETENTION", outboxRetention, 0),
+	}
+}
+
+// samePort tells if addresses a and b listen on the same port, whatever their hosts
+func samePort(a, b string) bool {
+	_, portA, errA := net.SplitHostPort(a)
+	_, portB, errB := net.SplitHostPort(b)
+
+	return errA == nil && errB == nil && portA == portB
+}
@@ -1,942 +1,1849 @@
-// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
 // source: service.proto
 
 package tomshop_v1
 
 import (
-	context "context"
-	fmt "fmt"
-	proto "github.com/gogo/protobuf/proto"
-	grpc "google.golang.org/grpc"
-	io "io"
-	math "math"
+	_ "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
-	strings "strings"
+	sync "sync"
+	unsafe "unsafe"
 )
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ = proto.Marshal
-var _ = fmt.Errorf
-var _ = math.Inf
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FulfillmentMode int32
+
+const (
+	// ALL_OR_NOTHING fails the whole order if any line cannot be fulfilled
+	FulfillmentMode_ALL_OR_NOTHING FulfillmentMode = 0
+	// BEST_EFFORT takes whatever in stock for every line
+	FulfillmentMode_BEST_EFFORT FulfillmentMode = 1
+	// PER_LINE_MINIMUM takes whatever in stock for lines can get at least their minQuantity
+	FulfillmentMode_PER_LINE_MINIMUM FulfillmentMode = 2
+)
+
+// Enum value maps for FulfillmentMode.
+var (
+	FulfillmentMode_name = map[int32]string{
+		0: "ALL_OR_NOTHING",
+		1: "BEST_EFFORT",
+		2: "PER_LINE_MINIMUM",
+	}
+	FulfillmentMode_value = map[string]int32{
+		"ALL_OR_NOTHING":   0,
+		"BEST_EFFORT":      1,
+		"PER_LINE_MINIMUM": 2,
+	}
+)
+
+func (x FulfillmentMode) Enum() *FulfillmentMode {
+	p := new(FulfillmentMode)
+	*p = x
+	return p
+}
+
+func (x FulfillmentMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FulfillmentMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_service_proto_enumTypes[0].Descriptor()
+}
+
+func (FulfillmentMode) Type() protoreflect.EnumType {
+	return &file_service_proto_enumTypes[0]
+}
+
+func (x FulfillmentMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FulfillmentMode.Descriptor instead.
+func (FulfillmentMode) EnumDescriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{0}
+}
+
+type StockMovementReason int32
+
+const (
+	StockMovementReason_UNKNOWN_REASON  StockMovementReason = 0
+	StockMovementReason_ORDER           StockMovementReason = 1
+	StockMovementReason_RESTOCK         StockMovementReason = 2
+	StockMovementReason_CANCELLATION    StockMovementReason = 3
+	StockMovementReason_CORRECTION      StockMovementReason = 4
+	StockMovementReason_OPENING_BALANCE StockMovementReason = 5
+	// BACKORDERED items of a restock or cancellation are held for backordered orders instead of added to stock
+	StockMovementReason_BACKORDERED StockMovementReason = 6
+)
+
+// Enum value maps for StockMovementReason.
+var (
+	StockMovementReason_name = map[int32]string{
+		0: "UNKNOWN_REASON",
+		1: "ORDER",
+		2: "RESTOCK",
+		3: "CANCELLATION",
+		4: "CORRECTION",
+		5: "OPENING_BALANCE",
+		6: "BACKORDERED",
+	}
+	StockMovementReason_value = map[string]int32{
+		"UNKNOWN_REASON":  0,
+		"ORDER":           1,
+		"RESTOCK":         2,
+		"CANCELLATION":    3,
+		"CORRECTION":      4,
+		"OPENING_BALANCE": 5,
+		"BACKORDERED":     6,
+	}
+)
+
+func (x StockMovementReason) Enum() *StockMovementReason {
+	p := new(StockMovementReason)
+	*p = x
+	return p
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the proto package it is being compiled against.
-// A compilation error at this line likely means your copy of the
-// proto package needs to be updated.
-const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
+func (x StockMovementReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StockMovementReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_service_proto_enumTypes[1].Descriptor()
+}
+
+func (StockMovementReason) Type() protoreflect.EnumType {
+	return &file_service_proto_enumTypes[1]
+}
+
+func (x StockMovementReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StockMovementReason.Descriptor instead.
+func (StockMovementReason) EnumDescriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{1}
+}
+
+type FulfillmentPolicyKind int32
+
+const (
+	// STOCK_ONLY sells only what is in stock, default for products without policy
+	FulfillmentPolicyKind_STOCK_ONLY FulfillmentPolicyKind = 0
+	// BACKORDER sells more than stock, up to limit items waiting for restock
+	FulfillmentPolicyKind_BACKORDER FulfillmentPolicyKind = 1
+	// PREORDER sells up to limit items before releaseAt, nothing is taken from stock
+	FulfillmentPolicyKind_PREORDER FulfillmentPolicyKind = 2
+)
+
+// Enum value maps for FulfillmentPolicyKind.
+var (
+	FulfillmentPolicyKind_name = map[int32]string{
+		0: "STOCK_ONLY",
+		1: "BACKORDER",
+		2: "PREORDER",
+	}
+	FulfillmentPolicyKind_value = map[string]int32{
+		"STOCK_ONLY": 0,
+		"BACKORDER":  1,
+		"PREORDER":   2,
+	}
+)
+
+func (x FulfillmentPolicyKind) Enum() *FulfillmentPolicyKind {
+	p := new(FulfillmentPolicyKind)
+	*p = x
+	return p
+}
+
+func (x FulfillmentPolicyKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FulfillmentPolicyKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_service_proto_enumTypes[2].Descriptor()
+}
+
+func (FulfillmentPolicyKind) Type() protoreflect.EnumType {
+	return &file_service_proto_enumTypes[2]
+}
+
+func (x FulfillmentPolicyKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FulfillmentPolicyKind.Descriptor instead.
+func (FulfillmentPolicyKind) EnumDescriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{2}
+}
 
 type Order struct {
-	ProductID int64 `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
-	Quantity  int64 `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Quantity  int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// minQuantity used by PER_LINE_MINIMUM mode, 0 means the whole quantity
+	MinQuantity   int64 `protobuf:"varint,3,opt,name=minQuantity,proto3" json:"minQuantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (m *Order) Reset()      { *m = Order{} }
 func (*Order) ProtoMessage() {}
-func (*Order) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0b84a42fa06f626, []int{0}
-}
-func (m *Order) XXX_Unmarshal(b []byte) error {
-	return m.Unmarshal(b)
-}
-func (m *Order) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	if deterministic {
-		return xxx_messageInfo_Order.Marshal(b, m, deterministic)
-	} else {
-		b = b[:cap(b)]
-		n, err := m.MarshalTo(b)
-		if err != nil {
-			return nil, err
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		return b[:n], nil
+		return ms
 	}
+	return mi.MessageOf(x)
 }
-func (m *Order) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Order.Merge(m, src)
-}
-func (m *Order) XXX_Size() int {
-	return m.Size()
-}
-func (m *Order) XXX_DiscardUnknown() {
-	xxx_messageInfo_Order.DiscardUnknown(m)
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{0}
 }
 
-var xxx_messageInfo_Order proto.InternalMessageInfo
+func (x *Order) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
 
-func (m *Order) GetProductID() int64 {
-	if m != nil {
-		return m.ProductID
+func (x *Order) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
 	}
 	return 0
 }
 
-func (m *Order) GetQuantity() int64 {
-	if m != nil {
-		return m.Quantity
+func (x *Order) GetMinQuantity() int64 {
+	if x != nil {
+		return x.MinQuantity
 	}
 	return 0
 }
 
 type OrderRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// purchases of different products, at most 100
 	Purchases []*Order `protobuf:"bytes,1,rep,name=purchases,proto3" json:"purchases,omitempty"`
+	// customerID is required by coupons with per customer limit
+	CustomerID  int64    `protobuf:"varint,2,opt,name=customerID,proto3" json:"customerID,omitempty"`
+	CouponCodes []string `protobuf:"bytes,3,rep,name=couponCodes,proto3" json:"couponCodes,omitempty"`
+	// hints for the warehouse allocation strategy configured in server
+	RegionHint           string `protobuf:"bytes,4,opt,name=regionHint,proto3" json:"regionHint,omitempty"`
+	PreferredWarehouseID int64  `protobuf:"varint,5,opt,name=preferredWarehouseID,proto3" json:"preferredWarehouseID,omitempty"`
+	// allowBackorder accepts backordered or preordered lines for products have such policy
+	AllowBackorder bool `protobuf:"varint,6,opt,name=allowBackorder,proto3" json:"allowBackorder,omitempty"`
+	// fulfillmentMode other than ALL_OR_NOTHING doesn't backorder
+	FulfillmentMode FulfillmentMode `protobuf:"varint,7,opt,name=fulfillmentMode,proto3,enum=tomshop.v1.FulfillmentMode" json:"fulfillmentMode,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *OrderRequest) Reset() {
+	*x = OrderRequest{}
+	mi := &file_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (m *OrderRequest) Reset()      { *m = OrderRequest{} }
 func (*OrderRequest) ProtoMessage() {}
-func (*OrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0b84a42fa06f626, []int{1}
-}
-func (m *OrderRequest) XXX_Unmarshal(b []byte) error {
-	return m.Unmarshal(b)
-}
-func (m *OrderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	if deterministic {
-		return xxx_messageInfo_OrderRequest.Marshal(b, m, deterministic)
-	} else {
-		b = b[:cap(b)]
-		n, err := m.MarshalTo(b)
-		if err != nil {
-			return nil, err
+
+func (x *OrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		return b[:n], nil
+		return ms
 	}
+	return mi.MessageOf(x)
 }
-func (m *OrderRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_OrderRequest.Merge(m, src)
-}
-func (m *OrderRequest) XXX_Size() int {
-	return m.Size()
+
+// Deprecated: Use OrderRequest.ProtoReflect.Descriptor instead.
+func (*OrderRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{1}
 }
-func (m *OrderRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_OrderRequest.DiscardUnknown(m)
+
+func (x *OrderRequest) GetPurchases() []*Order {
+	if x != nil {
+		return x.Purchases
+	}
+	return nil
 }
 
-var xxx_messageInfo_OrderRequest proto.InternalMessageInfo
+func (x *OrderRequest) GetCustomerID() int64 {
+	if x != nil {
+		return x.CustomerID
+	}
+	return 0
+}
 
-func (m *OrderRequest) GetPurchases() []*Order {
-	if m != nil {
-		return m.Purchases
+func (x *OrderRequest) GetCouponCodes() []string {
+	if x != nil {
+		return x.CouponCodes
 	}
 	return nil
 }
 
-type OrderResponse struct {
-	Successful bool `protobuf:"varint,1,opt,name=successful,proto3" json:"successful,omitempty"`
+func (x *OrderRequest) GetRegionHint() string {
+	if x != nil {
+		return x.RegionHint
+	}
+	return ""
 }
 
-func (m *OrderResponse) Reset()      { *m = OrderResponse{} }
-func (*OrderResponse) ProtoMessage() {}
-func (*OrderResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0b84a42fa06f626, []int{2}
-}
-func (m *OrderResponse) XXX_Unmarshal(b []byte) error {
-	return m.Unmarshal(b)
-}
-func (m *OrderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	if deterministic {
-		return xxx_messageInfo_OrderResponse.Marshal(b, m, deterministic)
-	} else {
-		b = b[:cap(b)]
-		n, err := m.MarshalTo(b)
-		if err != nil {
-			return nil, err
-		}
-		return b[:n], nil
+func (x *OrderRequest) GetPreferredWarehouseID() int64 {
+	if x != nil {
+		return x.PreferredWarehouseID
 	}
+	return 0
 }
-func (m *OrderResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_OrderResponse.Merge(m, src)
+
+func (x *OrderRequest) GetAllowBackorder() bool {
+	if x != nil {
+		return x.AllowBackorder
+	}
+	return false
 }
-func (m *OrderResponse) XXX_Size() int {
-	return m.Size()
+
+func (x *OrderRequest) GetFulfillmentMode() FulfillmentMode {
+	if x != nil {
+		return x.FulfillmentMode
+	}
+	return FulfillmentMode_ALL_OR_NOTHING
 }
-func (m *OrderResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_OrderResponse.DiscardUnknown(m)
+
+type Allocation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductID     int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	WarehouseID   int64                  `protobuf:"varint,2,opt,name=warehouseID,proto3" json:"warehouseID,omitempty"`
+	Quantity      int64                  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-var xxx_messageInfo_OrderResponse proto.InternalMessageInfo
+func (x *Allocation) Reset() {
+	*x = Allocation{}
+	mi := &file_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-func (m *OrderResponse) GetSuccessful() bool {
-	if m != nil {
-		return m.Successful
-	}
-	return false
+func (x *Allocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func init() {
-	proto.RegisterType((*Order)(nil), "tomshop.v1.Order")
-	proto.RegisterType((*OrderRequest)(nil), "tomshop.v1.OrderRequest")
-	proto.RegisterType((*OrderResponse)(nil), "tomshop.v1.OrderResponse")
-}
-
-func init() { proto.RegisterFile("service.proto", fileDescriptor_a0b84a42fa06f626) }
-
-var fileDescriptor_a0b84a42fa06f626 = []byte{
-	// 270 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2d, 0x4e, 0x2d, 0x2a,
-	0xcb, 0x4c, 0x4e, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x2a, 0xc9, 0xcf, 0x2d, 0xce,
-	0xc8, 0x2f, 0xd0, 0x2b, 0x33, 0x54, 0x72, 0xe4, 0x62, 0xf5, 0x2f, 0x4a, 0x49, 0x2d, 0x12, 0x92,
-	0xe1, 0xe2, 0x2c, 0x28, 0xca, 0x4f, 0x29, 0x4d, 0x2e, 0xf1, 0x74, 0x91, 0x60, 0x54, 0x60, 0xd4,
-	0x60, 0x0e, 0x42, 0x08, 0x08, 0x49, 0x71, 0x71, 0x14, 0x96, 0x26, 0xe6, 0x95, 0x64, 0x96, 0x54,
-	0x4a, 0x30, 0x81, 0x25, 0xe1, 0x7c, 0x25, 0x7b, 0x2e, 0x1e, 0xb0, 0x11, 0x41, 0xa9, 0x85, 0xa5,
-	0xa9, 0xc5, 0x25, 0x42, 0xfa, 0x5c, 0x9c, 0x05, 0xa5, 0x45, 0xc9, 0x19, 0x89, 0xc5, 0xa9, 0xc5,
-	0x12, 0x8c, 0x0a, 0xcc, 0x1a, 0xdc, 0x46, 0x82, 0x7a, 0x08, 0x2b, 0xf5, 0x20, 0x8a, 0x11, 0x6a,
-	0x94, 0xf4, 0xb9, 0x78, 0xa1, 0x06, 0x14, 0x17, 0xe4, 0xe7, 0x15, 0xa7, 0x0a, 0xc9, 0x71, 0x71,
-	0x15, 0x97, 0x26, 0x27, 0xa7, 0x16, 0x17, 0xa7, 0x95, 0xe6, 0x80, 0x1d, 0xc3, 0x11, 0x84, 0x24,
-	0x62, 0xe4, 0xcd, 0xc5, 0x1e, 0x92, 0x9f, 0x1b, 0x9c, 0x91, 0x5f, 0x20, 0xe4, 0xc0, 0xc5, 0xe9,
-	0x9b, 0x98, 0x9d, 0x0a, 0xf1, 0x83, 0x04, 0xa6, 0x35, 0x10, 0x37, 0x49, 0x49, 0x62, 0x91, 0x81,
-	0x58, 0xe6, 0x64, 0x72, 0xe1, 0xa1, 0x1c, 0xc3, 0x8d, 0x87, 0x72, 0x0c, 0x1f, 0x1e, 0xca, 0x31,
-	0x36, 0x3c, 0x92, 0x63, 0x5c, 0xf1, 0x48, 0x8e, 0xf1, 0xc4, 0x23, 0x39, 0xc6, 0x0b, 0x8f, 0xe4,
-	0x18, 0x1f, 0x3c, 0x92, 0x63, 0x7c, 0xf1, 0x48, 0x8e, 0xe1, 0xc3, 0x23, 0x39, 0xc6, 0x09, 0x8f,
-	0xe5, 0x18, 0x2e, 0x3c, 0x96, 0x63, 0xb8, 0xf1, 0x58, 0x8e, 0x21, 0x89, 0x0d, 0x1c, 0x94, 0xc6,
-	0x80, 0x00, 0x00, 0x00, 0xff, 0xff, 0xe6, 0x94, 0x2d, 0x7b, 0x5b, 0x01, 0x00, 0x00,
-}
-
-func (this *Order) Equal(that interface{}) bool {
-	if that == nil {
-		return this == nil
-	}
-
-	that1, ok := that.(*Order)
-	if !ok {
-		that2, ok := that.(Order)
-		if ok {
-			that1 = &that2
-		} else {
-			return false
+func (*Allocation) ProtoMessage() {}
+
+func (x *Allocation) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	if that1 == nil {
-		return this == nil
-	} else if this == nil {
-		return false
-	}
-	if this.ProductID != that1.ProductID {
-		return false
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Allocation.ProtoReflect.Descriptor instead.
+func (*Allocation) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Allocation) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
 	}
-	if this.Quantity != that1.Quantity {
-		return false
+	return 0
+}
+
+func (x *Allocation) GetWarehouseID() int64 {
+	if x != nil {
+		return x.WarehouseID
 	}
-	return true
+	return 0
 }
-func (this *OrderRequest) Equal(that interface{}) bool {
-	if that == nil {
-		return this == nil
+
+func (x *Allocation) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
 	}
+	return 0
+}
 
-	that1, ok := that.(*OrderRequest)
-	if !ok {
-		that2, ok := that.(OrderRequest)
-		if ok {
-			that1 = &that2
-		} else {
-			return false
+type LineResult struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	// immediate is taken from stock, backordered ships when restocked or released
+	Immediate   int64 `protobuf:"varint,2,opt,name=immediate,proto3" json:"immediate,omitempty"`
+	Backordered int64 `protobuf:"varint,3,opt,name=backordered,proto3" json:"backordered,omitempty"`
+	Preorder    bool  `protobuf:"varint,4,opt,name=preorder,proto3" json:"preorder,omitempty"`
+	// unfulfilled is dropped from the order in non strict fulfillment mode
+	Unfulfilled   int64 `protobuf:"varint,5,opt,name=unfulfilled,proto3" json:"unfulfilled,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LineResult) Reset() {
+	*x = LineResult{}
+	mi := &file_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LineResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LineResult) ProtoMessage() {}
+
+func (x *LineResult) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LineResult.ProtoReflect.Descriptor instead.
+func (*LineResult) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LineResult) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
 	}
-	if that1 == nil {
-		return this == nil
-	} else if this == nil {
-		return false
+	return 0
+}
+
+func (x *LineResult) GetImmediate() int64 {
+	if x != nil {
+		return x.Immediate
 	}
-	if len(this.Purchases) != len(that1.Purchases) {
-		return false
+	return 0
+}
+
+func (x *LineResult) GetBackordered() int64 {
+	if x != nil {
+		return x.Backordered
 	}
-	for i := range this.Purchases {
-		if !this.Purchases[i].Equal(that1.Purchases[i]) {
-			return false
-		}
+	return 0
+}
+
+func (x *LineResult) GetPreorder() bool {
+	if x != nil {
+		return x.Preorder
 	}
-	return true
+	return false
 }
-func (this *OrderResponse) Equal(that interface{}) bool {
-	if that == nil {
-		return this == nil
+
+func (x *LineResult) GetUnfulfilled() int64 {
+	if x != nil {
+		return x.Unfulfilled
 	}
+	return 0
+}
+
+type OrderResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Successful bool                   `protobuf:"varint,1,opt,name=successful,proto3" json:"successful,omitempty"`
+	// amounts are in the smallest currency unit
+	Subtotal int64 `protobuf:"varint,2,opt,name=subtotal,proto3" json:"subtotal,omitempty"`
+	Discount int64 `protobuf:"varint,3,opt,name=discount,proto3" json:"discount,omitempty"`
+	Total    int64 `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	// allocations is empty when server doesn't use warehouses
+	Allocations []*Allocation `protobuf:"bytes,5,rep,name=allocations,proto3" json:"allocations,omitempty"`
+	Lines       []*LineResult `protobuf:"bytes,6,rep,name=lines,proto3" json:"lines,omitempty"`
+	// orderID is the reference of the order in stock movements
+	OrderID       string `protobuf:"bytes,7,opt,name=orderID,proto3" json:"orderID,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderResponse) Reset() {
+	*x = OrderResponse{}
+	mi := &file_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-	that1, ok := that.(*OrderResponse)
-	if !ok {
-		that2, ok := that.(OrderResponse)
-		if ok {
-			that1 = &that2
-		} else {
-			return false
+func (x *OrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderResponse) ProtoMessage() {}
+
+func (x *OrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	if that1 == nil {
-		return this == nil
-	} else if this == nil {
-		return false
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderResponse.ProtoReflect.Descriptor instead.
+func (*OrderResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *OrderResponse) GetSuccessful() bool {
+	if x != nil {
+		return x.Successful
 	}
-	if this.Successful != that1.Successful {
-		return false
+	return false
+}
+
+func (x *OrderResponse) GetSubtotal() int64 {
+	if x != nil {
+		return x.Subtotal
 	}
-	return true
+	return 0
 }
-func (this *Order) GoString() string {
-	if this == nil {
-		return "nil"
+
+func (x *OrderResponse) GetDiscount() int64 {
+	if x != nil {
+		return x.Discount
 	}
-	s := make([]string, 0, 6)
-	s = append(s, "&tomshop_v1.Order{")
-	s = append(s, "ProductID: "+fmt.Sprintf("%#v", this.ProductID)+",\n")
-	s = append(s, "Quantity: "+fmt.Sprintf("%#v", this.Quantity)+",\n")
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return 0
 }
-func (this *OrderRequest) GoString() string {
-	if this == nil {
-		return "nil"
+
+func (x *OrderResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
 	}
-	s := make([]string, 0, 5)
-	s = append(s, "&tomshop_v1.OrderRequest{")
-	if this.Purchases != nil {
-		s = append(s, "Purchases: "+fmt.Sprintf("%#v", this.Purchases)+",\n")
+	return 0
+}
+
+func (x *OrderResponse) GetAllocations() []*Allocation {
+	if x != nil {
+		return x.Allocations
 	}
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return nil
 }
-func (this *OrderResponse) GoString() string {
-	if this == nil {
-		return "nil"
+
+func (x *OrderResponse) GetLines() []*LineResult {
+	if x != nil {
+		return x.Lines
 	}
-	s := make([]string, 0, 5)
-	s = append(s, "&tomshop_v1.OrderResponse{")
-	s = append(s, "Successful: "+fmt.Sprintf("%#v", this.Successful)+",\n")
-	s = append(s, "}")
-	return strings.Join(s, "")
+	return nil
 }
-func valueToGoStringService(v interface{}, typ string) string {
-	rv := reflect.ValueOf(v)
-	if rv.IsNil() {
-		return "nil"
+
+func (x *OrderResponse) GetOrderID() string {
+	if x != nil {
+		return x.OrderID
 	}
-	pv := reflect.Indirect(rv).Interface()
-	return fmt.Sprintf("func(v %v) *%v { return &v } ( %#v )", typ, typ, pv)
+	return ""
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+type StockMovement struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ProductID int64                  `protobuf:"varint,2,opt,name=productID,proto3" json:"productID,omitempty"`
+	Delta     int64                  `protobuf:"varint,3,opt,name=delta,proto3" json:"delta,omitempty"`
+	Reason    StockMovementReason    `protobuf:"varint,4,opt,name=reason,proto3,enum=tomshop.v1.StockMovementReason" json:"reason,omitempty"`
+	Reference string                 `protobuf:"bytes,5,opt,name=reference,proto3" json:"reference,omitempty"`
+	Actor     string                 `protobuf:"bytes,6,opt,name=actor,proto3" json:"actor,omitempty"`
+	Before    int64                  `protobuf:"varint,7,opt,name=before,proto3" json:"before,omitempty"`
+	After     int64                  `protobuf:"varint,8,opt,name=after,proto3" json:"after,omitempty"`
+	// createdAt in RFC 3339 format
+	CreatedAt     string `protobuf:"bytes,9,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (x *StockMovement) Reset() {
+	*x = StockMovement{}
+	mi := &file_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-// TomShopClient is the client API for TomShop service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type TomShopClient interface {
-	MakeOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*OrderResponse, error)
+func (x *StockMovement) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-type tomShopClient struct {
-	cc *grpc.ClientConn
+func (*StockMovement) ProtoMessage() {}
+
+func (x *StockMovement) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func NewTomShopClient(cc *grpc.ClientConn) TomShopClient {
-	return &tomShopClient{cc}
+// Deprecated: Use StockMovement.ProtoReflect.Descriptor instead.
+func (*StockMovement) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{5}
 }
 
-func (c *tomShopClient) MakeOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*OrderResponse, error) {
-	out := new(OrderResponse)
-	err := c.cc.Invoke(ctx, "/tomshop.v1.TomShop/MakeOrder", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (x *StockMovement) GetId() int64 {
+	if x != nil {
+		return x.Id
 	}
-	return out, nil
+	return 0
 }
 
-// TomShopServer is the server API for TomShop service.
-type TomShopServer interface {
-	MakeOrder(context.Context, *OrderRequest) (*OrderResponse, error)
+func (x *StockMovement) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
 }
 
-func RegisterTomShopServer(s *grpc.Server, srv TomShopServer) {
-	s.RegisterService(&_TomShop_serviceDesc, srv)
+func (x *StockMovement) GetDelta() int64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+func (x *StockMovement) GetReason() StockMovementReason {
+	if x != nil {
+		return x.Reason
+	}
+	return StockMovementReason_UNKNOWN_REASON
+}
+
+func (x *StockMovement) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
 }
 
-func _TomShop_MakeOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(OrderRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (x *StockMovement) GetActor() string {
+	if x != nil {
+		return x.Actor
 	}
-	if interceptor == nil {
-		return srv.(TomShopServer).MakeOrder(ctx, in)
+	return ""
+}
+
+func (x *StockMovement) GetBefore() int64 {
+	if x != nil {
+		return x.Before
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/tomshop.v1.TomShop/MakeOrder",
+	return 0
+}
+
+func (x *StockMovement) GetAfter() int64 {
+	if x != nil {
+		return x.After
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TomShopServer).MakeOrder(ctx, req.(*OrderRequest))
+	return 0
+}
+
+func (x *StockMovement) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-var _TomShop_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "tomshop.v1.TomShop",
-	HandlerType: (*TomShopServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "MakeOrder",
-			Handler:    _TomShop_MakeOrder_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "service.proto",
-}
-
-func (m *Order) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *Order) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.ProductID != 0 {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintService(dAtA, i, uint64(m.ProductID))
-	}
-	if m.Quantity != 0 {
-		dAtA[i] = 0x10
-		i++
-		i = encodeVarintService(dAtA, i, uint64(m.Quantity))
-	}
-	return i, nil
-}
-
-func (m *OrderRequest) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
-	}
-	return dAtA[:n], nil
-}
-
-func (m *OrderRequest) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if len(m.Purchases) > 0 {
-		for _, msg := range m.Purchases {
-			dAtA[i] = 0xa
-			i++
-			i = encodeVarintService(dAtA, i, uint64(msg.Size()))
-			n, err := msg.MarshalTo(dAtA[i:])
-			if err != nil {
-				return 0, err
-			}
-			i += n
+type ChangeStockRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	// quantity is added to stock for RESTOCK and CANCELLATION, it is the new stock for CORRECTION
+	Quantity int64 `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// reason is one of RESTOCK, CANCELLATION or CORRECTION, other movements are made by the server
+	Reason    StockMovementReason `protobuf:"varint,3,opt,name=reason,proto3,enum=tomshop.v1.StockMovementReason" json:"reason,omitempty"`
+	Reference string              `protobuf:"bytes,4,opt,name=reference,proto3" json:"reference,omitempty"`
+	Actor     string              `protobuf:"bytes,5,opt,name=actor,proto3" json:"actor,omitempty"`
+	// warehouseID the items are in or taken from, required when server allocates orders to warehouses.
+	// For CORRECTION quantity is then the new stock of the warehouse
+	WarehouseID int64 `protobuf:"varint,6,opt,name=warehouseID,proto3" json:"warehouseID,omitempty"`
+	// backordered items of the cancelled order for CANCELLATION, they are released instead of returned to stock
+	Backordered   int64 `protobuf:"varint,7,opt,name=backordered,proto3" json:"backordered,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeStockRequest) Reset() {
+	*x = ChangeStockRequest{}
+	mi := &file_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeStockRequest) ProtoMessage() {}
+
+func (x *ChangeStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	return i, nil
+	return mi.MessageOf(x)
 }
 
-func (m *OrderResponse) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalTo(dAtA)
-	if err != nil {
-		return nil, err
+// Deprecated: Use ChangeStockRequest.ProtoReflect.Descriptor instead.
+func (*ChangeStockRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ChangeStockRequest) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
 	}
-	return dAtA[:n], nil
+	return 0
 }
 
-func (m *OrderResponse) MarshalTo(dAtA []byte) (int, error) {
-	var i int
-	_ = i
-	var l int
-	_ = l
-	if m.Successful {
-		dAtA[i] = 0x8
-		i++
-		if m.Successful {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
-		}
-		i++
+func (x *ChangeStockRequest) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
 	}
-	return i, nil
+	return 0
 }
 
-func encodeVarintService(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (x *ChangeStockRequest) GetReason() StockMovementReason {
+	if x != nil {
+		return x.Reason
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return StockMovementReason_UNKNOWN_REASON
 }
-func (m *Order) Size() (n int) {
-	if m == nil {
-		return 0
+
+func (x *ChangeStockRequest) GetReference() string {
+	if x != nil {
+		return x.Reference
 	}
-	var l int
-	_ = l
-	if m.ProductID != 0 {
-		n += 1 + sovService(uint64(m.ProductID))
+	return ""
+}
+
+func (x *ChangeStockRequest) GetActor() string {
+	if x != nil {
+		return x.Actor
 	}
-	if m.Quantity != 0 {
-		n += 1 + sovService(uint64(m.Quantity))
+	return ""
+}
+
+func (x *ChangeStockRequest) GetWarehouseID() int64 {
+	if x != nil {
+		return x.WarehouseID
 	}
-	return n
+	return 0
 }
 
-func (m *OrderRequest) Size() (n int) {
-	if m == nil {
-		return 0
+func (x *ChangeStockRequest) GetBackordered() int64 {
+	if x != nil {
+		return x.Backordered
 	}
-	var l int
-	_ = l
-	if len(m.Purchases) > 0 {
-		for _, e := range m.Purchases {
-			l = e.Size()
-			n += 1 + l + sovService(uint64(l))
+	return 0
+}
+
+type StockHistoryRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	// limit default 100
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StockHistoryRequest) Reset() {
+	*x = StockHistoryRequest{}
+	mi := &file_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StockHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StockHistoryRequest) ProtoMessage() {}
+
+func (x *StockHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	return n
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StockHistoryRequest.ProtoReflect.Descriptor instead.
+func (*StockHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{7}
 }
 
-func (m *OrderResponse) Size() (n int) {
-	if m == nil {
-		return 0
+func (x *StockHistoryRequest) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
 	}
-	var l int
-	_ = l
-	if m.Successful {
-		n += 2
+	return 0
+}
+
+func (x *StockHistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
 	}
-	return n
+	return 0
+}
+
+type StockHistoryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// movements newest first
+	Movements     []*StockMovement `protobuf:"bytes,1,rep,name=movements,proto3" json:"movements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StockHistoryResponse) Reset() {
+	*x = StockHistoryResponse{}
+	mi := &file_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func sovService(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
+func (x *StockHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StockHistoryResponse) ProtoMessage() {}
+
+func (x *StockHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StockHistoryResponse.ProtoReflect.Descriptor instead.
+func (*StockHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StockHistoryResponse) GetMovements() []*StockMovement {
+	if x != nil {
+		return x.Movements
 	}
-	return n
-}
-func sozService(x uint64) (n int) {
-	return sovService(uint64((x << 1) ^ uint64((int64(x) >> 63))))
-}
-func (this *Order) String() string {
-	if this == nil {
-		return "nil"
-	}
-	s := strings.Join([]string{`&Order{`,
-		`ProductID:` + fmt.Sprintf("%v", this.ProductID) + `,`,
-		`Quantity:` + fmt.Sprintf("%v", this.Quantity) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *OrderRequest) String() string {
-	if this == nil {
-		return "nil"
-	}
-	repeatedStringForPurchases := "[]*Order{"
-	for _, f := range this.Purchases {
-		repeatedStringForPurchases += strings.Replace(f.String(), "Order", "Order", 1) + ","
-	}
-	repeatedStringForPurchases += "}"
-	s := strings.Join([]string{`&OrderRequest{`,
-		`Purchases:` + repeatedStringForPurchases + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func (this *OrderResponse) String() string {
-	if this == nil {
-		return "nil"
-	}
-	s := strings.Join([]string{`&OrderResponse{`,
-		`Successful:` + fmt.Sprintf("%v", this.Successful) + `,`,
-		`}`,
-	}, "")
-	return s
-}
-func valueToStringService(v interface{}) string {
-	rv := reflect.ValueOf(v)
-	if rv.IsNil() {
-		return "nil"
-	}
-	pv := reflect.Indirect(rv).Interface()
-	return fmt.Sprintf("*%v", pv)
-}
-func (m *Order) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowService
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
+	return nil
+}
+
+type WatchInventoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductIDs    []int64                `protobuf:"varint,1,rep,packed,name=productIDs,proto3" json:"productIDs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchInventoryRequest) Reset() {
+	*x = WatchInventoryRequest{}
+	mi := &file_service_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchInventoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchInventoryRequest) ProtoMessage() {}
+
+func (x *WatchInventoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Order: wiretype end group for non-group")
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchInventoryRequest.ProtoReflect.Descriptor instead.
+func (*WatchInventoryRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchInventoryRequest) GetProductIDs() []int64 {
+	if x != nil {
+		return x.ProductIDs
+	}
+	return nil
+}
+
+type Inventory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductID     int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	StockCount    int64                  `protobuf:"varint,2,opt,name=stockCount,proto3" json:"stockCount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Inventory) Reset() {
+	*x = Inventory{}
+	mi := &file_service_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Inventory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Inventory) ProtoMessage() {}
+
+func (x *Inventory) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Order: illegal tag %d (wire type %d)", fieldNum, wire)
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Inventory.ProtoReflect.Descriptor instead.
+func (*Inventory) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Inventory) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *Inventory) GetStockCount() int64 {
+	if x != nil {
+		return x.StockCount
+	}
+	return 0
+}
+
+type ListInventoriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductIDs    []int64                `protobuf:"varint,1,rep,packed,name=productIDs,proto3" json:"productIDs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInventoriesRequest) Reset() {
+	*x = ListInventoriesRequest{}
+	mi := &file_service_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInventoriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInventoriesRequest) ProtoMessage() {}
+
+func (x *ListInventoriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ProductID", wireType)
-			}
-			m.ProductID = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowService
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ProductID |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Quantity", wireType)
-			}
-			m.Quantity = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowService
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Quantity |= int64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipService(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthService
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthService
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInventoriesRequest.ProtoReflect.Descriptor instead.
+func (*ListInventoriesRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListInventoriesRequest) GetProductIDs() []int64 {
+	if x != nil {
+		return x.ProductIDs
+	}
+	return nil
+}
+
+type ListInventoriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Inventories   []*Inventory           `protobuf:"bytes,1,rep,name=inventories,proto3" json:"inventories,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListInventoriesResponse) Reset() {
+	*x = ListInventoriesResponse{}
+	mi := &file_service_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListInventoriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListInventoriesResponse) ProtoMessage() {}
+
+func (x *ListInventoriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListInventoriesResponse.ProtoReflect.Descriptor instead.
+func (*ListInventoriesResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{12}
+}
 
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
+func (x *ListInventoriesResponse) GetInventories() []*Inventory {
+	if x != nil {
+		return x.Inventories
 	}
 	return nil
 }
-func (m *OrderRequest) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowService
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
+
+type StockThreshold struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	// threshold 0 removes the threshold
+	Threshold     int64 `protobuf:"varint,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StockThreshold) Reset() {
+	*x = StockThreshold{}
+	mi := &file_service_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StockThreshold) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StockThreshold) ProtoMessage() {}
+
+func (x *StockThreshold) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: OrderRequest: wiretype end group for non-group")
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StockThreshold.ProtoReflect.Descriptor instead.
+func (*StockThreshold) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StockThreshold) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *StockThreshold) GetThreshold() int64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+type LowStock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductID     int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Threshold     int64                  `protobuf:"varint,2,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	StockCount    int64                  `protobuf:"varint,3,opt,name=stockCount,proto3" json:"stockCount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LowStock) Reset() {
+	*x = LowStock{}
+	mi := &file_service_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LowStock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LowStock) ProtoMessage() {}
+
+func (x *LowStock) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: OrderRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LowStock.ProtoReflect.Descriptor instead.
+func (*LowStock) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LowStock) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *LowStock) GetThreshold() int64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *LowStock) GetStockCount() int64 {
+	if x != nil {
+		return x.StockCount
+	}
+	return 0
+}
+
+type ListLowStockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLowStockRequest) Reset() {
+	*x = ListLowStockRequest{}
+	mi := &file_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLowStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLowStockRequest) ProtoMessage() {}
+
+func (x *ListLowStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		switch fieldNum {
-		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Purchases", wireType)
-			}
-			var msglen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowService
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				msglen |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if msglen < 0 {
-				return ErrInvalidLengthService
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthService
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Purchases = append(m.Purchases, &Order{})
-			if err := m.Purchases[len(m.Purchases)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipService(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthService
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthService
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLowStockRequest.ProtoReflect.Descriptor instead.
+func (*ListLowStockRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{15}
+}
+
+type ListLowStockResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Products      []*LowStock            `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListLowStockResponse) Reset() {
+	*x = ListLowStockResponse{}
+	mi := &file_service_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListLowStockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListLowStockResponse) ProtoMessage() {}
+
+func (x *ListLowStockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListLowStockResponse.ProtoReflect.Descriptor instead.
+func (*ListLowStockResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{16}
+}
 
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
+func (x *ListLowStockResponse) GetProducts() []*LowStock {
+	if x != nil {
+		return x.Products
 	}
 	return nil
 }
-func (m *OrderResponse) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowService
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
-			}
+
+type WatchLowStockRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchLowStockRequest) Reset() {
+	*x = WatchLowStockRequest{}
+	mi := &file_service_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchLowStockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchLowStockRequest) ProtoMessage() {}
+
+func (x *WatchLowStockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: OrderResponse: wiretype end group for non-group")
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchLowStockRequest.ProtoReflect.Descriptor instead.
+func (*WatchLowStockRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{17}
+}
+
+type FulfillmentPolicy struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Kind      FulfillmentPolicyKind  `protobuf:"varint,2,opt,name=kind,proto3,enum=tomshop.v1.FulfillmentPolicyKind" json:"kind,omitempty"`
+	// limit of items can be backordered or preordered
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// backorderedCount is items waiting for restock or release, it is ignored when setting a policy
+	BackorderedCount int64 `protobuf:"varint,4,opt,name=backorderedCount,proto3" json:"backorderedCount,omitempty"`
+	// releaseAt in RFC 3339 format
+	ReleaseAt     string `protobuf:"bytes,5,opt,name=releaseAt,proto3" json:"releaseAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FulfillmentPolicy) Reset() {
+	*x = FulfillmentPolicy{}
+	mi := &file_service_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FulfillmentPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FulfillmentPolicy) ProtoMessage() {}
+
+func (x *FulfillmentPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: OrderResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FulfillmentPolicy.ProtoReflect.Descriptor instead.
+func (*FulfillmentPolicy) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *FulfillmentPolicy) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *FulfillmentPolicy) GetKind() FulfillmentPolicyKind {
+	if x != nil {
+		return x.Kind
+	}
+	return FulfillmentPolicyKind_STOCK_ONLY
+}
+
+func (x *FulfillmentPolicy) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *FulfillmentPolicy) GetBackorderedCount() int64 {
+	if x != nil {
+		return x.BackorderedCount
+	}
+	return 0
+}
+
+func (x *FulfillmentPolicy) GetReleaseAt() string {
+	if x != nil {
+		return x.ReleaseAt
+	}
+	return ""
+}
+
+type BatchOrder struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// clientID is returned with the result for correlation
+	ClientID      string        `protobuf:"bytes,1,opt,name=clientID,proto3" json:"clientID,omitempty"`
+	Order         *OrderRequest `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchOrder) Reset() {
+	*x = BatchOrder{}
+	mi := &file_service_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchOrder) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchOrder) ProtoMessage() {}
+
+func (x *BatchOrder) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Successful", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowService
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Successful = bool(v != 0)
-		default:
-			iNdEx = preIndex
-			skippy, err := skipService(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthService
-			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthService
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchOrder.ProtoReflect.Descriptor instead.
+func (*BatchOrder) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *BatchOrder) GetClientID() string {
+	if x != nil {
+		return x.ClientID
+	}
+	return ""
+}
+
+func (x *BatchOrder) GetOrder() *OrderRequest {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+type BatchOrderResult struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ClientID string                 `protobuf:"bytes,1,opt,name=clientID,proto3" json:"clientID,omitempty"`
+	Response *OrderResponse         `protobuf:"bytes,2,opt,name=response,proto3" json:"response,omitempty"`
+	// code is the gRPC status code of the order, 0 when successful
+	Code          int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchOrderResult) Reset() {
+	*x = BatchOrderResult{}
+	mi := &file_service_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchOrderResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchOrderResult) ProtoMessage() {}
+
+func (x *BatchOrderResult) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchOrderResult.ProtoReflect.Descriptor instead.
+func (*BatchOrderResult) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *BatchOrderResult) GetClientID() string {
+	if x != nil {
+		return x.ClientID
+	}
+	return ""
+}
 
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
+func (x *BatchOrderResult) GetResponse() *OrderResponse {
+	if x != nil {
+		return x.Response
 	}
 	return nil
 }
-func skipService(dAtA []byte) (n int, err error) {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return 0, ErrIntOverflowService
-			}
-			if iNdEx >= l {
-				return 0, io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
+
+func (x *BatchOrderResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BatchOrderResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type MakeOrdersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// orders are validated one by one when made, so an invalid order fails alone. At most 500
+	Orders        []*BatchOrder `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MakeOrdersRequest) Reset() {
+	*x = MakeOrdersRequest{}
+	mi := &file_service_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MakeOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MakeOrdersRequest) ProtoMessage() {}
+
+func (x *MakeOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		wireType := int(wire & 0x7)
-		switch wireType {
-		case 0:
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return 0, ErrIntOverflowService
-				}
-				if iNdEx >= l {
-					return 0, io.ErrUnexpectedEOF
-				}
-				iNdEx++
-				if dAtA[iNdEx-1] < 0x80 {
-					break
-				}
-			}
-			return iNdEx, nil
-		case 1:
-			iNdEx += 8
-			return iNdEx, nil
-		case 2:
-			var length int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return 0, ErrIntOverflowService
-				}
-				if iNdEx >= l {
-					return 0, io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				length |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if length < 0 {
-				return 0, ErrInvalidLengthService
-			}
-			iNdEx += length
-			if iNdEx < 0 {
-				return 0, ErrInvalidLengthService
-			}
-			return iNdEx, nil
-		case 3:
-			for {
-				var innerWire uint64
-				var start int = iNdEx
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return 0, ErrIntOverflowService
-					}
-					if iNdEx >= l {
-						return 0, io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					innerWire |= (uint64(b) & 0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				innerWireType := int(innerWire & 0x7)
-				if innerWireType == 4 {
-					break
-				}
-				next, err := skipService(dAtA[start:])
-				if err != nil {
-					return 0, err
-				}
-				iNdEx = start + next
-				if iNdEx < 0 {
-					return 0, ErrInvalidLengthService
-				}
-			}
-			return iNdEx, nil
-		case 4:
-			return iNdEx, nil
-		case 5:
-			iNdEx += 4
-			return iNdEx, nil
-		default:
-			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MakeOrdersRequest.ProtoReflect.Descriptor instead.
+func (*MakeOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *MakeOrdersRequest) GetOrders() []*BatchOrder {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+type MakeOrdersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results in the same order as requested
+	Results       []*BatchOrderResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MakeOrdersResponse) Reset() {
+	*x = MakeOrdersResponse{}
+	mi := &file_service_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MakeOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MakeOrdersResponse) ProtoMessage() {}
+
+func (x *MakeOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
+		return ms
 	}
-	panic("unreachable")
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MakeOrdersResponse.ProtoReflect.Descriptor instead.
+func (*MakeOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{22}
 }
 
+func (x *MakeOrdersResponse) GetResults() []*BatchOrderResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_service_proto protoreflect.FileDescriptor
+
+const file_service_proto_rawDesc = "" +
+	"\n" +
+	"\rservice.proto\x12\n" +
+	"tomshop.v1\x1a\x1bbuf/validate/validate.proto\x1a\x1cgoogle/api/annotations.proto\"~\n" +
+	"\x05Order\x12%\n" +
+	"\tproductID\x18\x01 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\tproductID\x12#\n" +
+	"\bquantity\x18\x02 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\bquantity\x12)\n" +
+	"\vminQuantity\x18\x03 \x01(\x03B\a\xbaH\x04\"\x02(\x00R\vminQuantity\"\xaa\x03\n" +
+	"\fOrderRequest\x12\x94\x01\n" +
+	"\tpurchases\x18\x01 \x03(\v2\x11.tomshop.v1.OrderBc\xbaH`\xba\x01V\n" +
+	"\x18purchases.unique_product\x12\x17products must be unique\x1a!this.map(o, o.productID).unique()\x92\x01\x04\b\x01\x10dR\tpurchases\x12\x1e\n" +
+	"\n" +
+	"customerID\x18\x02 \x01(\x03R\n" +
+	"customerID\x12 \n" +
+	"\vcouponCodes\x18\x03 \x03(\tR\vcouponCodes\x12\x1e\n" +
+	"\n" +
+	"regionHint\x18\x04 \x01(\tR\n" +
+	"regionHint\x122\n" +
+	"\x14preferredWarehouseID\x18\x05 \x01(\x03R\x14preferredWarehouseID\x12&\n" +
+	"\x0eallowBackorder\x18\x06 \x01(\bR\x0eallowBackorder\x12E\n" +
+	"\x0ffulfillmentMode\x18\a \x01(\x0e2\x1b.tomshop.v1.FulfillmentModeR\x0ffulfillmentMode\"h\n" +
+	"\n" +
+	"Allocation\x12\x1c\n" +
+	"\tproductID\x18\x01 \x01(\x03R\tproductID\x12 \n" +
+	"\vwarehouseID\x18\x02 \x01(\x03R\vwarehouseID\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\x03R\bquantity\"\xa8\x01\n" +
+	"\n" +
+	"LineResult\x12\x1c\n" +
+	"\tproductID\x18\x01 \x01(\x03R\tproductID\x12\x1c\n" +
+	"\timmediate\x18\x02 \x01(\x03R\timmediate\x12 \n" +
+	"\vbackordered\x18\x03 \x01(\x03R\vbackordered\x12\x1a\n" +
+	"\bpreorder\x18\x04 \x01(\bR\bpreorder\x12 \n" +
+	"\vunfulfilled\x18\x05 \x01(\x03R\vunfulfilled\"\xff\x01\n" +
+	"\rOrderResponse\x12\x1e\n" +
+	"\n" +
+	"successful\x18\x01 \x01(\bR\n" +
+	"successful\x12\x1a\n" +
+	"\bsubtotal\x18\x02 \x01(\x03R\bsubtotal\x12\x1a\n" +
+	"\bdiscount\x18\x03 \x01(\x03R\bdiscount\x12\x14\n" +
+	"\x05total\x18\x04 \x01(\x03R\x05total\x128\n" +
+	"\vallocations\x18\x05 \x03(\v2\x16.tomshop.v1.AllocationR\vallocations\x12,\n" +
+	"\x05lines\x18\x06 \x03(\v2\x16.tomshop.v1.LineResultR\x05lines\x12\x18\n" +
+	"\aorderID\x18\a \x01(\tR\aorderID\"\x8c\x02\n" +
+	"\rStockMovement\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1c\n" +
+	"\tproductID\x18\x02 \x01(\x03R\tproductID\x12\x14\n" +
+	"\x05delta\x18\x03 \x01(\x03R\x05delta\x127\n" +
+	"\x06reason\x18\x04 \x01(\x0e2\x1f.tomshop.v1.StockMovementReasonR\x06reason\x12\x1c\n" +
+	"\treference\x18\x05 \x01(\tR\treference\x12\x14\n" +
+	"\x05actor\x18\x06 \x01(\tR\x05actor\x12\x16\n" +
+	"\x06before\x18\a \x01(\x03R\x06before\x12\x14\n" +
+	"\x05after\x18\b \x01(\x03R\x05after\x12\x1c\n" +
+	"\tcreatedAt\x18\t \x01(\tR\tcreatedAt\"\xb1\x02\n" +
+	"\x12ChangeStockRequest\x12%\n" +
+	"\tproductID\x18\x01 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\tproductID\x12#\n" +
+	"\bquantity\x18\x02 \x01(\x03B\a\xbaH\x04\"\x02(\x00R\bquantity\x12E\n" +
+	"\x06reason\x18\x03 \x01(\x0e2\x1f.tomshop.v1.StockMovementReasonB\f\xbaH\t\x82\x01\x06\x18\x02\x18\x03\x18\x04R\x06reason\x12\x1c\n" +
+	"\treference\x18\x04 \x01(\tR\treference\x12\x14\n" +
+	"\x05actor\x18\x05 \x01(\tR\x05actor\x12)\n" +
+	"\vwarehouseID\x18\x06 \x01(\x03B\a\xbaH\x04\"\x02(\x00R\vwarehouseID\x12)\n" +
+	"\vbackordered\x18\a \x01(\x03B\a\xbaH\x04\"\x02(\x00R\vbackordered\"R\n" +
+	"\x13StockHistoryRequest\x12%\n" +
+	"\tproductID\x18\x01 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\tproductID\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"O\n" +
+	"\x14StockHistoryResponse\x127\n" +
+	"\tmovements\x18\x01 \x03(\v2\x19.tomshop.v1.StockMovementR\tmovements\"J\n" +
+	"\x15WatchInventoryRequest\x121\n" +
+	"\n" +
+	"productIDs\x18\x01 \x03(\x03B\x11\xbaH\x0e\x92\x01\v\b\x01\x10\xe8\a\"\x04\"\x02 \x00R\n" +
+	"productIDs\"I\n" +
+	"\tInventory\x12\x1c\n" +
+	"\tproductID\x18\x01 \x01(\x03R\tproductID\x12\x1e\n" +
+	"\n" +
+	"stockCount\x18\x02 \x01(\x03R\n" +
+	"stockCount\"K\n" +
+	"\x16ListInventoriesRequest\x121\n" +
+	"\n" +
+	"productIDs\x18\x01 \x03(\x03B\x11\xbaH\x0e\x92\x01\v\b\x01\x10\xe8\a\"\x04\"\x02 \x00R\n" +
+	"productIDs\"R\n" +
+	"\x17ListInventoriesResponse\x127\n" +
+	"\vinventories\x18\x01 \x03(\v2\x15.tomshop.v1.InventoryR\vinventories\"^\n" +
+	"\x0eStockThreshold\x12%\n" +
+	"\tproductID\x18\x01 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\tproductID\x12%\n" +
+	"\tthreshold\x18\x02 \x01(\x03B\a\xbaH\x04\"\x02(\x00R\tthreshold\"f\n" +
+	"\bLowStock\x12\x1c\n" +
+	"\tproductID\x18\x01 \x01(\x03R\tproductID\x12\x1c\n" +
+	"\tthreshold\x18\x02 \x01(\x03R\tthreshold\x12\x1e\n" +
+	"\n" +
+	"stockCount\x18\x03 \x01(\x03R\n" +
+	"stockCount\"\x15\n" +
+	"\x13ListLowStockRequest\"H\n" +
+	"\x14ListLowStockResponse\x120\n" +
+	"\bproducts\x18\x01 \x03(\v2\x14.tomshop.v1.LowStockR\bproducts\"\x16\n" +
+	"\x14WatchLowStockRequest\"\xdb\x02\n" +
+	"\x11FulfillmentPolicy\x12%\n" +
+	"\tproductID\x18\x01 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\tproductID\x12?\n" +
+	"\x04kind\x18\x02 \x01(\x0e2!.tomshop.v1.FulfillmentPolicyKindB\b\xbaH\x05\x82\x01\x02\x10\x01R\x04kind\x12\x1d\n" +
+	"\x05limit\x18\x03 \x01(\x03B\a\xbaH\x04\"\x02(\x00R\x05limit\x12*\n" +
+	"\x10backorderedCount\x18\x04 \x01(\x03R\x10backorderedCount\x12\x1c\n" +
+	"\treleaseAt\x18\x05 \x01(\tR\treleaseAt:u\xbaHr\x1ap\n" +
+	"#fulfillment_policy.preorder_release\x12!releaseAt is required by PREORDER\x1a&this.kind != 2 || this.releaseAt != ''\"X\n" +
+	"\n" +
+	"BatchOrder\x12\x1a\n" +
+	"\bclientID\x18\x01 \x01(\tR\bclientID\x12.\n" +
+	"\x05order\x18\x02 \x01(\v2\x18.tomshop.v1.OrderRequestR\x05order\"\x93\x01\n" +
+	"\x10BatchOrderResult\x12\x1a\n" +
+	"\bclientID\x18\x01 \x01(\tR\bclientID\x125\n" +
+	"\bresponse\x18\x02 \x01(\v2\x19.tomshop.v1.OrderResponseR\bresponse\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"K\n" +
+	"\x11MakeOrdersRequest\x126\n" +
+	"\x06orders\x18\x01 \x03(\v2\x16.tomshop.v1.BatchOrderB\x06\xbaH\x03\xd8\x01\x03R\x06orders\"L\n" +
+	"\x12MakeOrdersResponse\x126\n" +
+	"\aresults\x18\x01 \x03(\v2\x1c.tomshop.v1.BatchOrderResultR\aresults*L\n" +
+	"\x0fFulfillmentMode\x12\x12\n" +
+	"\x0eALL_OR_NOTHING\x10\x00\x12\x0f\n" +
+	"\vBEST_EFFORT\x10\x01\x12\x14\n" +
+	"\x10PER_LINE_MINIMUM\x10\x02*\x89\x01\n" +
+	"\x13StockMovementReason\x12\x12\n" +
+	"\x0eUNKNOWN_REASON\x10\x00\x12\t\n" +
+	"\x05ORDER\x10\x01\x12\v\n" +
+	"\aRESTOCK\x10\x02\x12\x10\n" +
+	"\fCANCELLATION\x10\x03\x12\x0e\n" +
+	"\n" +
+	"CORRECTION\x10\x04\x12\x13\n" +
+	"\x0fOPENING_BALANCE\x10\x05\x12\x0f\n" +
+	"\vBACKORDERED\x10\x06*D\n" +
+	"\x15FulfillmentPolicyKind\x12\x0e\n" +
+	"\n" +
+	"STOCK_ONLY\x10\x00\x12\r\n" +
+	"\tBACKORDER\x10\x01\x12\f\n" +
+	"\bPREORDER\x10\x022\xd1\t\n" +
+	"\aTomShop\x12W\n" +
+	"\tMakeOrder\x12\x18.tomshop.v1.OrderRequest\x1a\x19.tomshop.v1.OrderResponse\"\x15\x82\xd3\xe4\x93\x02\x0f:\x01*\"\n" +
+	"/v1/orders\x12h\n" +
+	"\n" +
+	"MakeOrders\x12\x1d.tomshop.v1.MakeOrdersRequest\x1a\x1e.tomshop.v1.MakeOrdersResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/v1/orders:batch\x12H\n" +
+	"\fStreamOrders\x12\x16.tomshop.v1.BatchOrder\x1a\x1e.tomshop.v1.MakeOrdersResponse(\x01\x12s\n" +
+	"\vChangeStock\x12\x1e.tomshop.v1.ChangeStockRequest\x1a\x19.tomshop.v1.StockMovement\")\x82\xd3\xe4\x93\x02#:\x01*\"\x1e/v1/products/{productID}/stock\x12\x84\x01\n" +
+	"\x0fGetStockHistory\x12\x1f.tomshop.v1.StockHistoryRequest\x1a .tomshop.v1.StockHistoryResponse\".\x82\xd3\xe4\x93\x02(\x12&/v1/products/{productID}/stock/history\x12s\n" +
+	"\x0fListInventories\x12\".tomshop.v1.ListInventoriesRequest\x1a#.tomshop.v1.ListInventoriesResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/v1/inventories\x12k\n" +
+	"\x0eWatchInventory\x12!.tomshop.v1.WatchInventoryRequest\x1a\x15.tomshop.v1.Inventory\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/inventories:watch0\x01\x12z\n" +
+	"\x11SetStockThreshold\x12\x1a.tomshop.v1.StockThreshold\x1a\x1a.tomshop.v1.StockThreshold\"-\x82\xd3\xe4\x93\x02':\x01*\x1a\"/v1/products/{productID}/threshold\x12\x8c\x01\n" +
+	"\x14SetFulfillmentPolicy\x12\x1d.tomshop.v1.FulfillmentPolicy\x1a\x1d.tomshop.v1.FulfillmentPolicy\"6\x82\xd3\xe4\x93\x020:\x01*\x1a+/v1/products/{productID}/fulfillment-policy\x12h\n" +
+	"\fListLowStock\x12\x1f.tomshop.v1.ListLowStockRequest\x1a .tomshop.v1.ListLowStockResponse\"\x15\x82\xd3\xe4\x93\x02\x0f\x12\r/v1/low-stock\x12f\n" +
+	"\rWatchLowStock\x12 .tomshop.v1.WatchLowStockRequest\x1a\x14.tomshop.v1.LowStock\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/low-stock:watch0\x01B\x19Z\x17tomshop/grpc;tomshop_v1b\x06proto3"
+
 var (
-	ErrInvalidLengthService = fmt.Errorf("proto: negative length found during unmarshaling")
-	ErrIntOverflowService   = fmt.Errorf("proto: integer overflow")
+	file_service_proto_rawDescOnce sync.Once
+	file_service_proto_rawDescData []byte
 )
+
+func file_service_proto_rawDescGZIP() []byte {
+	file_service_proto_rawDescOnce.Do(func() {
+		file_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_service_proto_rawDesc), len(file_service_proto_rawDesc)))
+	})
+	return file_service_proto_rawDescData
+}
+
+var file_service_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_service_proto_goTypes = []any{
+	(FulfillmentMode)(0),            // 0: tomshop.v1.FulfillmentMode
+	(StockMovementReason)(0),        // 1: tomshop.v1.StockMovementReason
+	(FulfillmentPolicyKind)(0),      // 2: tomshop.v1.FulfillmentPolicyKind
+	(*Order)(nil),                   // 3: tomshop.v1.Order
+	(*OrderRequest)(nil),            // 4: tomshop.v1.OrderRequest
+	(*Allocation)(nil),              // 5: tomshop.v1.Allocation
+	(*LineResult)(nil),              // 6: tomshop.v1.LineResult
+	(*OrderResponse)(nil),           // 7: tomshop.v1.OrderResponse
+	(*StockMovement)(nil),           // 8: tomshop.v1.StockMovement
+	(*ChangeStockRequest)(nil),      // 9: tomshop.v1.ChangeStockRequest
+	(*StockHistoryRequest)(nil),     // 10: tomshop.v1.StockHistoryRequest
+	(*StockHistoryResponse)(nil),    // 11: tomshop.v1.StockHistoryResponse
+	(*WatchInventoryRequest)(nil),   // 12: tomshop.v1.WatchInventoryRequest
+	(*Inventory)(nil),               // 13: tomshop.v1.Inventory
+	(*ListInventoriesRequest)(nil),  // 14: tomshop.v1.ListInventoriesRequest
+	(*ListInventoriesResponse)(nil), // 15: tomshop.v1.ListInventoriesResponse
+	(*StockThreshold)(nil),          // 16: tomshop.v1.StockThreshold
+	(*LowStock)(nil),                // 17: tomshop.v1.LowStock
+	(*ListLowStockRequest)(nil),     // 18: tomshop.v1.ListLowStockRequest
+	(*ListLowStockResponse)(nil),    // 19: tomshop.v1.ListLowStockResponse
+	(*WatchLowStockRequest)(nil),    // 20: tomshop.v1.WatchLowStockRequest
+	(*FulfillmentPolicy)(nil),       // 21: tomshop.v1.FulfillmentPolicy
+	(*BatchOrder)(nil),              // 22: tomshop.v1.BatchOrder
+	(*BatchOrderResult)(nil),        // 23: tomshop.v1.BatchOrderResult
+	(*MakeOrdersRequest)(nil),       // 24: tomshop.v1.MakeOrdersRequest
+	(*MakeOrdersResponse)(nil),      // 25: tomshop.v1.MakeOrdersResponse
+}
+var file_service_proto_depIdxs = []int32{
+	3,  // 0: tomshop.v1.OrderRequest.purchases:type_name -> tomshop.v1.Order
+	0,  // 1: tomshop.v1.OrderRequest.fulfillmentMode:type_name -> tomshop.v1.FulfillmentMode
+	5,  // 2: tomshop.v1.OrderResponse.allocations:type_name -> tomshop.v1.Allocation
+	6,  // 3: tomshop.v1.OrderResponse.lines:type_name -> tomshop.v1.LineResult
+	1,  // 4: tomshop.v1.StockMovement.reason:type_name -> tomshop.v1.StockMovementReason
+	1,  // 5: tomshop.v1.ChangeStockRequest.reason:type_name -> tomshop.v1.StockMovementReason
+	8,  // 6: tomshop.v1.StockHistoryResponse.movements:type_name -> tomshop.v1.StockMovement
+	13, // 7: tomshop.v1.ListInventoriesResponse.inventories:type_name -> tomshop.v1.Inventory
+	17, // 8: tomshop.v1.ListLowStockResponse.products:type_name -> tomshop.v1.LowStock
+	2,  // 9: tomshop.v1.FulfillmentPolicy.kind:type_name -> tomshop.v1.FulfillmentPolicyKind
+	4,  // 10: tomshop.v1.BatchOrder.order:type_name -> tomshop.v1.OrderRequest
+	7,  // 11: tomshop.v1.BatchOrderResult.response:type_name -> tomshop.v1.OrderResponse
+	22, // 12: tomshop.v1.MakeOrdersRequest.orders:type_name -> tomshop.v1.BatchOrder
+	23, // 13: tomshop.v1.MakeOrdersResponse.results:type_name -> tomshop.v1.BatchOrderResult
+	4,  // 14: tomshop.v1.TomShop.MakeOrder:input_type -> tomshop.v1.OrderRequest
+	24, // 15: tomshop.v1.TomShop.MakeOrders:input_type -> tomshop.v1.MakeOrdersRequest
+	22, // 16: tomshop.v1.TomShop.StreamOrders:input_type -> tomshop.v1.BatchOrder
+	9,  // 17: tomshop.v1.TomShop.ChangeStock:input_type -> tomshop.v1.ChangeStockRequest
+	10, // 18: tomshop.v1.TomShop.GetStockHistory:input_type -> tomshop.v1.StockHistoryRequest
+	14, // 19: tomshop.v1.TomShop.ListInventories:input_type -> tomshop.v1.ListInventoriesRequest
+	12, // 20: tomshop.v1.TomShop.WatchInventory:input_type -> tomshop.v1.WatchInventoryRequest
+	16, // 21: tomshop.v1.TomShop.SetStockThreshold:input_type -> tomshop.v1.StockThreshold
+	21, // 22: tomshop.v1.TomShop.SetFulfillmentPolicy:input_type -> tomshop.v1.FulfillmentPolicy
+	18, // 23: tomshop.v1.TomShop.ListLowStock:input_type -> tomshop.v1.ListLowStockRequest
+	20, // 24: tomshop.v1.TomShop.WatchLowStock:input_type -> tomshop.v1.WatchLowStockRequest
+	7,  // 25: tomshop.v1.TomShop.MakeOrder:output_type -> tomshop.v1.OrderResponse
+	25, // 26: tomshop.v1.TomShop.MakeOrders:output_type -> tomshop.v1.MakeOrdersResponse
+	25, // 27: tomshop.v1.TomShop.StreamOrders:output_type -> tomshop.v1.MakeOrdersResponse
+	8,  // 28: tomshop.v1.TomShop.ChangeStock:output_type -> tomshop.v1.StockMovement
+	11, // 29: tomshop.v1.TomShop.GetStockHistory:output_type -> tomshop.v1.StockHistoryResponse
+	15, // 30: tomshop.v1.TomShop.ListInventories:output_type -> tomshop.v1.ListInventoriesResponse
+	13, // 31: tomshop.v1.TomShop.WatchInventory:output_type -> tomshop.v1.Inventory
+	16, // 32: tomshop.v1.TomShop.SetStockThreshold:output_type -> tomshop.v1.StockThreshold
+	21, // 33: tomshop.v1.TomShop.SetFulfillmentPolicy:output_type -> tomshop.v1.FulfillmentPolicy
+	19, // 34: tomshop.v1.TomShop.ListLowStock:output_type -> tomshop.v1.ListLowStockResponse
+	17, // 35: tomshop.v1.TomShop.WatchLowStock:output_type -> tomshop.v1.LowStock
+	25, // [25:36] is the sub-list for method output_type
+	14, // [14:25] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_service_proto_init() }
+func file_service_proto_init() {
+	if File_service_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_service_proto_rawDesc), len(file_service_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_service_proto_goTypes,
+		DependencyIndexes: file_service_proto_depIdxs,
+		EnumInfos:         file_service_proto_enumTypes,
+		MessageInfos:      file_service_proto_msgTypes,
+	}.Build()
+	File_service_proto = out.File
+	file_service_proto_goTypes = nil
+	file_service_proto_depIdxs = nil
+}
@@ -0,0 +1,525 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: service.proto
+
+package tomshop_v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TomShop_MakeOrder_FullMethodName            = "/tomshop.v1.TomShop/MakeOrder"
+	TomShop_MakeOrders_FullMethodName           = "/tomshop.v1.TomShop/MakeOrders"
+	TomShop_StreamOrders_FullMethodName         = "/tomshop.v1.TomShop/StreamOrders"
+	TomShop_ChangeStock_FullMethodName          = "/tomshop.v1.TomShop/ChangeStock"
+	TomShop_GetStockHistory_FullMethodName      = "/tomshop.v1.TomShop/GetStockHistory"
+	TomShop_ListInventories_FullMethodName      = "/tomshop.v1.TomShop/ListInventories"
+	TomShop_WatchInventory_FullMethodName       = "/tomshop.v1.TomShop/WatchInventory"
+	TomShop_SetStockThreshold_FullMethodName    = "/tomshop.v1.TomShop/SetStockThreshold"
+	TomShop_SetFulfillmentPolicy_FullMethodName = "/tomshop.v1.TomShop/SetFulfillmentPolicy"
+	TomShop_ListLowStock_FullMethodName         = "/tomshop.v1.TomShop/ListLowStock"
+	TomShop_WatchLowStock_FullMethodName        = "/tomshop.v1.TomShop/WatchLowStock"
+)
+
+// TomShopClient is the client API for TomShop service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TomShopClient interface {
+	MakeOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*OrderResponse, error)
+	// MakeOrders makes every order independently in request order, one failed or invalid order doesn't fail others
+	MakeOrders(ctx context.Context, in *MakeOrdersRequest, opts ...grpc.CallOption) (*MakeOrdersResponse, error)
+	// StreamOrders makes orders as they are received, results are returned when client closes sending.
+	// Invalid orders fail alone like in MakeOrders
+	// The stream fails with RESOURCE_EXHAUSTED past 5000 orders, orders made before are kept
+	StreamOrders(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[BatchOrder, MakeOrdersResponse], error)
+	ChangeStock(ctx context.Context, in *ChangeStockRequest, opts ...grpc.CallOption) (*StockMovement, error)
+	GetStockHistory(ctx context.Context, in *StockHistoryRequest, opts ...grpc.CallOption) (*StockHistoryResponse, error)
+	// ListInventories lists stock of products for display, it may be a second stale,
+	// products not found have zero stock
+	ListInventories(ctx context.Context, in *ListInventoriesRequest, opts ...grpc.CallOption) (*ListInventoriesResponse, error)
+	// WatchInventory sends current stock of every product first then the latest stock of changed products,
+	// changes in between sends can be coalesced
+	WatchInventory(ctx context.Context, in *WatchInventoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Inventory], error)
+	SetStockThreshold(ctx context.Context, in *StockThreshold, opts ...grpc.CallOption) (*StockThreshold, error)
+	// SetFulfillmentPolicy decides what happens when stock of a product is not enough,
+	// items already backordered are kept whatever the new policy
+	SetFulfillmentPolicy(ctx context.Context, in *FulfillmentPolicy, opts ...grpc.CallOption) (*FulfillmentPolicy, error)
+	// ListLowStock lists every product currently below its threshold
+	ListLowStock(ctx context.Context, in *ListLowStockRequest, opts ...grpc.CallOption) (*ListLowStockResponse, error)
+	// WatchLowStock sends an alert whenever a product crosses below its threshold
+	WatchLowStock(ctx context.Context, in *WatchLowStockRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LowStock], error)
+}
+
+type tomShopClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTomShopClient(cc grpc.ClientConnInterface) TomShopClient {
+	return &tomShopClient{cc}
+}
+
+func (c *tomShopClient) MakeOrder(ctx context.Context, in *OrderRequest, opts ...grpc.CallOption) (*OrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderResponse)
+	err := c.cc.Invoke(ctx, TomShop_MakeOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) MakeOrders(ctx context.Context, in *MakeOrdersRequest, opts ...grpc.CallOption) (*MakeOrdersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MakeOrdersResponse)
+	err := c.cc.Invoke(ctx, TomShop_MakeOrders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) StreamOrders(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[BatchOrder, MakeOrdersResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TomShop_ServiceDesc.Streams[0], TomShop_StreamOrders_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BatchOrder, MakeOrdersResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TomShop_StreamOrdersClient = grpc.ClientStreamingClient[BatchOrder, MakeOrdersResponse]
+
+func (c *tomShopClient) ChangeStock(ctx context.Context, in *ChangeStockRequest, opts ...grpc.CallOption) (*StockMovement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StockMovement)
+	err := c.cc.Invoke(ctx, TomShop_ChangeStock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) GetStockHistory(ctx context.Context, in *StockHistoryRequest, opts ...grpc.CallOption) (*StockHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StockHistoryResponse)
+	err := c.cc.Invoke(ctx, TomShop_GetStockHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) ListInventories(ctx context.Context, in *ListInventoriesRequest, opts ...grpc.CallOption) (*ListInventoriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInventoriesResponse)
+	err := c.cc.Invoke(ctx, TomShop_ListInventories_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) WatchInventory(ctx context.Context, in *WatchInventoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Inventory], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TomShop_ServiceDesc.Streams[1], TomShop_WatchInventory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchInventoryRequest, Inventory]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TomShop_WatchInventoryClient = grpc.ServerStreamingClient[Inventory]
+
+func (c *tomShopClient) SetStockThreshold(ctx context.Context, in *StockThreshold, opts ...grpc.CallOption) (*StockThreshold, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StockThreshold)
+	err := c.cc.Invoke(ctx, TomShop_SetStockThreshold_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) SetFulfillmentPolicy(ctx context.Context, in *FulfillmentPolicy, opts ...grpc.CallOption) (*FulfillmentPolicy, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FulfillmentPolicy)
+	err := c.cc.Invoke(ctx, TomShop_SetFulfillmentPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) ListLowStock(ctx context.Context, in *ListLowStockRequest, opts ...grpc.CallOption) (*ListLowStockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListLowStockResponse)
+	err := c.cc.Invoke(ctx, TomShop_ListLowStock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tomShopClient) WatchLowStock(ctx context.Context, in *WatchLowStockRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LowStock], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TomShop_ServiceDesc.Streams[2], TomShop_WatchLowStock_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchLowStockRequest, LowStock]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TomShop_WatchLowStockClient = grpc.ServerStreamingClient[LowStock]
+
+// TomShopServer is the server API for TomShop service.
+// All implementations must embed UnimplementedTomShopServer
+// for forward compatibility.
+type TomShopServer interface {
+	MakeOrder(context.Context, *OrderRequest) (*OrderResponse, error)
+	// MakeOrders makes every order independently in request order, one failed or invalid order doesn't fail others
+	MakeOrders(context.Context, *MakeOrdersRequest) (*MakeOrdersResponse, error)
+	// StreamOrders makes orders as they are received, results are returned when client closes sending.
+	// Invalid orders fail alone like in MakeOrders
+	// The stream fails with RESOURCE_EXHAUSTED past 5000 orders, orders made before are kept
+	StreamOrders(grpc.ClientStreamingServer[BatchOrder, MakeOrdersResponse]) error
+	ChangeStock(context.Context, *ChangeStockRequest) (*StockMovement, error)
+	GetStockHistory(context.Context, *StockHistoryRequest) (*StockHistoryResponse, error)
+	// ListInventories lists stock of products for display, it may be a second stale,
+	// products not found have zero stock
+	ListInventories(context.Context, *ListInventoriesRequest) (*ListInventoriesResponse, error)
+	// WatchInventory sends current stock of every product first then the latest stock of changed products,
+	// changes in between sends can be coalesced
+	WatchInventory(*WatchInventoryRequest, grpc.ServerStreamingServer[Inventory]) error
+	SetStockThreshold(context.Context, *StockThreshold) (*StockThreshold, error)
+	// SetFulfillmentPolicy decides what happens when stock of a product is not enough,
+	// items already backordered are kept whatever the new policy
+	SetFulfillmentPolicy(context.Context, *FulfillmentPolicy) (*FulfillmentPolicy, error)
+	// ListLowStock lists every product currently below its threshold
+	ListLowStock(context.Context, *ListLowStockRequest) (*ListLowStockResponse, error)
+	// WatchLowStock sends an alert whenever a product crosses below its threshold
+	WatchLowStock(*WatchLowStockRequest, grpc.ServerStreamingServer[LowStock]) error
+	mustEmbedUnimplementedTomShopServer()
+}
+
+// UnimplementedTomShopServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTomShopServer struct{}
+
+func (UnimplementedTomShopServer) MakeOrder(context.Context, *OrderRequest) (*OrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MakeOrder not implemented")
+}
+func (UnimplementedTomShopServer) MakeOrders(context.Context, *MakeOrdersRequest) (*MakeOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MakeOrders not implemented")
+}
+func (UnimplementedTomShopServer) StreamOrders(grpc.ClientStreamingServer[BatchOrder, MakeOrdersResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamOrders not implemented")
+}
+func (UnimplementedTomShopServer) ChangeStock(context.Context, *ChangeStockRequest) (*StockMovement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeStock not implemented")
+}
+func (UnimplementedTomShopServer) GetStockHistory(context.Context, *StockHistoryRequest) (*StockHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStockHistory not implemented")
+}
+func (UnimplementedTomShopServer) ListInventories(context.Context, *ListInventoriesRequest) (*ListInventoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInventories not implemented")
+}
+func (UnimplementedTomShopServer) WatchInventory(*WatchInventoryRequest, grpc.ServerStreamingServer[Inventory]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchInventory not implemented")
+}
+func (UnimplementedTomShopServer) SetStockThreshold(context.Context, *StockThreshold) (*StockThreshold, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetStockThreshold not implemented")
+}
+func (UnimplementedTomShopServer) SetFulfillmentPolicy(context.Context, *FulfillmentPolicy) (*FulfillmentPolicy, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFulfillmentPolicy not implemented")
+}
+func (UnimplementedTomShopServer) ListLowStock(context.Context, *ListLowStockRequest) (*ListLowStockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLowStock not implemented")
+}
+func (UnimplementedTomShopServer) WatchLowStock(*WatchLowStockRequest, grpc.ServerStreamingServer[LowStock]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchLowStock not implemented")
+}
+func (UnimplementedTomShopServer) mustEmbedUnimplementedTomShopServer() {}
+func (UnimplementedTomShopServer) testEmbeddedByValue()                 {}
+
+// UnsafeTomShopServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TomShopServer will
+// result in compilation errors.
+type UnsafeTomShopServer interface {
+	mustEmbedUnimplementedTomShopServer()
+}
+
+func RegisterTomShopServer(s grpc.ServiceRegistrar, srv TomShopServer) {
+	// If the following call pancis, it indicates UnimplementedTomShopServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TomShop_ServiceDesc, srv)
+}
+
+func _TomShop_MakeOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).MakeOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_MakeOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).MakeOrder(ctx, req.(*OrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_MakeOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MakeOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).MakeOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_MakeOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).MakeOrders(ctx, req.(*MakeOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_StreamOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TomShopServer).StreamOrders(&grpc.GenericServerStream[BatchOrder, MakeOrdersResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TomShop_StreamOrdersServer = grpc.ClientStreamingServer[BatchOrder, MakeOrdersResponse]
+
+func _TomShop_ChangeStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).ChangeStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_ChangeStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).ChangeStock(ctx, req.(*ChangeStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_GetStockHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StockHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).GetStockHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_GetStockHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).GetStockHistory(ctx, req.(*StockHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_ListInventories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInventoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).ListInventories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_ListInventories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).ListInventories(ctx, req.(*ListInventoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_WatchInventory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchInventoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TomShopServer).WatchInventory(m, &grpc.GenericServerStream[WatchInventoryRequest, Inventory]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TomShop_WatchInventoryServer = grpc.ServerStreamingServer[Inventory]
+
+func _TomShop_SetStockThreshold_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StockThreshold)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).SetStockThreshold(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_SetStockThreshold_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).SetStockThreshold(ctx, req.(*StockThreshold))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_SetFulfillmentPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FulfillmentPolicy)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).SetFulfillmentPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_SetFulfillmentPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).SetFulfillmentPolicy(ctx, req.(*FulfillmentPolicy))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_ListLowStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLowStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).ListLowStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_ListLowStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).ListLowStock(ctx, req.(*ListLowStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TomShop_WatchLowStock_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchLowStockRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TomShopServer).WatchLowStock(m, &grpc.GenericServerStream[WatchLowStockRequest, LowStock]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TomShop_WatchLowStockServer = grpc.ServerStreamingServer[LowStock]
+
+// TomShop_ServiceDesc is the grpc.ServiceDesc for TomShop service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TomShop_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tomshop.v1.TomShop",
+	HandlerType: (*TomShopServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "MakeOrder",
+			Handler:    _TomShop_MakeOrder_Handler,
+		},
+		{
+			MethodName: "MakeOrders",
+			Handler:    _TomShop_MakeOrders_Handler,
+		},
+		{
+			MethodName: "ChangeStock",
+			Handler:    _TomShop_ChangeStock_Handler,
+		},
+		{
+			MethodName: "GetStockHistory",
+			Handler:    _TomShop_GetStockHistory_Handler,
+		},
+		{
+			MethodName: "ListInventories",
+			Handler:    _TomShop_ListInventories_Handler,
+		},
+		{
+			MethodName: "SetStockThreshold",
+			Handler:    _TomShop_SetStockThreshold_Handler,
+		},
+		{
+			MethodName: "SetFulfillmentPolicy",
+			Handler:    _TomShop_SetFulfillmentPolicy_Handler,
+		},
+		{
+			MethodName: "ListLowStock",
+			Handler:    _TomShop_ListLowStock_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamOrders",
+			Handler:       _TomShop_StreamOrders_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchInventory",
+			Handler:       _TomShop_WatchInventory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchLowStock",
+			Handler:       _TomShop_WatchLowStock_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "service.proto",
+}
@@ -0,0 +1,823 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: v2/service.proto
+
+package tomshop_v2
+
+import (
+	_ "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FulfillmentMode int32
+
+const (
+	// ALL_OR_NOTHING rejects the whole order if any line cannot be fulfilled
+	FulfillmentMode_ALL_OR_NOTHING FulfillmentMode = 0
+	// BEST_EFFORT takes whatever in stock for every line
+	FulfillmentMode_BEST_EFFORT FulfillmentMode = 1
+	// PER_LINE_MINIMUM takes whatever in stock for lines can get at least their minQuantity
+	FulfillmentMode_PER_LINE_MINIMUM FulfillmentMode = 2
+)
+
+// Enum value maps for FulfillmentMode.
+var (
+	FulfillmentMode_name = map[int32]string{
+		0: "ALL_OR_NOTHING",
+		1: "BEST_EFFORT",
+		2: "PER_LINE_MINIMUM",
+	}
+	FulfillmentMode_value = map[string]int32{
+		"ALL_OR_NOTHING":   0,
+		"BEST_EFFORT":      1,
+		"PER_LINE_MINIMUM": 2,
+	}
+)
+
+func (x FulfillmentMode) Enum() *FulfillmentMode {
+	p := new(FulfillmentMode)
+	*p = x
+	return p
+}
+
+func (x FulfillmentMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FulfillmentMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_v2_service_proto_enumTypes[0].Descriptor()
+}
+
+func (FulfillmentMode) Type() protoreflect.EnumType {
+	return &file_v2_service_proto_enumTypes[0]
+}
+
+func (x FulfillmentMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FulfillmentMode.Descriptor instead.
+func (FulfillmentMode) EnumDescriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{0}
+}
+
+type OrderStatus int32
+
+const (
+	OrderStatus_UNKNOWN_STATUS OrderStatus = 0
+	// PLACED orders took stock or backordered their lines
+	OrderStatus_PLACED OrderStatus = 1
+	// REJECTED orders changed nothing, the reason is in rejection
+	OrderStatus_REJECTED OrderStatus = 2
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "UNKNOWN_STATUS",
+		1: "PLACED",
+		2: "REJECTED",
+	}
+	OrderStatus_value = map[string]int32{
+		"UNKNOWN_STATUS": 0,
+		"PLACED":         1,
+		"REJECTED":       2,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_v2_service_proto_enumTypes[1].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_v2_service_proto_enumTypes[1]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{1}
+}
+
+type RejectionReason int32
+
+const (
+	RejectionReason_UNKNOWN_REJECTION     RejectionReason = 0
+	RejectionReason_OUT_OF_STOCK          RejectionReason = 1
+	RejectionReason_UNKNOWN_COUPON        RejectionReason = 2
+	RejectionReason_COUPON_NOT_APPLICABLE RejectionReason = 3
+)
+
+// Enum value maps for RejectionReason.
+var (
+	RejectionReason_name = map[int32]string{
+		0: "UNKNOWN_REJECTION",
+		1: "OUT_OF_STOCK",
+		2: "UNKNOWN_COUPON",
+		3: "COUPON_NOT_APPLICABLE",
+	}
+	RejectionReason_value = map[string]int32{
+		"UNKNOWN_REJECTION":     0,
+		"OUT_OF_STOCK":          1,
+		"UNKNOWN_COUPON":        2,
+		"COUPON_NOT_APPLICABLE": 3,
+	}
+)
+
+func (x RejectionReason) Enum() *RejectionReason {
+	p := new(RejectionReason)
+	*p = x
+	return p
+}
+
+func (x RejectionReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RejectionReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_v2_service_proto_enumTypes[2].Descriptor()
+}
+
+func (RejectionReason) Type() protoreflect.EnumType {
+	return &file_v2_service_proto_enumTypes[2]
+}
+
+func (x RejectionReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RejectionReason.Descriptor instead.
+func (RejectionReason) EnumDescriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{2}
+}
+
+type OrderLine struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Quantity  int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// minQuantity used by PER_LINE_MINIMUM mode, 0 means the whole quantity
+	MinQuantity   int64 `protobuf:"varint,3,opt,name=minQuantity,proto3" json:"minQuantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderLine) Reset() {
+	*x = OrderLine{}
+	mi := &file_v2_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderLine) ProtoMessage() {}
+
+func (x *OrderLine) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderLine.ProtoReflect.Descriptor instead.
+func (*OrderLine) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *OrderLine) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *OrderLine) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderLine) GetMinQuantity() int64 {
+	if x != nil {
+		return x.MinQuantity
+	}
+	return 0
+}
+
+type PlaceOrderRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// lines of different products, at most 100
+	Lines []*OrderLine `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	// customerID is required by coupons with per customer limit
+	CustomerID  int64    `protobuf:"varint,2,opt,name=customerID,proto3" json:"customerID,omitempty"`
+	CouponCodes []string `protobuf:"bytes,3,rep,name=couponCodes,proto3" json:"couponCodes,omitempty"`
+	// hints for the warehouse allocation strategy configured in server
+	RegionHint           string `protobuf:"bytes,4,opt,name=regionHint,proto3" json:"regionHint,omitempty"`
+	PreferredWarehouseID int64  `protobuf:"varint,5,opt,name=preferredWarehouseID,proto3" json:"preferredWarehouseID,omitempty"`
+	// allowBackorder accepts backordered or preordered lines for products have such policy
+	AllowBackorder bool `protobuf:"varint,6,opt,name=allowBackorder,proto3" json:"allowBackorder,omitempty"`
+	// fulfillmentMode other than ALL_OR_NOTHING doesn't backorder
+	FulfillmentMode FulfillmentMode `protobuf:"varint,7,opt,name=fulfillmentMode,proto3,enum=tomshop.v2.FulfillmentMode" json:"fulfillmentMode,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PlaceOrderRequest) Reset() {
+	*x = PlaceOrderRequest{}
+	mi := &file_v2_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlaceOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaceOrderRequest) ProtoMessage() {}
+
+func (x *PlaceOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaceOrderRequest.ProtoReflect.Descriptor instead.
+func (*PlaceOrderRequest) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PlaceOrderRequest) GetLines() []*OrderLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *PlaceOrderRequest) GetCustomerID() int64 {
+	if x != nil {
+		return x.CustomerID
+	}
+	return 0
+}
+
+func (x *PlaceOrderRequest) GetCouponCodes() []string {
+	if x != nil {
+		return x.CouponCodes
+	}
+	return nil
+}
+
+func (x *PlaceOrderRequest) GetRegionHint() string {
+	if x != nil {
+		return x.RegionHint
+	}
+	return ""
+}
+
+func (x *PlaceOrderRequest) GetPreferredWarehouseID() int64 {
+	if x != nil {
+		return x.PreferredWarehouseID
+	}
+	return 0
+}
+
+func (x *PlaceOrderRequest) GetAllowBackorder() bool {
+	if x != nil {
+		return x.AllowBackorder
+	}
+	return false
+}
+
+func (x *PlaceOrderRequest) GetFulfillmentMode() FulfillmentMode {
+	if x != nil {
+		return x.FulfillmentMode
+	}
+	return FulfillmentMode_ALL_OR_NOTHING
+}
+
+type Rejection struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Reason RejectionReason        `protobuf:"varint,1,opt,name=reason,proto3,enum=tomshop.v2.RejectionReason" json:"reason,omitempty"`
+	// productID of the line could not be fulfilled, 0 if the order is rejected as a whole
+	ProductID     int64  `protobuf:"varint,2,opt,name=productID,proto3" json:"productID,omitempty"`
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rejection) Reset() {
+	*x = Rejection{}
+	mi := &file_v2_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rejection) ProtoMessage() {}
+
+func (x *Rejection) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rejection.ProtoReflect.Descriptor instead.
+func (*Rejection) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Rejection) GetReason() RejectionReason {
+	if x != nil {
+		return x.Reason
+	}
+	return RejectionReason_UNKNOWN_REJECTION
+}
+
+func (x *Rejection) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *Rejection) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type Allocation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WarehouseID   int64                  `protobuf:"varint,1,opt,name=warehouseID,proto3" json:"warehouseID,omitempty"`
+	Quantity      int64                  `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Allocation) Reset() {
+	*x = Allocation{}
+	mi := &file_v2_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Allocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Allocation) ProtoMessage() {}
+
+func (x *Allocation) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Allocation.ProtoReflect.Descriptor instead.
+func (*Allocation) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Allocation) GetWarehouseID() int64 {
+	if x != nil {
+		return x.WarehouseID
+	}
+	return 0
+}
+
+func (x *Allocation) GetQuantity() int64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+type LineResult struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductID int64                  `protobuf:"varint,1,opt,name=productID,proto3" json:"productID,omitempty"`
+	Requested int64                  `protobuf:"varint,2,opt,name=requested,proto3" json:"requested,omitempty"`
+	// immediate is taken from stock, backordered ships when restocked or released
+	Immediate   int64 `protobuf:"varint,3,opt,name=immediate,proto3" json:"immediate,omitempty"`
+	Backordered int64 `protobuf:"varint,4,opt,name=backordered,proto3" json:"backordered,omitempty"`
+	Preorder    bool  `protobuf:"varint,5,opt,name=preorder,proto3" json:"preorder,omitempty"`
+	// unfulfilled is dropped from the order in non strict fulfillment mode
+	Unfulfilled int64 `protobuf:"varint,6,opt,name=unfulfilled,proto3" json:"unfulfilled,omitempty"`
+	// allocations of immediate to warehouses, empty when server doesn't use warehouses
+	Allocations   []*Allocation `protobuf:"bytes,7,rep,name=allocations,proto3" json:"allocations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LineResult) Reset() {
+	*x = LineResult{}
+	mi := &file_v2_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LineResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LineResult) ProtoMessage() {}
+
+func (x *LineResult) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LineResult.ProtoReflect.Descriptor instead.
+func (*LineResult) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LineResult) GetProductID() int64 {
+	if x != nil {
+		return x.ProductID
+	}
+	return 0
+}
+
+func (x *LineResult) GetRequested() int64 {
+	if x != nil {
+		return x.Requested
+	}
+	return 0
+}
+
+func (x *LineResult) GetImmediate() int64 {
+	if x != nil {
+		return x.Immediate
+	}
+	return 0
+}
+
+func (x *LineResult) GetBackordered() int64 {
+	if x != nil {
+		return x.Backordered
+	}
+	return 0
+}
+
+func (x *LineResult) GetPreorder() bool {
+	if x != nil {
+		return x.Preorder
+	}
+	return false
+}
+
+func (x *LineResult) GetUnfulfilled() int64 {
+	if x != nil {
+		return x.Unfulfilled
+	}
+	return 0
+}
+
+func (x *LineResult) GetAllocations() []*Allocation {
+	if x != nil {
+		return x.Allocations
+	}
+	return nil
+}
+
+// Totals in the smallest currency unit
+type Totals struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subtotal      int64                  `protobuf:"varint,1,opt,name=subtotal,proto3" json:"subtotal,omitempty"`
+	Discount      int64                  `protobuf:"varint,2,opt,name=discount,proto3" json:"discount,omitempty"`
+	Total         int64                  `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Totals) Reset() {
+	*x = Totals{}
+	mi := &file_v2_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Totals) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Totals) ProtoMessage() {}
+
+func (x *Totals) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Totals.ProtoReflect.Descriptor instead.
+func (*Totals) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Totals) GetSubtotal() int64 {
+	if x != nil {
+		return x.Subtotal
+	}
+	return 0
+}
+
+func (x *Totals) GetDiscount() int64 {
+	if x != nil {
+		return x.Discount
+	}
+	return 0
+}
+
+func (x *Totals) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type PlaceOrderResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// orderID is the reference of the order in stock movements, empty if rejected
+	OrderID string      `protobuf:"bytes,1,opt,name=orderID,proto3" json:"orderID,omitempty"`
+	Status  OrderStatus `protobuf:"varint,2,opt,name=status,proto3,enum=tomshop.v2.OrderStatus" json:"status,omitempty"`
+	// rejection is set if REJECTED
+	Rejection *Rejection `protobuf:"bytes,3,opt,name=rejection,proto3" json:"rejection,omitempty"`
+	Totals    *Totals    `protobuf:"bytes,4,opt,name=totals,proto3" json:"totals,omitempty"`
+	// lines is empty if rejected
+	Lines         []*LineResult `protobuf:"bytes,5,rep,name=lines,proto3" json:"lines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlaceOrderResponse) Reset() {
+	*x = PlaceOrderResponse{}
+	mi := &file_v2_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlaceOrderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlaceOrderResponse) ProtoMessage() {}
+
+func (x *PlaceOrderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v2_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlaceOrderResponse.ProtoReflect.Descriptor instead.
+func (*PlaceOrderResponse) Descriptor() ([]byte, []int) {
+	return file_v2_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PlaceOrderResponse) GetOrderID() string {
+	if x != nil {
+		return x.OrderID
+	}
+	return ""
+}
+
+func (x *PlaceOrderResponse) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_UNKNOWN_STATUS
+}
+
+func (x *PlaceOrderResponse) GetRejection() *Rejection {
+	if x != nil {
+		return x.Rejection
+	}
+	return nil
+}
+
+func (x *PlaceOrderResponse) GetTotals() *Totals {
+	if x != nil {
+		return x.Totals
+	}
+	return nil
+}
+
+func (x *PlaceOrderResponse) GetLines() []*LineResult {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+var File_v2_service_proto protoreflect.FileDescriptor
+
+const file_v2_service_proto_rawDesc = "" +
+	"\n" +
+	"\x10v2/service.proto\x12\n" +
+	"tomshop.v2\x1a\x1bbuf/validate/validate.proto\x1a\x1cgoogle/api/annotations.proto\"\x82\x01\n" +
+	"\tOrderLine\x12%\n" +
+	"\tproductID\x18\x01 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\tproductID\x12#\n" +
+	"\bquantity\x18\x02 \x01(\x03B\a\xbaH\x04\"\x02 \x00R\bquantity\x12)\n" +
+	"\vminQuantity\x18\x03 \x01(\x03B\a\xbaH\x04\"\x02(\x00R\vminQuantity\"\xa7\x03\n" +
+	"\x11PlaceOrderRequest\x12\x8c\x01\n" +
+	"\x05lines\x18\x01 \x03(\v2\x15.tomshop.v2.OrderLineB_\xbaH\\\xba\x01R\n" +
+	"\x14lines.unique_product\x12\x17products must be unique\x1a!this.map(l, l.productID).unique()\x92\x01\x04\b\x01\x10dR\x05lines\x12\x1e\n" +
+	"\n" +
+	"customerID\x18\x02 \x01(\x03R\n" +
+	"customerID\x12 \n" +
+	"\vcouponCodes\x18\x03 \x03(\tR\vcouponCodes\x12\x1e\n" +
+	"\n" +
+	"regionHint\x18\x04 \x01(\tR\n" +
+	"regionHint\x122\n" +
+	"\x14preferredWarehouseID\x18\x05 \x01(\x03R\x14preferredWarehouseID\x12&\n" +
+	"\x0eallowBackorder\x18\x06 \x01(\bR\x0eallowBackorder\x12E\n" +
+	"\x0ffulfillmentMode\x18\a \x01(\x0e2\x1b.tomshop.v2.FulfillmentModeR\x0ffulfillmentMode\"x\n" +
+	"\tRejection\x123\n" +
+	"\x06reason\x18\x01 \x01(\x0e2\x1b.tomshop.v2.RejectionReasonR\x06reason\x12\x1c\n" +
+	"\tproductID\x18\x02 \x01(\x03R\tproductID\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"J\n" +
+	"\n" +
+	"Allocation\x12 \n" +
+	"\vwarehouseID\x18\x01 \x01(\x03R\vwarehouseID\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x03R\bquantity\"\x80\x02\n" +
+	"\n" +
+	"LineResult\x12\x1c\n" +
+	"\tproductID\x18\x01 \x01(\x03R\tproductID\x12\x1c\n" +
+	"\trequested\x18\x02 \x01(\x03R\trequested\x12\x1c\n" +
+	"\timmediate\x18\x03 \x01(\x03R\timmediate\x12 \n" +
+	"\vbackordered\x18\x04 \x01(\x03R\vbackordered\x12\x1a\n" +
+	"\bpreorder\x18\x05 \x01(\bR\bpreorder\x12 \n" +
+	"\vunfulfilled\x18\x06 \x01(\x03R\vunfulfilled\x128\n" +
+	"\vallocations\x18\a \x03(\v2\x16.tomshop.v2.AllocationR\vallocations\"V\n" +
+	"\x06Totals\x12\x1a\n" +
+	"\bsubtotal\x18\x01 \x01(\x03R\bsubtotal\x12\x1a\n" +
+	"\bdiscount\x18\x02 \x01(\x03R\bdiscount\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x03R\x05total\"\xee\x01\n" +
+	"\x12PlaceOrderResponse\x12\x18\n" +
+	"\aorderID\x18\x01 \x01(\tR\aorderID\x12/\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x17.tomshop.v2.OrderStatusR\x06status\x123\n" +
+	"\trejection\x18\x03 \x01(\v2\x15.tomshop.v2.RejectionR\trejection\x12*\n" +
+	"\x06totals\x18\x04 \x01(\v2\x12.tomshop.v2.TotalsR\x06totals\x12,\n" +
+	"\x05lines\x18\x05 \x03(\v2\x16.tomshop.v2.LineResultR\x05lines*L\n" +
+	"\x0fFulfillmentMode\x12\x12\n" +
+	"\x0eALL_OR_NOTHING\x10\x00\x12\x0f\n" +
+	"\vBEST_EFFORT\x10\x01\x12\x14\n" +
+	"\x10PER_LINE_MINIMUM\x10\x02*;\n" +
+	"\vOrderStatus\x12\x12\n" +
+	"\x0eUNKNOWN_STATUS\x10\x00\x12\n" +
+	"\n" +
+	"\x06PLACED\x10\x01\x12\f\n" +
+	"\bREJECTED\x10\x02*i\n" +
+	"\x0fRejectionReason\x12\x15\n" +
+	"\x11UNKNOWN_REJECTION\x10\x00\x12\x10\n" +
+	"\fOUT_OF_STOCK\x10\x01\x12\x12\n" +
+	"\x0eUNKNOWN_COUPON\x10\x02\x12\x19\n" +
+	"\x15COUPON_NOT_APPLICABLE\x10\x032m\n" +
+	"\aTomShop\x12b\n" +
+	"\n" +
+	"PlaceOrder\x12\x1d.tomshop.v2.PlaceOrderRequest\x1a\x1e.tomshop.v2.PlaceOrderResponse\"\x15\x82\xd3\xe4\x93\x02\x0f:\x01*\"\n" +
+	"/v2/ordersB\x1cZ\x1atomshop/grpc/v2;tomshop_v2b\x06proto3"
+
+var (
+	file_v2_service_proto_rawDescOnce sync.Once
+	file_v2_service_proto_rawDescData []byte
+)
+
+func file_v2_service_proto_rawDescGZIP() []byte {
+	file_v2_service_proto_rawDescOnce.Do(func() {
+		file_v2_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v2_service_proto_rawDesc), len(file_v2_service_proto_rawDesc)))
+	})
+	return file_v2_service_proto_rawDescData
+}
+
+var file_v2_service_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_v2_service_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_v2_service_proto_goTypes = []any{
+	(FulfillmentMode)(0),       // 0: tomshop.v2.FulfillmentMode
+	(OrderStatus)(0),           // 1: tomshop.v2.OrderStatus
+	(RejectionReason)(0),       // 2: tomshop.v2.RejectionReason
+	(*OrderLine)(nil),          // 3: tomshop.v2.OrderLine
+	(*PlaceOrderRequest)(nil),  // 4: tomshop.v2.PlaceOrderRequest
+	(*Rejection)(nil),          // 5: tomshop.v2.Rejection
+	(*Allocation)(nil),         // 6: tomshop.v2.Allocation
+	(*LineResult)(nil),         // 7: tomshop.v2.LineResult
+	(*Totals)(nil),             // 8: tomshop.v2.Totals
+	(*PlaceOrderResponse)(nil), // 9: tomshop.v2.PlaceOrderResponse
+}
+var file_v2_service_proto_depIdxs = []int32{
+	3, // 0: tomshop.v2.PlaceOrderRequest.lines:type_name -> tomshop.v2.OrderLine
+	0, // 1: tomshop.v2.PlaceOrderRequest.fulfillmentMode:type_name -> tomshop.v2.FulfillmentMode
+	2, // 2: tomshop.v2.Rejection.reason:type_name -> tomshop.v2.RejectionReason
+	6, // 3: tomshop.v2.LineResult.allocations:type_name -> tomshop.v2.Allocation
+	1, // 4: tomshop.v2.PlaceOrderResponse.status:type_name -> tomshop.v2.OrderStatus
+	5, // 5: tomshop.v2.PlaceOrderResponse.rejection:type_name -> tomshop.v2.Rejection
+	8, // 6: tomshop.v2.PlaceOrderResponse.totals:type_name -> tomshop.v2.Totals
+	7, // 7: tomshop.v2.PlaceOrderResponse.lines:type_name -> tomshop.v2.LineResult
+	4, // 8: tomshop.v2.TomShop.PlaceOrder:input_type -> tomshop.v2.PlaceOrderRequest
+	9, // 9: tomshop.v2.TomShop.PlaceOrder:output_type -> tomshop.v2.PlaceOrderResponse
+	9, // [9:10] is the sub-list for method output_type
+	8, // [8:9] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_v2_service_proto_init() }
+func file_v2_service_proto_init() {
+	if File_v2_service_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v2_service_proto_rawDesc), len(file_v2_service_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_v2_service_proto_goTypes,
+		DependencyIndexes: file_v2_service_proto_depIdxs,
+		EnumInfos:         file_v2_service_proto_enumTypes,
+		MessageInfos:      file_v2_service_proto_msgTypes,
+	}.Build()
+	File_v2_service_proto = out.File
+	file_v2_service_proto_goTypes = nil
+	file_v2_service_proto_depIdxs = nil
+}
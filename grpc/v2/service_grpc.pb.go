@@ -0,0 +1,127 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: v2/service.proto
+
+package tomshop_v2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TomShop_PlaceOrder_FullMethodName = "/tomshop.v2.TomShop/PlaceOrder"
+)
+
+// TomShopClient is the client API for TomShop service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TomShop reports orders refused by business rules in responses, errors are left for invalid
+// requests and failures of the server
+type TomShopClient interface {
+	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error)
+}
+
+type tomShopClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTomShopClient(cc grpc.ClientConnInterface) TomShopClient {
+	return &tomShopClient{cc}
+}
+
+func (c *tomShopClient) PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlaceOrderResponse)
+	err := c.cc.Invoke(ctx, TomShop_PlaceOrder_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TomShopServer is the server API for TomShop service.
+// All implementations must embed UnimplementedTomShopServer
+// for forward compatibility.
+//
+// TomShop reports orders refused by business rules in responses, errors are left for invalid
+// requests and failures of the server
+type TomShopServer interface {
+	PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error)
+	mustEmbedUnimplementedTomShopServer()
+}
+
+// UnimplementedTomShopServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTomShopServer struct{}
+
+func (UnimplementedTomShopServer) PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PlaceOrder not implemented")
+}
+func (UnimplementedTomShopServer) mustEmbedUnimplementedTomShopServer() {}
+func (UnimplementedTomShopServer) testEmbeddedByValue()                 {}
+
+// UnsafeTomShopServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TomShopServer will
+// result in compilation errors.
+type UnsafeTomShopServer interface {
+	mustEmbedUnimplementedTomShopServer()
+}
+
+func RegisterTomShopServer(s grpc.ServiceRegistrar, srv TomShopServer) {
+	// If the following call pancis, it indicates UnimplementedTomShopServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TomShop_ServiceDesc, srv)
+}
+
+func _TomShop_PlaceOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaceOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TomShopServer).PlaceOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TomShop_PlaceOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TomShopServer).PlaceOrder(ctx, req.(*PlaceOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TomShop_ServiceDesc is the grpc.ServiceDesc for TomShop service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TomShop_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tomshop.v2.TomShop",
+	HandlerType: (*TomShopServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PlaceOrder",
+			Handler:    _TomShop_PlaceOrder_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "v2/service.proto",
+}
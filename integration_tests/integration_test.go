@@ -5,16 +5,18 @@ import (
 	"database/sql"
 	"log"
 	"os"
-	"reflect"
 	"testing"
 	"time"
 
 	pb "tomshop/grpc"
+	"tomshop/repositories"
+	repo "tomshop/repositories/sql"
 
 	_ "github.com/lib/pq"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestRunner(t *testing.T) {
@@ -46,6 +48,38 @@ func TestRunner(t *testing.T) {
 	t.Run("order with negative stock", func(tt *testing.T) {
 		orderWithNegativeStock(c, db, tt)
 	})
+
+	t.Run("2 concurent request using a coupon can only be redeemed once", func(tt *testing.T) {
+		concurrentCouponRedemption(c, db, tt)
+	})
+
+	t.Run("best effort order takes whatever in stock", func(tt *testing.T) {
+		bestEffortOrder(c, db, tt)
+	})
+
+	t.Run("stock history records corrections and orders", func(tt *testing.T) {
+		stockHistory(c, db, tt)
+	})
+
+	t.Run("watching inventory receives stock after order", func(tt *testing.T) {
+		watchInventory(c, db, tt)
+	})
+
+	t.Run("order taking stock below threshold alerts once", func(tt *testing.T) {
+		lowStockAlert(c, db, tt)
+	})
+
+	t.Run("batch orders are made independently", func(tt *testing.T) {
+		batchOrders(c, db, tt)
+	})
+
+	t.Run("listing inventories sees stock taken by order", func(tt *testing.T) {
+		listInventories(c, db, tt)
+	})
+
+	t.Run("listing unpublished events skips excluded aggregates", func(tt *testing.T) {
+		unpublishedEvents(db, tt)
+	})
 }
 
 // example 1 (details can be found in Manabie Senior Golang BE Coding Challenge)
@@ -65,14 +99,20 @@ func scenario1(c pb.TomShopClient, db *sql.DB, t *testing.T) {
 		},
 	})
 
-	if !reflect.DeepEqual(resp, &pb.OrderResponse{
-		Successful: true,
-	}) {
-		t.Error("expecting successful request")
+	if err != nil {
+		t.Fatal("unexpected error", err)
 	}
 
-	if err != nil {
-		t.Error("unexpected error", err)
+	// products of the scenario have no price, the order ID is generated
+	if resp.OrderID == "" || !proto.Equal(resp, &pb.OrderResponse{
+		Successful: true,
+		OrderID:    resp.OrderID,
+		Lines: []*pb.LineResult{
+			{ProductID: 11, Immediate: 2},
+			{ProductID: 12, Immediate: 1},
+		},
+	}) {
+		t.Error("expecting successful request with every line taken, got", resp)
 	}
 
 	checkUpdatedQty(db, t, 11, 8)
@@ -233,6 +273,329 @@ func concurrentRequestsOnlyOneOK(c pb.TomShopClient, db *sql.DB, t *testing.T) {
 	}
 }
 
+func concurrentCouponRedemption(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	type result struct {
+		resp *pb.OrderResponse
+		err  error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			resp, err := c.MakeOrder(ctx, &pb.OrderRequest{
+				Purchases: []*pb.Order{
+					&pb.Order{
+						ProductID: 51,
+						Quantity:  1,
+					},
+				},
+				CustomerID:  1,
+				CouponCodes: []string{"ONCE10"},
+			})
+			results <- result{resp, err}
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err == nil {
+			succeeded++
+			if r.resp.Total != 90 {
+				t.Error("expecting discounted total 90, got", r.resp.Total)
+			}
+			continue
+		}
+
+		if status.Code(r.err) != codes.FailedPrecondition {
+			t.Error("expecting gRPC FailedPrecondition error, got", r.err)
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("expecting exactly one successful order, got %d", succeeded)
+	}
+
+	checkUpdatedQty(db, t, 51, 9)
+}
+
+func bestEffortOrder(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := c.MakeOrder(ctx, &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			&pb.Order{
+				ProductID: 61,
+				Quantity:  5,
+			},
+			&pb.Order{
+				ProductID: 62,
+				Quantity:  1,
+			},
+		},
+		FulfillmentMode: pb.FulfillmentMode_BEST_EFFORT,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if resp.Lines[0].Immediate != 3 || resp.Lines[0].Unfulfilled != 2 {
+		t.Error("expecting 3 items taken and 2 unfulfilled for product 61, got", resp.Lines[0])
+	}
+
+	if resp.Lines[1].Immediate != 0 || resp.Lines[1].Unfulfilled != 1 {
+		t.Error("expecting nothing taken for product 62, got", resp.Lines[1])
+	}
+
+	checkUpdatedQty(db, t, 61, 0)
+	checkUpdatedQty(db, t, 62, 0)
+}
+
+func stockHistory(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := c.ChangeStock(ctx, &pb.ChangeStockRequest{
+		ProductID: 71,
+		Quantity:  5,
+		Reason:    pb.StockMovementReason_CORRECTION,
+		Actor:     "integration_tests",
+	})
+	if err != nil {
+		t.Fatal("unexpected error when correcting stock", err)
+	}
+
+	resp, err := c.MakeOrder(ctx, &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			&pb.Order{
+				ProductID: 71,
+				Quantity:  2,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error when ordering", err)
+	}
+
+	history, err := c.GetStockHistory(ctx, &pb.StockHistoryRequest{
+		ProductID: 71,
+		Limit:     2,
+	})
+	if err != nil {
+		t.Fatal("unexpected error when getting history", err)
+	}
+
+	if len(history.Movements) != 2 {
+		t.Fatal("expecting 2 movements, got", history.Movements)
+	}
+
+	order, correction := history.Movements[0], history.Movements[1]
+	if order.Reason != pb.StockMovementReason_ORDER || order.Delta != -2 || order.Reference != resp.OrderID ||
+		order.Before != 5 || order.After != 3 {
+		t.Error("unexpected order movement", order)
+	}
+
+	if correction.Reason != pb.StockMovementReason_CORRECTION || correction.After != 5 || correction.Actor != "integration_tests" {
+		t.Error("unexpected correction movement", correction)
+	}
+
+	checkUpdatedQty(db, t, 71, 3)
+}
+
+func watchInventory(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := c.WatchInventory(ctx, &pb.WatchInventoryRequest{
+		ProductIDs: []int64{81},
+	})
+	if err != nil {
+		t.Fatal("unexpected error when watching", err)
+	}
+
+	snapshot, err := stream.Recv()
+	if err != nil {
+		t.Fatal("unexpected error when receiving snapshot", err)
+	}
+
+	if snapshot.ProductID != 81 || snapshot.StockCount != 4 {
+		t.Error("expecting snapshot with stock 4, got", snapshot)
+	}
+
+	_, err = c.MakeOrder(ctx, &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			&pb.Order{
+				ProductID: 81,
+				Quantity:  1,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error when ordering", err)
+	}
+
+	changed, err := stream.Recv()
+	if err != nil {
+		t.Fatal("unexpected error when receiving change", err)
+	}
+
+	if changed.ProductID != 81 || changed.StockCount != 3 {
+		t.Error("expecting change with stock 3, got", changed)
+	}
+}
+
+func lowStockAlert(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := c.SetStockThreshold(ctx, &pb.StockThreshold{
+		ProductID: 91,
+		Threshold: 3,
+	})
+	if err != nil {
+		t.Fatal("unexpected error when setting threshold", err)
+	}
+
+	stream, err := c.WatchLowStock(ctx, &pb.WatchLowStockRequest{})
+	if err != nil {
+		t.Fatal("unexpected error when watching", err)
+	}
+
+	// crossing below threshold then staying below
+	for _, qty := range []int64{3, 1} {
+		_, err = c.MakeOrder(ctx, &pb.OrderRequest{
+			Purchases: []*pb.Order{
+				&pb.Order{
+					ProductID: 91,
+					Quantity:  qty,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal("unexpected error when ordering", err)
+		}
+	}
+
+	alert, err := stream.Recv()
+	if err != nil {
+		t.Fatal("unexpected error when receiving alert", err)
+	}
+
+	if alert.ProductID != 91 || alert.StockCount != 2 {
+		t.Error("expecting alert with stock 2, got", alert)
+	}
+
+	low, err := c.ListLowStock(ctx, &pb.ListLowStockRequest{})
+	if err != nil {
+		t.Fatal("unexpected error when listing low stock", err)
+	}
+
+	found := false
+	for _, l := range low.Products {
+		found = found || (l.ProductID == 91 && l.StockCount == 1)
+	}
+	if !found {
+		t.Error("expecting product 91 with stock 1 listed, got", low.Products)
+	}
+}
+
+func batchOrders(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	order := &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			&pb.Order{
+				ProductID: 101,
+				Quantity:  1,
+			},
+		},
+	}
+	resp, err := c.MakeOrders(ctx, &pb.MakeOrdersRequest{
+		Orders: []*pb.BatchOrder{
+			{ClientID: "first", Order: order},
+			{ClientID: "second", Order: order},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	first, second := resp.Results[0], resp.Results[1]
+	if first.ClientID != "first" || codes.Code(first.Code) != codes.OK || !first.Response.Successful {
+		t.Error("expecting first order successful, got", first)
+	}
+
+	if second.ClientID != "second" || codes.Code(second.Code) != codes.FailedPrecondition {
+		t.Error("expecting second order out of stock, got", second)
+	}
+
+	checkUpdatedQty(db, t, 101, 0)
+}
+
+func listInventories(c pb.TomShopClient, db *sql.DB, t *testing.T) {
+	ctx := context.Background()
+	checkListed := func(expected int64) {
+		resp, err := c.ListInventories(ctx, &pb.ListInventoriesRequest{
+			ProductIDs: []int64{111},
+		})
+		if err != nil {
+			t.Fatal("unexpected error when listing", err)
+		}
+
+		if len(resp.Inventories) != 1 || resp.Inventories[0].StockCount != expected {
+			t.Errorf("expecting stock %d, got %v", expected, resp.Inventories)
+		}
+	}
+
+	checkListed(6)
+	_, err := c.MakeOrder(ctx, &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			&pb.Order{
+				ProductID: 111,
+				Quantity:  2,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error when ordering", err)
+	}
+	checkListed(4)
+}
+
+func unpublishedEvents(db *sql.DB, t *testing.T) {
+	// "integration" events are listed before events of orders and products
+	_, err := db.Exec(`DELETE FROM outbox WHERE aggregate_type = 'integration';
+		INSERT INTO outbox (aggregate_type, aggregate_id, seq, event_type, payload) VALUES
+		('integration', 'a', 1, 'tested', '{}'),
+		('integration', 'b', 1, 'tested', '{}'),
+		('integration', 'b', 2, 'tested', '{}');`)
+	if err != nil {
+		t.Fatal("cannot insert events", err)
+	}
+	defer db.Exec("DELETE FROM outbox WHERE aggregate_type = 'integration'")
+
+	r := repo.NewCockroachRepo(db)
+	events, err := r.ListUnpublishedEvents(context.Background(), 2, nil)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if len(events) != 2 || events[0].AggregateID != "a" || events[1].AggregateID != "b" || events[1].Seq != 1 {
+		t.Error("expecting events of a then b, got", events)
+	}
+
+	events, err = r.ListUnpublishedEvents(context.Background(), 2, []repositories.Aggregate{
+		{Type: "integration", ID: "a"},
+		{Type: repositories.AggregateOrder, ID: "a"},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if len(events) != 2 || events[0].AggregateID != "b" || events[0].Seq != 1 || events[1].Seq != 2 {
+		t.Error("expecting only events of b, got", events)
+	}
+}
+
 func checkUpdatedQty(db *sql.DB, t *testing.T, productID, expectedQty int64) {
 	var currentQty int64
 	err := db.QueryRow(
@@ -262,10 +625,25 @@ func setupDB(connStr string) *sql.DB {
 		(31, 10, 0),
 		(32, 5, 0),
 		(41, 10, 0),
-		(42, 5, 0);`)
+		(42, 5, 0),
+		(61, 3, 0),
+		(62, 0, 0),
+		(81, 4, 0),
+		(91, 5, 0),
+		(101, 1, 0),
+		(111, 6, 0);`)
 	if err != nil {
 		log.Fatal("error inserting test data to the database: ", err)
 	}
 
+	_, err = db.Exec(`UPSERT INTO inventories (id, stock_count, version, price) VALUES
+		(51, 10, 0, 100);
+		DELETE FROM promotion_redemptions WHERE code = 'ONCE10';
+		UPSERT INTO promotions (code, kind, value, usage_limit, used_count, per_customer_limit) VALUES
+		('ONCE10', 1, 10, 1, 0, 1);`)
+	if err != nil {
+		log.Fatal("error inserting test promotions to the database: ", err)
+	}
+
 	return db
 }
@@ -0,0 +1,52 @@
+package interceptors
+
+import (
+	"context"
+
+	"tomshop/admission"
+	"tomshop/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var overloadedErr = status.Error(codes.Unavailable, "server is overloaded, retry later")
+
+// Admission rejects unary RPCs with Unavailable when l is at the limit of their priority, RPCs without
+// a priority are not limited. Handlers failed by DeadlineExceeded or Unavailable count as overloaded
+func Admission(l *admission.Limiter, priorities map[string]admission.Priority) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p, ok := priorities[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		done, err := Admit(l, info.FullMethod, p)
+		if err != nil {
+			return nil, err
+		}
+
+		// deferred so a panicking handler still leaves
+		defer func() { done(err) }()
+
+		var resp interface{}
+		resp, err = handler(ctx, req)
+		return resp, err
+	}
+}
+
+// Admit work of method at priority p by l, for handlers admitting parts of a call one by one, e.g. batches of a
+// stream. It fails with Unavailable when l is at the limit, done must be called with the error the work ended with
+func Admit(l *admission.Limiter, method string, p admission.Priority) (done func(error), err error) {
+	release, ok := l.Acquire(p)
+	if !ok {
+		metrics.AdmissionRejected.Add(method, 1)
+		return nil, overloadedErr
+	}
+
+	return func(err error) {
+		c := status.Code(err)
+		release(c == codes.DeadlineExceeded || c == codes.Unavailable)
+	}, nil
+}
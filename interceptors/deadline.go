@@ -0,0 +1,24 @@
+// Package interceptors holds gRPC server interceptors shared by all services
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Deadline of unary RPCs whose client set none or a later one, so a stuck call doesn't hold
+// a DB connection until the client gives up. Streams are long lived and not bounded
+func Deadline(d time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if d <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
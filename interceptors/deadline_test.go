@@ -0,0 +1,43 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestDeadline(t *testing.T) {
+	deadlineOf := func(ctx context.Context, d time.Duration) (time.Time, bool) {
+		var deadline time.Time
+		var ok bool
+		Deadline(d)(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			deadline, ok = ctx.Deadline()
+			return nil, nil
+		})
+		return deadline, ok
+	}
+
+	t.Run("expecting default deadline when client set none", func(tt *testing.T) {
+		deadline, ok := deadlineOf(context.Background(), time.Second)
+		if !ok || time.Until(deadline) > time.Second {
+			tt.Error("expecting deadline within 1s, got", deadline, ok)
+		}
+	})
+
+	t.Run("expecting earlier deadline of client kept", func(tt *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		want, _ := ctx.Deadline()
+		if deadline, _ := deadlineOf(ctx, time.Hour); !deadline.Equal(want) {
+			tt.Errorf("expecting deadline %s, got %s", want, deadline)
+		}
+	})
+
+	t.Run("expecting no deadline when disabled", func(tt *testing.T) {
+		if _, ok := deadlineOf(context.Background(), 0); ok {
+			tt.Error("unexpected deadline")
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package interceptors
+
+import (
+	"context"
+	"runtime/debug"
+
+	"tomshop/ctxlog"
+	"tomshop/metrics"
+
+	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Recovery turns panics of handlers into Internal errors, the panic is logged with its stack and
+// tags of the call and counted by method in metrics.Panics
+func Recovery() grpc.UnaryServerInterceptor {
+	return grpc_recovery.UnaryServerInterceptor(grpc_recovery.WithRecoveryHandlerContext(recovered))
+}
+
+// RecoveryStream is Recovery of streams
+func RecoveryStream() grpc.StreamServerInterceptor {
+	return grpc_recovery.StreamServerInterceptor(grpc_recovery.WithRecoveryHandlerContext(recovered))
+}
+
+var internalErr = status.Error(codes.Internal, "internal error")
+
+// recovered is called by the deferred recover, debug.Stack still has the frames of the panic
+func recovered(ctx context.Context, p interface{}) error {
+	method, _ := grpc.Method(ctx)
+	metrics.Panics.Add(method, 1)
+	ctxlog.Printf(ctx, "panic: %v\n%s", p, debug.Stack())
+
+	return internalErr
+}
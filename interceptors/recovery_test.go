@@ -0,0 +1,38 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func panicking() {
+	var m map[string]int
+	m["boom"]++
+}
+
+func TestRecovery(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	_, err := Recovery()(context.Background(), nil, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) {
+		panicking()
+		return nil, nil
+	})
+
+	if status.Code(err) != codes.Internal {
+		t.Error("expecting gRPC Internal error, got", err)
+	}
+
+	if !strings.Contains(out.String(), "assignment to entry in nil map") || !strings.Contains(out.String(), "interceptors.panicking") {
+		t.Error("expecting panic logged with its stack, got", out.String())
+	}
+}
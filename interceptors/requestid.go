@@ -0,0 +1,91 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"tomshop/ctxlog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RequestIDHeader carries the request ID in request metadata and response headers
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLength of IDs propagated from clients, longer ones are replaced
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID propagates the x-request-id of clients or generates one and echoes it in response headers.
+// The ID, service, method and peer are tagged for ctxlog down the call
+func RequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withRequestID(ctx, info.FullMethod)
+		// fails only when headers were sent, which cannot happen before the handler
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, RequestIDOf(ctx)))
+
+		return handler(ctx, req)
+	}
+}
+
+// RequestIDStream is RequestID of streams
+func RequestIDStream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withRequestID(ss.Context(), info.FullMethod)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, RequestIDOf(ctx)))
+
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RequestIDOf the call of ctx, empty outside of calls
+func RequestIDOf(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func withRequestID(ctx context.Context, fullMethod string) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDHeader); len(ids) > 0 && ids[0] != "" && len(ids[0]) <= maxRequestIDLength {
+			id = ids[0]
+		}
+	}
+
+	if id == "" {
+		id = newRequestID()
+	}
+
+	tags := []string{"request_id", id}
+	// fullMethod is /package.Service/Method
+	if i := strings.LastIndexByte(fullMethod, '/'); i > 0 {
+		tags = append(tags, "grpc.service", fullMethod[1:i], "grpc.method", fullMethod[i+1:])
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		tags = append(tags, "peer.address", p.Addr.String())
+	}
+	ctx = ctxlog.With(ctx, tags...)
+
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
+
+// contextStream replaces the context of a stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
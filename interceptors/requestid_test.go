@@ -0,0 +1,91 @@
+package interceptors
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tomshop/ctxlog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// mockTransportStream records headers set by interceptors
+type mockTransportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *mockTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestRequestID(t *testing.T) {
+	call := func(md metadata.MD) (context.Context, *mockTransportStream) {
+		ts := &mockTransportStream{}
+		ctx := grpc.NewContextWithServerTransportStream(metadata.NewIncomingContext(context.Background(), md), ts)
+		var handled context.Context
+		RequestID()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/tomshop.v1.TomShop/MakeOrder"}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			handled = ctx
+			return nil, nil
+		})
+		return handled, ts
+	}
+
+	t.Run("expecting request ID of client propagated and echoed", func(tt *testing.T) {
+		ctx, ts := call(metadata.Pairs(RequestIDHeader, "abc"))
+		if RequestIDOf(ctx) != "abc" {
+			tt.Error("expecting request ID abc, got", RequestIDOf(ctx))
+		}
+
+		if ids := ts.header.Get(RequestIDHeader); len(ids) != 1 || ids[0] != "abc" {
+			tt.Error("expecting request ID echoed, got", ts.header)
+		}
+	})
+
+	t.Run("expecting request ID generated if client sent none or too long", func(tt *testing.T) {
+		for _, md := range []metadata.MD{nil, metadata.Pairs(RequestIDHeader, strings.Repeat("a", 200))} {
+			ctx, ts := call(md)
+			if id := RequestIDOf(ctx); len(id) != 32 || ts.header.Get(RequestIDHeader)[0] != id {
+				tt.Error("expecting generated request ID echoed, got", id, ts.header)
+			}
+		}
+	})
+
+	t.Run("expecting request ID and method tagged", func(tt *testing.T) {
+		ctx, _ := call(metadata.Pairs(RequestIDHeader, "abc"))
+		if prefix := ctxlog.Prefix(ctx); prefix != "[grpc.method=MakeOrder grpc.service=tomshop.v1.TomShop request_id=abc] " {
+			tt.Error("unexpected tags", prefix)
+		}
+	})
+}
+
+type mockHeaderStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *mockHeaderStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockHeaderStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestRequestIDStream(t *testing.T) {
+	ss := &mockHeaderStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, "abc"))}
+	var id string
+	RequestIDStream()(nil, ss, &grpc.StreamServerInfo{FullMethod: "/tomshop.v1.TomShop/WatchInventory"}, func(_ interface{}, stream grpc.ServerStream) error {
+		id = RequestIDOf(stream.Context())
+		return nil
+	})
+
+	if id != "abc" || ss.header.Get(RequestIDHeader)[0] != "abc" {
+		t.Error("expecting request ID abc propagated and echoed, got", id, ss.header)
+	}
+}
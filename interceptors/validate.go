@@ -0,0 +1,101 @@
+package interceptors
+
+import (
+	"context"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Validate requests of unary RPCs against the rules declared in protos before handlers run,
+// invalid ones fail with InvalidArgument listing the violated fields as BadRequest details.
+// Requests of skipped full methods are validated by their handlers, e.g. batches validating every item
+func Validate(v protovalidate.Validator, skipped ...string) grpc.UnaryServerInterceptor {
+	skip := skipSet(skipped)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		if err := ValidateMessage(v, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// ValidateStream validates every message received by streams like Validate, the stream is broken
+// by the first invalid one. Streams of skipped full methods validate messages themselves
+func ValidateStream(v protovalidate.Validator, skipped ...string) grpc.StreamServerInterceptor {
+	skip := skipSet(skipped)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		return handler(srv, &validatingStream{ServerStream: ss, v: v})
+	}
+}
+
+func skipSet(methods []string) map[string]bool {
+	skip := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		skip[m] = true
+	}
+	return skip
+}
+
+type validatingStream struct {
+	grpc.ServerStream
+	v protovalidate.Validator
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return ValidateMessage(s.v, m)
+}
+
+// ValidateMessage m as Validate does, for handlers of skipped methods
+func ValidateMessage(v protovalidate.Validator, m interface{}) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	err := v.Validate(msg)
+	if err == nil {
+		return nil
+	}
+
+	verr, ok := err.(*protovalidate.ValidationError)
+	if !ok {
+		// rules cannot be compiled or evaluated, that is a bug of the protos rather than the request
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	br := &errdetails.BadRequest{}
+	for _, violation := range verr.Violations {
+		desc := violation.Proto.GetMessage()
+		if desc == "" {
+			desc = violation.Proto.GetRuleId()
+		}
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       protovalidate.FieldPathString(violation.Proto.GetField()),
+			Description: desc,
+		})
+	}
+
+	st, detailsErr := status.New(codes.InvalidArgument, verr.Error()).WithDetails(br)
+	if detailsErr != nil {
+		return status.Error(codes.InvalidArgument, verr.Error())
+	}
+	return st.Err()
+}
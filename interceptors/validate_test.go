@@ -0,0 +1,189 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+
+	pb "tomshop/grpc"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// violatedFields of an InvalidArgument error
+func violatedFields(t *testing.T, err error) []string {
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatal("expecting gRPC InvalidArgument error, got", err)
+	}
+
+	var fields []string
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				fields = append(fields, v.Field)
+			}
+		}
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	v, err := protovalidate.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(req interface{}) (bool, error) {
+		called := false
+		_, err := Validate(v)(context.Background(), req, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+		return called, err
+	}
+
+	t.Run("expecting valid request handled", func(tt *testing.T) {
+		called, err := call(&pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}}})
+		if err != nil || !called {
+			tt.Error("expecting handler called, got", called, err)
+		}
+	})
+
+	t.Run("expecting InvalidArgument with field path of invalid quantity", func(tt *testing.T) {
+		called, err := call(&pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}, {ProductID: 2}}})
+		if called {
+			tt.Error("unexpected handler called")
+		}
+
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "purchases[1].quantity" {
+			tt.Error("expecting purchases[1].quantity violated, got", fields)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if no purchase", func(tt *testing.T) {
+		_, err := call(&pb.OrderRequest{})
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "purchases" {
+			tt.Error("expecting purchases violated, got", fields)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if product purchased twice", func(tt *testing.T) {
+		_, err := call(&pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}, {ProductID: 1, Quantity: 1}}})
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "purchases" {
+			tt.Error("expecting purchases violated, got", fields)
+		}
+	})
+
+	t.Run("expecting every violation reported", func(tt *testing.T) {
+		_, err := call(&pb.OrderRequest{Purchases: []*pb.Order{{Quantity: -1}}})
+		if fields := violatedFields(tt, err); len(fields) != 2 {
+			tt.Error("expecting 2 violations, got", fields)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if reason of stock change is ORDER", func(tt *testing.T) {
+		_, err := call(&pb.ChangeStockRequest{ProductID: 1, Quantity: 1, Reason: pb.StockMovementReason_ORDER})
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "reason" {
+			tt.Error("expecting reason violated, got", fields)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if threshold negative", func(tt *testing.T) {
+		_, err := call(&pb.StockThreshold{ProductID: 1, Threshold: -1})
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "threshold" {
+			tt.Error("expecting threshold violated, got", fields)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if preorder policy has no release", func(tt *testing.T) {
+		_, err := call(&pb.FulfillmentPolicy{ProductID: 1, Kind: pb.FulfillmentPolicyKind_PREORDER, Limit: 5})
+		if status.Code(err) != codes.InvalidArgument {
+			tt.Error("expecting InvalidArgument, got", err)
+		}
+
+		if _, err := call(&pb.FulfillmentPolicy{ProductID: 1, Kind: pb.FulfillmentPolicyKind_BACKORDER, Limit: 5}); err != nil {
+			tt.Error("expecting backorder policy valid without release, got", err)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if listing invalid product", func(tt *testing.T) {
+		_, err := call(&pb.ListInventoriesRequest{ProductIDs: []int64{1, 0}})
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "productIDs[1]" {
+			tt.Error("expecting productIDs[1] violated, got", fields)
+		}
+	})
+
+	t.Run("expecting InvalidArgument if watching no product", func(tt *testing.T) {
+		_, err := call(&pb.WatchInventoryRequest{})
+		if fields := violatedFields(tt, err); len(fields) != 1 || fields[0] != "productIDs" {
+			tt.Error("expecting productIDs violated, got", fields)
+		}
+	})
+
+	t.Run("expecting skipped methods handled without validation", func(tt *testing.T) {
+		called := false
+		info := &grpc.UnaryServerInfo{FullMethod: pb.TomShop_MakeOrder_FullMethodName}
+		_, err := Validate(v, pb.TomShop_MakeOrder_FullMethodName)(context.Background(), &pb.OrderRequest{}, info, func(context.Context, interface{}) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+		if err != nil || !called {
+			tt.Error("expecting handler called, got", called, err)
+		}
+	})
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	received []*pb.BatchOrder
+}
+
+func (s *mockServerStream) RecvMsg(m interface{}) error {
+	m.(*pb.BatchOrder).Order = s.received[0].Order
+	s.received = s.received[1:]
+	return nil
+}
+
+func TestValidateStream(t *testing.T) {
+	v, err := protovalidate.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss := &mockServerStream{received: []*pb.BatchOrder{
+		{Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 1}}}},
+		{Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 0, Quantity: 1}}}},
+	}}
+	var errs []error
+	ValidateStream(v)(nil, ss, &grpc.StreamServerInfo{}, func(_ interface{}, stream grpc.ServerStream) error {
+		for i := 0; i < 2; i++ {
+			errs = append(errs, stream.RecvMsg(&pb.BatchOrder{}))
+		}
+		return nil
+	})
+
+	if errs[0] != nil {
+		t.Error("unexpected error", errs[0])
+	}
+
+	if fields := violatedFields(t, errs[1]); len(fields) != 1 || fields[0] != "order.purchases[0].productID" {
+		t.Error("expecting order.purchases[0].productID violated, got", fields)
+	}
+
+	t.Run("expecting skipped streams not validated", func(tt *testing.T) {
+		ss := &mockServerStream{received: []*pb.BatchOrder{
+			{Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 0, Quantity: 1}}}},
+		}}
+		info := &grpc.StreamServerInfo{FullMethod: pb.TomShop_StreamOrders_FullMethodName}
+		ValidateStream(v, pb.TomShop_StreamOrders_FullMethodName)(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+			if err := stream.RecvMsg(&pb.BatchOrder{}); err != nil {
+				tt.Error("unexpected error", err)
+			}
+			return nil
+		})
+	})
+}
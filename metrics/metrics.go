@@ -0,0 +1,37 @@
+// Package metrics keeps counters published by expvar, served as JSON at /debug/vars
+package metrics
+
+import "expvar"
+
+var (
+	// TxnRetries counts retries of transactions by repository method
+	TxnRetries = expvar.NewMap("txn_retries")
+	// TxnRetriesExhausted counts transactions given up after too many retries by repository method
+	TxnRetriesExhausted = expvar.NewMap("txn_retries_exhausted")
+	// StockCacheHits counts products served by the stock cache
+	StockCacheHits = expvar.NewInt("stock_cache_hits")
+	// StockCacheMisses counts products read through the stock cache, including those waiting for another read
+	StockCacheMisses = expvar.NewInt("stock_cache_misses")
+	// Panics counts panics recovered from gRPC handlers by full method
+	Panics = expvar.NewMap("panics")
+	// AdmissionLimit is the concurrency limit adapted by the admission limiter
+	AdmissionLimit = expvar.NewInt("admission_limit")
+	// AdmissionInFlight counts requests admitted and not finished yet
+	AdmissionInFlight = expvar.NewInt("admission_in_flight")
+	// AdmissionRejected counts requests rejected by the admission limiter by full method
+	AdmissionRejected = expvar.NewMap("admission_rejected")
+	// BreakerState of the circuit breaker around placing orders: closed, open or half-open
+	BreakerState = expvar.NewString("breaker_state")
+	// BreakerRejected counts calls refused by the circuit breaker without trying the database
+	BreakerRejected = expvar.NewInt("breaker_rejected")
+)
+
+func init() {
+	expvar.Publish("stock_cache_hit_ratio", expvar.Func(func() interface{} {
+		hits, misses := StockCacheHits.Value(), StockCacheMisses.Value()
+		if hits+misses == 0 {
+			return 0.0
+		}
+		return float64(hits) / float64(hits+misses)
+	}))
+}
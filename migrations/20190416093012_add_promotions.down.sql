@@ -0,0 +1,3 @@
+DROP TABLE promotion_redemptions;
+DROP TABLE promotions;
+ALTER TABLE inventories DROP COLUMN price;
\ No newline at end of file
@@ -0,0 +1,22 @@
+ALTER TABLE inventories ADD COLUMN price INT NOT NULL DEFAULT 0;
+
+CREATE TABLE promotions (
+  code STRING PRIMARY KEY,
+  kind INT NOT NULL,
+  value INT NOT NULL DEFAULT 0,
+  product_id INT NOT NULL DEFAULT 0,
+  buy_quantity INT NOT NULL DEFAULT 0,
+  free_quantity INT NOT NULL DEFAULT 0,
+  usage_limit INT NOT NULL DEFAULT 0,
+  used_count INT NOT NULL DEFAULT 0,
+  per_customer_limit INT NOT NULL DEFAULT 0,
+  valid_from TIMESTAMPTZ NOT NULL DEFAULT now(),
+  valid_until TIMESTAMPTZ
+);
+
+CREATE TABLE promotion_redemptions (
+  code STRING NOT NULL REFERENCES promotions (code),
+  customer_id INT NOT NULL,
+  used_count INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (code, customer_id)
+);
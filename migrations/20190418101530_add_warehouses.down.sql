@@ -0,0 +1,2 @@
+DROP TABLE warehouse_stocks;
+DROP TABLE warehouses;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE warehouses (
+  id INT PRIMARY KEY,
+  region STRING NOT NULL DEFAULT ''
+);
+
+-- inventories.stock_count stays the total of a product in all warehouses
+CREATE TABLE warehouse_stocks (
+  product_id INT NOT NULL,
+  warehouse_id INT NOT NULL REFERENCES warehouses (id),
+  stock_count INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (product_id, warehouse_id)
+);
@@ -0,0 +1 @@
+DROP TABLE fulfillment_policies;
\ No newline at end of file
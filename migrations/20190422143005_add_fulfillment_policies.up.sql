@@ -0,0 +1,8 @@
+-- policy: 0 stock only, 1 backorder, 2 preorder
+CREATE TABLE fulfillment_policies (
+  product_id INT PRIMARY KEY,
+  policy INT NOT NULL DEFAULT 0,
+  backorder_limit INT NOT NULL DEFAULT 0,
+  backordered_count INT NOT NULL DEFAULT 0,
+  release_at TIMESTAMPTZ
+);
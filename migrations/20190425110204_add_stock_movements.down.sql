@@ -0,0 +1 @@
+DROP TABLE stock_movements;
\ No newline at end of file
@@ -0,0 +1,18 @@
+CREATE TABLE stock_movements (
+  id INT PRIMARY KEY DEFAULT unique_rowid(),
+  product_id INT NOT NULL,
+  delta INT NOT NULL,
+  reason STRING NOT NULL,
+  reference STRING NOT NULL DEFAULT '',
+  actor STRING NOT NULL DEFAULT '',
+  before_count INT NOT NULL,
+  after_count INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  INDEX stock_movements_product_id_idx (product_id, id DESC),
+  INDEX stock_movements_reference_idx (reference)
+);
+
+-- stock before the ledger existed
+INSERT INTO stock_movements (product_id, delta, reason, actor, before_count, after_count)
+SELECT id, COALESCE(stock_count, 0), 'opening_balance', 'migration', 0, COALESCE(stock_count, 0)
+FROM inventories;
@@ -0,0 +1,2 @@
+DROP TABLE outbox;
+DROP TABLE outbox_sequences;
\ No newline at end of file
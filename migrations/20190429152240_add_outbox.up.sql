@@ -0,0 +1,19 @@
+CREATE TABLE outbox_sequences (
+  aggregate_type STRING NOT NULL,
+  aggregate_id STRING NOT NULL,
+  seq INT NOT NULL,
+  PRIMARY KEY (aggregate_type, aggregate_id)
+);
+
+CREATE TABLE outbox (
+  id INT PRIMARY KEY DEFAULT unique_rowid(),
+  aggregate_type STRING NOT NULL,
+  aggregate_id STRING NOT NULL,
+  seq INT NOT NULL,
+  event_type STRING NOT NULL,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  published_at TIMESTAMPTZ,
+  UNIQUE INDEX outbox_aggregate_seq_idx (aggregate_type, aggregate_id, seq),
+  INDEX outbox_unpublished_idx (published_at, aggregate_type, aggregate_id, seq)
+);
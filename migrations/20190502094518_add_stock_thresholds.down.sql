@@ -0,0 +1 @@
+DROP TABLE stock_thresholds;
\ No newline at end of file
@@ -0,0 +1,6 @@
+-- below is set once stock crossed under threshold and reset when it is back, so a crossing is alerted once
+CREATE TABLE stock_thresholds (
+  product_id INT PRIMARY KEY,
+  threshold INT NOT NULL,
+  below BOOL NOT NULL DEFAULT false
+);
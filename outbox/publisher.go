@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"tomshop/repositories"
+)
+
+// Publishers publish to every Publisher, returns the first error so the event is published again
+// to all of them
+type Publishers []Publisher
+
+// Publish to all
+func (ps Publishers) Publish(ctx context.Context, e repositories.OutboxEvent) error {
+	var first error
+	for _, p := range ps {
+		if err := p.Publish(ctx, e); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// WriterPublisher writes events as JSON lines, to stdout or a file
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterPublisher writes to w
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+type jsonEvent struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregateType"`
+	AggregateID   string          `json:"aggregateID"`
+	Seq           int64           `json:"seq"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// Publish implements Publisher
+func (p *WriterPublisher) Publish(_ context.Context, e repositories.OutboxEvent) error {
+	data, err := json.Marshal(jsonEvent{
+		ID:            e.ID,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		Seq:           e.Seq,
+		Type:          e.Type,
+		Payload:       json.RawMessage(e.Payload),
+		CreatedAt:     e.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(data, '\n'))
+
+	return err
+}
+
+// MemoryPublisher keeps events in process, for tests
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []repositories.OutboxEvent
+	// Fail decides if an event fails to publish, nil for never
+	Fail func(repositories.OutboxEvent) error
+}
+
+// Publish implements Publisher
+func (p *MemoryPublisher) Publish(_ context.Context, e repositories.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Fail != nil {
+		if err := p.Fail(e); err != nil {
+			return err
+		}
+	}
+	p.events = append(p.events, e)
+
+	return nil
+}
+
+// Events published so far
+func (p *MemoryPublisher) Events() []repositories.OutboxEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]repositories.OutboxEvent(nil), p.events...)
+}
@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"tomshop/repositories"
+)
+
+// Publisher delivers an event to downstream systems
+type Publisher interface {
+	Publish(context.Context, repositories.OutboxEvent) error
+}
+
+// Relay publishes outbox events at least once, events of an aggregate are published in Seq order
+type Relay struct {
+	Repo interface {
+		ListUnpublishedEvents(context.Context, int, []repositories.Aggregate) ([]repositories.OutboxEvent, error)
+		MarkEventsPublished(context.Context, []int64) error
+		DeletePublishedEvents(context.Context, time.Time) (int64, error)
+	}
+	Publisher Publisher
+	// BatchSize default 100
+	BatchSize int
+	// Interval between polls when there is nothing to publish, default 1 second
+	Interval time.Duration
+	// Retention of published events, default 7 days
+	Retention time.Duration
+}
+
+// Run relays and cleans up until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	lastCleanup := time.Time{}
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			log.Println("outbox relay error:", err)
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			if _, err := r.Cleanup(ctx); err != nil {
+				log.Println("outbox cleanup error:", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		// a full batch likely means more to publish
+		wait := interval
+		if err == nil && n >= r.batchSize() {
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RelayOnce publishes a batch of events and returns number of published events. Once an event
+// fails, later events of the same aggregate are skipped to keep their order. A full batch with
+// failed aggregates is followed by one excluding them, so an aggregate failing with a batch of
+// pending events doesn't starve the others, until batch size aggregates failed in the poll
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var failed []repositories.Aggregate
+	var publishErr error
+	n := 0
+	for {
+		events, err := r.Repo.ListUnpublishedEvents(ctx, r.batchSize(), failed)
+		if err != nil {
+			return n, err
+		}
+
+		failedBefore := len(failed)
+		published := make([]int64, 0, len(events))
+		for _, e := range events {
+			a := repositories.Aggregate{Type: e.AggregateType, ID: e.AggregateID}
+			// events of an aggregate are listed together, so it can only be the last one failed
+			if len(failed) > failedBefore && failed[len(failed)-1] == a {
+				continue
+			}
+
+			if err := r.Publisher.Publish(ctx, e); err != nil {
+				failed = append(failed, a)
+				publishErr = err
+				continue
+			}
+			published = append(published, e.ID)
+		}
+
+		// events published but not marked will be published again, it is what at least once means
+		if err := r.Repo.MarkEventsPublished(ctx, published); err != nil {
+			return n, err
+		}
+		n += len(published)
+
+		if len(events) < r.batchSize() || len(failed) == failedBefore || len(failed) >= r.batchSize() {
+			return n, publishErr
+		}
+	}
+}
+
+// Cleanup deletes events published before retention
+func (r *Relay) Cleanup(ctx context.Context) (int64, error) {
+	retention := r.Retention
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	return r.Repo.DeletePublishedEvents(ctx, time.Now().Add(-retention))
+}
+
+func (r *Relay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return 100
+	}
+
+	return r.BatchSize
+}
@@ -0,0 +1,214 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+var testEvents = []repositories.OutboxEvent{
+	{ID: 10, AggregateType: "product", AggregateID: "1", Seq: 1, Type: "stock.changed", Payload: []byte(`{}`)},
+	{ID: 12, AggregateType: "product", AggregateID: "1", Seq: 2, Type: "stock.changed", Payload: []byte(`{}`)},
+	{ID: 11, AggregateType: "product", AggregateID: "2", Seq: 1, Type: "stock.changed", Payload: []byte(`{}`)},
+}
+
+func TestRelay_RelayOnce(t *testing.T) {
+	t.Run("expecting all events published and marked", relayAll)
+	t.Run("expecting later events of failed aggregate skipped", relaySkipFailedAggregate)
+	t.Run("expecting aggregates after a failed full batch published", relayPastFailedAggregate)
+	t.Run("expecting nothing marked if cannot list events", relayListError)
+}
+
+func relayAll(t *testing.T) {
+	repo := &mockRepo{events: testEvents}
+	pub := &MemoryPublisher{}
+	r := &Relay{Repo: repo, Publisher: pub, BatchSize: 10}
+
+	n, err := r.RelayOnce(context.Background())
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if n != 3 {
+		t.Errorf("expecting 3 events published, got %d", n)
+	}
+
+	if !reflect.DeepEqual(pub.Events(), testEvents) {
+		t.Error("expecting events published in listed order, got", pub.Events())
+	}
+
+	if !reflect.DeepEqual(repo.marked, []int64{10, 12, 11}) {
+		t.Error("expecting all events marked, got", repo.marked)
+	}
+
+	if repo.limit != 10 {
+		t.Errorf("expecting batch size 10, got %d", repo.limit)
+	}
+}
+
+func relaySkipFailedAggregate(t *testing.T) {
+	repo := &mockRepo{events: testEvents}
+	pub := &MemoryPublisher{
+		Fail: func(e repositories.OutboxEvent) error {
+			if e.ID == 10 {
+				return fmt.Errorf("dummyPublishError")
+			}
+			return nil
+		},
+	}
+	r := &Relay{Repo: repo, Publisher: pub}
+
+	n, err := r.RelayOnce(context.Background())
+	if err == nil || err.Error() != "dummyPublishError" {
+		t.Error("expecting dummyPublishError, got", err)
+	}
+
+	if n != 1 {
+		t.Errorf("expecting 1 event published, got %d", n)
+	}
+
+	if !reflect.DeepEqual(repo.marked, []int64{11}) {
+		t.Error("expecting only event of product 2 marked, got", repo.marked)
+	}
+}
+
+func relayPastFailedAggregate(t *testing.T) {
+	repo := &mockRepo{events: testEvents}
+	pub := &MemoryPublisher{
+		Fail: func(e repositories.OutboxEvent) error {
+			if e.AggregateID == "1" {
+				return fmt.Errorf("dummyPublishError")
+			}
+			return nil
+		},
+	}
+	r := &Relay{Repo: repo, Publisher: pub, BatchSize: 2}
+
+	n, err := r.RelayOnce(context.Background())
+	if err == nil || err.Error() != "dummyPublishError" {
+		t.Error("expecting dummyPublishError, got", err)
+	}
+
+	if n != 1 {
+		t.Errorf("expecting 1 event published, got %d", n)
+	}
+
+	if !reflect.DeepEqual(repo.marked, []int64{11}) {
+		t.Error("expecting event of product 2 marked, got", repo.marked)
+	}
+
+	expected := [][]repositories.Aggregate{nil, {{Type: "product", ID: "1"}}}
+	if !reflect.DeepEqual(repo.excluded, expected) {
+		t.Error("expecting product 1 excluded from the second batch, got", repo.excluded)
+	}
+}
+
+func relayListError(t *testing.T) {
+	repo := &mockRepo{listErr: fmt.Errorf("dummyListError")}
+	r := &Relay{Repo: repo, Publisher: &MemoryPublisher{}}
+
+	if _, err := r.RelayOnce(context.Background()); err == nil {
+		t.Error("expecting error")
+	}
+
+	if repo.marked != nil {
+		t.Error("expecting nothing marked, got", repo.marked)
+	}
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	repo := &mockRepo{}
+	r := &Relay{Repo: repo, Retention: time.Hour}
+
+	if _, err := r.Cleanup(context.Background()); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if d := time.Since(repo.deletedBefore); d < time.Hour || d > time.Hour+time.Minute {
+		t.Error("expecting events published more than an hour ago deleted, got", repo.deletedBefore)
+	}
+}
+
+func TestWriterPublisher(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := NewWriterPublisher(buf)
+	err := p.Publish(context.Background(), repositories.OutboxEvent{
+		ID:            1,
+		AggregateType: "order",
+		AggregateID:   "abc",
+		Seq:           1,
+		Type:          "order.placed",
+		Payload:       []byte(`{"orderID":"abc"}`),
+		CreatedAt:     time.Date(2019, 4, 29, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := `{"id":1,"aggregateType":"order","aggregateID":"abc","seq":1,"type":"order.placed",` +
+		`"payload":{"orderID":"abc"},"createdAt":"2019-04-29T00:00:00Z"}` + "\n"
+	if buf.String() != expected {
+		t.Error("unexpected output", buf.String())
+	}
+}
+
+func TestPublishers(t *testing.T) {
+	dummyErr := fmt.Errorf("dummy publish error")
+	failing := &MemoryPublisher{Fail: func(repositories.OutboxEvent) error { return dummyErr }}
+	other := &MemoryPublisher{}
+
+	err := Publishers{failing, other}.Publish(context.Background(), testEvents[0])
+	if err != dummyErr {
+		t.Error("expecting dummyErr, got", err)
+	}
+
+	if !reflect.DeepEqual(other.Events(), testEvents[:1]) {
+		t.Error("expecting event published to the others, got", other.Events())
+	}
+}
+
+type mockRepo struct {
+	events        []repositories.OutboxEvent
+	listErr       error
+	limit         int
+	excluded      [][]repositories.Aggregate
+	marked        []int64
+	deletedBefore time.Time
+}
+
+func (r *mockRepo) ListUnpublishedEvents(
+	_ context.Context,
+	limit int,
+	excluded []repositories.Aggregate,
+) ([]repositories.OutboxEvent, error) {
+	r.limit = limit
+	r.excluded = append(r.excluded, append([]repositories.Aggregate(nil), excluded...))
+
+	events := []repositories.OutboxEvent{}
+	for _, e := range r.events {
+		skipped := false
+		for _, a := range excluded {
+			skipped = skipped || a == repositories.Aggregate{Type: e.AggregateType, ID: e.AggregateID}
+		}
+		if !skipped && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+
+	return events, r.listErr
+}
+
+func (r *mockRepo) MarkEventsPublished(_ context.Context, ids []int64) error {
+	r.marked = append(r.marked, ids...)
+	return nil
+}
+
+func (r *mockRepo) DeletePublishedEvents(_ context.Context, before time.Time) (int64, error) {
+	r.deletedBefore = before
+	return 0, nil
+}
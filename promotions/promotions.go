@@ -0,0 +1,132 @@
+package promotions
+
+import (
+	"errors"
+	"time"
+
+	"tomshop/repositories"
+)
+
+var (
+	// ErrNotActive when coupon used outside its validity window
+	ErrNotActive = errors.New("coupon is not valid at this time")
+	// ErrUsageExhausted when coupon reached its usage limit
+	ErrUsageExhausted = errors.New("coupon usage limit reached")
+	// ErrCustomerRequired when coupon has per customer limit but order is anonymous
+	ErrCustomerRequired = errors.New("coupon requires a customer")
+	// ErrNotApplicable when the order doesn't contain product the coupon is for
+	ErrNotApplicable = errors.New("coupon is not applicable to order")
+)
+
+// Line is a priced order line
+type Line struct {
+	ProductID int64
+	Quantity  int64
+	UnitPrice int64
+}
+
+// Result of applying promotions, Discount never exceed Subtotal
+type Result struct {
+	Subtotal int64
+	Discount int64
+	Total    int64
+}
+
+// Apply calculates discount of all promotions on lines. It only does a best effort check for
+// usage limits, the real one happens when redemptions are consumed in DB
+func Apply(lines []Line, promos []repositories.Promotion, customerID int64, now time.Time) (Result, error) {
+	result := Result{}
+	for _, l := range lines {
+		result.Subtotal += l.Quantity * l.UnitPrice
+	}
+
+	for _, p := range promos {
+		if err := check(p, customerID, now); err != nil {
+			return Result{}, err
+		}
+
+		discount, err := discountOf(p, lines, result.Subtotal)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Discount += discount
+	}
+
+	if result.Discount > result.Subtotal {
+		result.Discount = result.Subtotal
+	}
+	result.Total = result.Subtotal - result.Discount
+
+	return result, nil
+}
+
+// Redemptions need to be consumed for promos
+func Redemptions(promos []repositories.Promotion, customerID int64) []repositories.Redemption {
+	redemptions := make([]repositories.Redemption, len(promos))
+	for i, p := range promos {
+		redemptions[i] = repositories.Redemption{
+			Code:             p.Code,
+			CustomerID:       customerID,
+			PerCustomerLimit: p.PerCustomerLimit,
+		}
+	}
+
+	return redemptions
+}
+
+func check(p repositories.Promotion, customerID int64, now time.Time) error {
+	if now.Before(p.ValidFrom) || (!p.ValidUntil.IsZero() && !now.Before(p.ValidUntil)) {
+		return ErrNotActive
+	}
+
+	if p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit {
+		return ErrUsageExhausted
+	}
+
+	if p.PerCustomerLimit > 0 && customerID == 0 {
+		return ErrCustomerRequired
+	}
+
+	return nil
+}
+
+// discountOf promotion p, lines of the same product are summed up as a single line
+func discountOf(p repositories.Promotion, lines []Line, subtotal int64) (int64, error) {
+	base := subtotal
+	var line Line
+	if p.ProductID != 0 {
+		base = 0
+		for _, l := range lines {
+			if l.ProductID == p.ProductID {
+				line.ProductID, line.UnitPrice = l.ProductID, l.UnitPrice
+				line.Quantity += l.Quantity
+				base += l.Quantity * l.UnitPrice
+			}
+		}
+
+		if line.ProductID == 0 {
+			return 0, ErrNotApplicable
+		}
+	}
+
+	switch p.Kind {
+	case repositories.PercentageDiscount:
+		return base * p.Value / 100, nil
+	case repositories.FixedDiscount:
+		if p.Value > base {
+			return base, nil
+		}
+		return p.Value, nil
+	case repositories.BuyXGetY:
+		if p.ProductID == 0 || p.BuyQuantity <= 0 || p.FreeQuantity <= 0 {
+			return 0, ErrNotApplicable
+		}
+		free := line.Quantity / (p.BuyQuantity + p.FreeQuantity) * p.FreeQuantity
+		if free == 0 {
+			return 0, ErrNotApplicable
+		}
+		return free * line.UnitPrice, nil
+	}
+
+	return 0, ErrNotApplicable
+}
@@ -0,0 +1,181 @@
+package promotions
+
+import (
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+var testLines = []Line{
+	{
+		ProductID: 1,
+		Quantity:  5,
+		UnitPrice: 100,
+	},
+	{
+		ProductID: 2,
+		Quantity:  1,
+		UnitPrice: 50,
+	},
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2019, 4, 16, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		promos   []repositories.Promotion
+		customer int64
+		expected Result
+		err      error
+	}{
+		{
+			name:     "no promotion",
+			expected: Result{Subtotal: 550, Total: 550},
+		},
+		{
+			name: "percentage on whole order",
+			promos: []repositories.Promotion{
+				{Code: "P10", Kind: repositories.PercentageDiscount, Value: 10},
+			},
+			expected: Result{Subtotal: 550, Discount: 55, Total: 495},
+		},
+		{
+			name: "percentage on a product",
+			promos: []repositories.Promotion{
+				{Code: "P10", Kind: repositories.PercentageDiscount, Value: 10, ProductID: 2},
+			},
+			expected: Result{Subtotal: 550, Discount: 5, Total: 545},
+		},
+		{
+			name: "fixed discount capped by product amount",
+			promos: []repositories.Promotion{
+				{Code: "F80", Kind: repositories.FixedDiscount, Value: 80, ProductID: 2},
+			},
+			expected: Result{Subtotal: 550, Discount: 50, Total: 500},
+		},
+		{
+			name: "buy 2 get 1",
+			promos: []repositories.Promotion{
+				{Code: "B2G1", Kind: repositories.BuyXGetY, ProductID: 1, BuyQuantity: 2, FreeQuantity: 1},
+			},
+			expected: Result{Subtotal: 550, Discount: 100, Total: 450},
+		},
+		{
+			name: "stacked discounts never exceed subtotal",
+			promos: []repositories.Promotion{
+				{Code: "F500", Kind: repositories.FixedDiscount, Value: 500},
+				{Code: "P50", Kind: repositories.PercentageDiscount, Value: 50},
+			},
+			expected: Result{Subtotal: 550, Discount: 550, Total: 0},
+		},
+		{
+			name: "expired",
+			promos: []repositories.Promotion{
+				{Code: "OLD", Kind: repositories.FixedDiscount, Value: 1, ValidUntil: now},
+			},
+			err: ErrNotActive,
+		},
+		{
+			name: "not started",
+			promos: []repositories.Promotion{
+				{Code: "NEW", Kind: repositories.FixedDiscount, Value: 1, ValidFrom: now.Add(time.Hour)},
+			},
+			err: ErrNotActive,
+		},
+		{
+			name: "usage exhausted",
+			promos: []repositories.Promotion{
+				{Code: "USED", Kind: repositories.FixedDiscount, Value: 1, UsageLimit: 3, UsedCount: 3},
+			},
+			err: ErrUsageExhausted,
+		},
+		{
+			name: "per customer limit without customer",
+			promos: []repositories.Promotion{
+				{Code: "ONCE", Kind: repositories.FixedDiscount, Value: 1, PerCustomerLimit: 1},
+			},
+			err: ErrCustomerRequired,
+		},
+		{
+			name: "per customer limit with customer",
+			promos: []repositories.Promotion{
+				{Code: "ONCE", Kind: repositories.FixedDiscount, Value: 1, PerCustomerLimit: 1},
+			},
+			customer: 1,
+			expected: Result{Subtotal: 550, Discount: 1, Total: 549},
+		},
+		{
+			name: "product not in order",
+			promos: []repositories.Promotion{
+				{Code: "P3", Kind: repositories.PercentageDiscount, Value: 10, ProductID: 3},
+			},
+			err: ErrNotApplicable,
+		},
+		{
+			name: "buy x get y not reaching x plus y",
+			promos: []repositories.Promotion{
+				{Code: "B5G1", Kind: repositories.BuyXGetY, ProductID: 1, BuyQuantity: 5, FreeQuantity: 1},
+			},
+			err: ErrNotApplicable,
+		},
+	}
+
+	for _, c := range cases {
+		result, err := Apply(testLines, c.promos, c.customer, now)
+		if err != c.err {
+			t.Errorf("%s: expecting error %v, got %v", c.name, c.err, err)
+		}
+
+		if result != c.expected {
+			t.Errorf("%s: expecting %+v, got %+v", c.name, c.expected, result)
+		}
+	}
+}
+
+func TestApply_DuplicateProductLines(t *testing.T) {
+	now := time.Date(2019, 4, 16, 0, 0, 0, 0, time.UTC)
+	lines := []Line{
+		{ProductID: 1, Quantity: 2, UnitPrice: 100},
+		{ProductID: 2, Quantity: 1, UnitPrice: 50},
+		{ProductID: 1, Quantity: 1, UnitPrice: 100},
+	}
+	cases := []struct {
+		name     string
+		promos   []repositories.Promotion
+		expected Result
+	}{
+		{
+			name: "buy 2 get 1 over both lines",
+			promos: []repositories.Promotion{
+				{Code: "B2G1", Kind: repositories.BuyXGetY, ProductID: 1, BuyQuantity: 2, FreeQuantity: 1},
+			},
+			expected: Result{Subtotal: 350, Discount: 100, Total: 250},
+		},
+		{
+			name: "percentage on both lines",
+			promos: []repositories.Promotion{
+				{Code: "P10", Kind: repositories.PercentageDiscount, Value: 10, ProductID: 1},
+			},
+			expected: Result{Subtotal: 350, Discount: 30, Total: 320},
+		},
+		{
+			name: "fixed discount capped by amount of both lines",
+			promos: []repositories.Promotion{
+				{Code: "F250", Kind: repositories.FixedDiscount, Value: 250, ProductID: 1},
+			},
+			expected: Result{Subtotal: 350, Discount: 250, Total: 100},
+		},
+	}
+
+	for _, c := range cases {
+		result, err := Apply(lines, c.promos, 0, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+
+		if result != c.expected {
+			t.Errorf("%s: expecting %+v, got %+v", c.name, c.expected, result)
+		}
+	}
+}
@@ -1,7 +1,27 @@
 package repositories
 
+import "time"
+
 // InventoryQuantityUpdateError tell which item cannot update and reason
 type InventoryQuantityUpdateError interface {
 	error
 	ProductID() int64
 }
+
+// PromotionRedemptionError tell which coupon cannot be redeemed any more
+type PromotionRedemptionError interface {
+	error
+	Code() string
+}
+
+// RetriesExhaustedError tell a transaction was given up after retrying too many times
+type RetriesExhaustedError interface {
+	error
+	Retries() int
+}
+
+// UnavailableError tell the database is considered down, calls are refused without trying it
+type UnavailableError interface {
+	error
+	RetryAfter() time.Duration
+}
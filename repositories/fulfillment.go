@@ -0,0 +1,25 @@
+package repositories
+
+import "time"
+
+// FulfillmentPolicyKind decide what happen when stock is not enough
+type FulfillmentPolicyKind int
+
+const (
+	// StockOnly sells only what is in stock, default for products without policy
+	StockOnly FulfillmentPolicyKind = iota
+	// Backorder sells more than stock, up to Limit items waiting for restock
+	Backorder
+	// Preorder sells up to Limit items before ReleaseAt, nothing is taken from stock
+	Preorder
+)
+
+// FulfillmentPolicy of a product stored in DB
+type FulfillmentPolicy struct {
+	ProductID int64
+	Kind      FulfillmentPolicyKind
+	// Limit of items can be backordered or preordered
+	Limit            int64
+	BackorderedCount int64
+	ReleaseAt        time.Time
+}
@@ -5,10 +5,54 @@ type Inventory struct {
 	ProductID  int64
 	StockCount int64
 	Version    int64
+	// Price per item in the smallest currency unit
+	Price int64
 }
 
 // Order not stored in DB.. for now
 type Order struct {
-	ProductID int64
-	Quantity  int64
+	ProductID int64 `json:"productID"`
+	// Quantity taken from stock
+	Quantity int64 `json:"quantity"`
+	// Backordered quantity waiting for restock or release, counted against FulfillmentPolicy.Limit
+	Backordered int64 `json:"backordered,omitempty"`
+	// MinQuantity is the least Quantity accepted when order can be partially fulfilled
+	MinQuantity int64 `json:"-"`
+	// Allocations split Quantity into warehouses, empty when warehouses are not used
+	Allocations []Allocation `json:"allocations,omitempty"`
+}
+
+// AdjustOptions carry everything need to be written in the same transaction with stock changes
+type AdjustOptions struct {
+	Redemptions []Redemption
+	// Reference and Actor are recorded in stock movements
+	Reference string
+	Actor     string
+	// Committed is called with stock of the adjusted products once the adjustment is committed
+	Committed func([]Inventory)
+}
+
+// AdjustOption modify AdjustOptions
+type AdjustOption func(*AdjustOptions)
+
+// WithRedemptions consumes coupons together with stock
+func WithRedemptions(redemptions ...Redemption) AdjustOption {
+	return func(o *AdjustOptions) {
+		o.Redemptions = append(o.Redemptions, redemptions...)
+	}
+}
+
+// OnCommitted calls fn with stock the adjusted products are left with once committed, e.g. to tell watchers
+func OnCommitted(fn func([]Inventory)) AdjustOption {
+	return func(o *AdjustOptions) {
+		o.Committed = fn
+	}
+}
+
+// WithReference records reference and actor in stock movements
+func WithReference(reference, actor string) AdjustOption {
+	return func(o *AdjustOptions) {
+		o.Reference = reference
+		o.Actor = actor
+	}
 }
@@ -0,0 +1,58 @@
+package repositories
+
+import "time"
+
+// MovementReason of a stock change
+type MovementReason string
+
+const (
+	// ReasonOrder takes items from stock for an order
+	ReasonOrder MovementReason = "order"
+	// ReasonRestock adds received items to stock
+	ReasonRestock MovementReason = "restock"
+	// ReasonCancellation returns items of a cancelled order to stock
+	ReasonCancellation MovementReason = "cancellation"
+	// ReasonCorrection sets stock to the counted value
+	ReasonCorrection MovementReason = "correction"
+	// ReasonOpeningBalance is stock before the ledger existed
+	ReasonOpeningBalance MovementReason = "opening_balance"
+	// ReasonBackordered takes items of a restock or cancellation for backordered orders
+	ReasonBackordered MovementReason = "backordered"
+)
+
+// StockMovement is an append-only ledger entry, sum of Delta of a product equals its stock
+type StockMovement struct {
+	ID        int64
+	ProductID int64
+	Delta     int64
+	Reason    MovementReason
+	// Reference is the order ID for orders and cancellations, free text otherwise
+	Reference string
+	Actor     string
+	Before    int64
+	After     int64
+	CreatedAt time.Time
+}
+
+// StockChange is a manual change of stock
+type StockChange struct {
+	ProductID int64
+	// Quantity is added to stock for ReasonRestock and ReasonCancellation,
+	// it is the new stock for ReasonCorrection
+	Quantity  int64
+	Reason    MovementReason
+	Reference string
+	Actor     string
+	// WarehouseID changes stock of the warehouse too and Quantity of ReasonCorrection is its new stock,
+	// 0 when warehouses are not used
+	WarehouseID int64
+	// Backordered items of the order cancelled by ReasonCancellation, they are released instead of returned to stock
+	Backordered int64
+}
+
+// StockDrift when stock differs from what the ledger says
+type StockDrift struct {
+	ProductID   int64
+	StockCount  int64
+	LedgerCount int64
+}
@@ -0,0 +1,30 @@
+package repositories
+
+// OrderQuery tells PlaceOrder what to read before planning an order
+type OrderQuery struct {
+	ProductIDs  []int64
+	CouponCodes []string
+	// WarehouseStocks are read only when needed for allocation
+	WarehouseStocks bool
+}
+
+// OrderSnapshot is what is read by PlaceOrder in the order transaction
+type OrderSnapshot struct {
+	// Inventories omit products not in DB
+	Inventories []Inventory
+	// Policies omit products without policy
+	Policies []FulfillmentPolicy
+	// Promotions omit codes not in DB
+	Promotions      []Promotion
+	WarehouseStocks []WarehouseStock
+}
+
+// OrderPlan decides from the snapshot which orders to take and what to write with them.
+// It can be called more than once when the transaction is retried, its error is returned by PlaceOrder as is
+type OrderPlan func(OrderSnapshot) ([]Order, []AdjustOption, error)
+
+// OrderPlacement is an order waiting to be placed together with others
+type OrderPlacement struct {
+	Query OrderQuery
+	Plan  OrderPlan
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import "time"
+
+const (
+	// AggregateOrder events have order ID as aggregate ID
+	AggregateOrder = "order"
+	// AggregateProduct events have product ID as aggregate ID
+	AggregateProduct = "product"
+
+	// EventOrderPlaced with OrderPlacedPayload
+	EventOrderPlaced = "order.placed"
+	// EventStockChanged with StockChangedPayload
+	EventStockChanged = "stock.changed"
+	// EventStockLow with LowStock as payload, a product has just crossed below its threshold
+	EventStockLow = "stock.low"
+)
+
+// Aggregate of outbox events, e.g. AggregateProduct and a product ID
+type Aggregate struct {
+	Type string
+	ID   string
+}
+
+// OutboxEvent written in the same transaction with the change it tells about,
+// Seq increases by one for every event of an aggregate
+type OutboxEvent struct {
+	ID            int64
+	AggregateType string
+	AggregateID   string
+	Seq           int64
+	Type          string
+	// Payload in JSON
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// OrderPlacedPayload of EventOrderPlaced
+type OrderPlacedPayload struct {
+	OrderID     string   `json:"orderID"`
+	Actor       string   `json:"actor"`
+	Lines       []Order  `json:"lines"`
+	CouponCodes []string `json:"couponCodes,omitempty"`
+}
+
+// StockChangedPayload of EventStockChanged
+type StockChangedPayload struct {
+	ProductID int64          `json:"productID"`
+	Delta     int64          `json:"delta"`
+	Reason    MovementReason `json:"reason"`
+	Reference string         `json:"reference"`
+	Actor     string         `json:"actor"`
+}
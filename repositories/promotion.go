@@ -0,0 +1,43 @@
+package repositories
+
+import "time"
+
+// PromotionKind decide how discount calculated
+type PromotionKind int
+
+const (
+	// PercentageDiscount takes Value percent off
+	PercentageDiscount PromotionKind = iota + 1
+	// FixedDiscount takes Value off, never more than the discounted amount
+	FixedDiscount
+	// BuyXGetY gives FreeQuantity items for every BuyQuantity items of ProductID
+	BuyXGetY
+)
+
+// Promotion stored in DB, identified by coupon code
+type Promotion struct {
+	Code string
+	Kind PromotionKind
+	// Value is percent for PercentageDiscount and amount for FixedDiscount
+	Value int64
+	// ProductID limits discount to one product, 0 for whole order.
+	// Required by BuyXGetY
+	ProductID    int64
+	BuyQuantity  int64
+	FreeQuantity int64
+	// UsageLimit of all customers, 0 for unlimited
+	UsageLimit int64
+	UsedCount  int64
+	// PerCustomerLimit 0 for unlimited
+	PerCustomerLimit int64
+	ValidFrom        time.Time
+	// ValidUntil zero value for no expiry
+	ValidUntil time.Time
+}
+
+// Redemption of a coupon by a customer
+type Redemption struct {
+	Code             string
+	CustomerID       int64
+	PerCustomerLimit int64
+}
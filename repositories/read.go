@@ -0,0 +1,17 @@
+package repositories
+
+// ReadOptions of a read not in a transaction
+type ReadOptions struct {
+	// AllowStale lets the read be routed by the read policy of the repository
+	AllowStale bool
+}
+
+// ReadOption modify ReadOptions
+type ReadOption func(*ReadOptions)
+
+// AllowStale tells the read may be served stale or from a replica, it is strong otherwise
+func AllowStale() ReadOption {
+	return func(o *ReadOptions) {
+		o.AllowStale = true
+	}
+}
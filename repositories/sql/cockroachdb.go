@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 
 	"tomshop/repositories"
 
@@ -13,60 +14,517 @@ import (
 
 // CockroachRepo built for CockroachDB in mind but can worl pretty well with any SQL DBMS
 type CockroachRepo struct {
-	txnFactory func(context.Context, *sql.TxOptions) (crdb.Tx, error)
+	// Retry of transactions failed by contention
+	Retry RetryPolicy
+	// Reads allowing staleness are routed by Reads, strong by default
+	Reads ReadRouting
+
+	txnFactory func(context.Context, *sql.TxOptions) (Tx, error)
 	querier    Querier
 }
 
 // NewCockroachRepo with sql.DB, ctx must be request scope
 func NewCockroachRepo(db *sql.DB) *CockroachRepo {
 	return &CockroachRepo{
-		txnFactory: func(ctx context.Context, opts *sql.TxOptions) (crdb.Tx, error) {
+		txnFactory: func(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
 			return db.BeginTx(ctx, opts)
 		},
 		querier: db,
 	}
 }
 
-// AdjustInventories uses Quantity as delta, not absolute value
-func (r *CockroachRepo) AdjustInventories(ctx context.Context, orders []repositories.Order) error {
+// AdjustInventories uses Quantity as delta, not absolute value.
+// Coupon redemptions given by opts are consumed in the same transaction
+func (r *CockroachRepo) AdjustInventories(ctx context.Context, orders []repositories.Order, opts ...repositories.AdjustOption) error {
 	if len(orders) == 0 {
 		return nil
 	}
 
+	options := repositories.AdjustOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	tx, err := r.txnFactory(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return err
 	}
 
-	return crdb.ExecuteInTx(ctx, tx, func() error {
-		updateStmt := "UPDATE inventories SET stock_count = stock_count - $1 WHERE id = $2 AND stock_count >= $3"
-		for _, o := range orders {
-			result, err := tx.ExecContext(ctx, updateStmt, o.Quantity, o.ProductID, o.Quantity)
-			if err != nil {
-				return err
+	var stocks map[int64]int64
+	err = r.executeInTx(ctx, "AdjustInventories", tx, func() error {
+		stocks, err = adjust(ctx, tx, orders, options)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	committed(options, stocks)
+	return nil
+}
+
+// PlaceOrder reads what query asks for, plans the order and takes its stock in one transaction,
+// so the plan never sees stale stock
+func (r *CockroachRepo) PlaceOrder(ctx context.Context, query repositories.OrderQuery, plan repositories.OrderPlan) error {
+	tx, err := r.txnFactory(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+
+	var options repositories.AdjustOptions
+	var stocks map[int64]int64
+	err = r.executeInTx(ctx, "PlaceOrder", tx, func() error {
+		options, stocks = repositories.AdjustOptions{}, nil
+		snapshot, err := readOrderSnapshot(ctx, tx, query)
+		if err != nil {
+			return err
+		}
+
+		orders, opts, err := plan(snapshot)
+		if err != nil {
+			return err
+		}
+
+		if len(orders) == 0 {
+			return nil
+		}
+
+		for _, opt := range opts {
+			opt(&options)
+		}
+
+		stocks, err = adjust(ctx, tx, orders, options)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	committed(options, stocks)
+	return nil
+}
+
+// committed tells options.Committed about stocks left by an adjustment once it is committed
+func committed(options repositories.AdjustOptions, stocks map[int64]int64) {
+	if options.Committed == nil || len(stocks) == 0 {
+		return
+	}
+
+	inventories := make([]repositories.Inventory, 0, len(stocks))
+	for id, count := range stocks {
+		inventories = append(inventories, repositories.Inventory{ProductID: id, StockCount: count})
+	}
+	sort.Slice(inventories, func(i, j int) bool { return inventories[i].ProductID < inventories[j].ProductID })
+
+	options.Committed(inventories)
+}
+
+func readOrderSnapshot(ctx context.Context, tx Tx, query repositories.OrderQuery) (repositories.OrderSnapshot, error) {
+	snapshot := repositories.OrderSnapshot{}
+	var err error
+	if snapshot.Inventories, err = listInventories(ctx, tx, "", query.ProductIDs); err != nil {
+		return snapshot, err
+	}
+
+	if snapshot.Policies, err = listFulfillmentPolicies(ctx, tx, query.ProductIDs); err != nil {
+		return snapshot, err
+	}
+
+	if len(query.CouponCodes) > 0 {
+		if snapshot.Promotions, err = listPromotions(ctx, tx, query.CouponCodes); err != nil {
+			return snapshot, err
+		}
+	}
+
+	if query.WarehouseStocks {
+		if snapshot.WarehouseStocks, err = listWarehouseStocks(ctx, tx, "", query.ProductIDs); err != nil {
+			return snapshot, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// adjust takes stock of orders and writes everything come with it, returns stock of products after it
+func adjust(
+	ctx context.Context,
+	tx Tx,
+	orders []repositories.Order,
+	options repositories.AdjustOptions,
+) (map[int64]int64, error) {
+	stocks, err := takeStock(ctx, tx, orders)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := crossThresholds(ctx, tx, stocks); err != nil {
+		return nil, err
+	}
+
+	if err := recordOrderMovements(ctx, tx, orders, options); err != nil {
+		return nil, err
+	}
+
+	if err := adjustWarehouseStocks(ctx, tx, orders); err != nil {
+		return nil, err
+	}
+
+	if err := reserveBackorders(ctx, tx, orders); err != nil {
+		return nil, err
+	}
+
+	if err := redeemPromotions(ctx, tx, options.Redemptions); err != nil {
+		return nil, err
+	}
+
+	if err := writeOrderEvents(ctx, tx, orders, options); err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
+// takeStockStmt subtracts quantities of products in one round trip, rows are returned only for products
+// have enough stock. CockroachDB 2.1 has no UPDATE ... FROM so quantities are looked up by array_position
+const takeStockStmt = `UPDATE inventories
+	SET stock_count = stock_count - ($2::INT[])[array_position($1::INT[], id)]
+	WHERE id = ANY ($1) AND stock_count >= ($2::INT[])[array_position($1::INT[], id)]
+	RETURNING id, stock_count`
+
+// takeStock of orders, quantities of the same product are summed, returns stock of products after it.
+// It fails with inventoryAdjustError of the first order whose product doesn't have enough stock
+func takeStock(ctx context.Context, q Querier, orders []repositories.Order) (map[int64]int64, error) {
+	ids := []int64{}
+	qtys := []int64{}
+	index := map[int64]int{}
+	for _, o := range orders {
+		i, ok := index[o.ProductID]
+		if !ok {
+			i = len(ids)
+			index[o.ProductID] = i
+			ids = append(ids, o.ProductID)
+			qtys = append(qtys, 0)
+		}
+		qtys[i] += o.Quantity
+	}
+
+	rows, err := q.QueryContext(ctx, takeStockStmt, pq.Array(ids), pq.Array(qtys))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := make(map[int64]int64, len(ids))
+	for rows.Next() {
+		var id, stock int64
+		if err := rows.Scan(&id, &stock); err != nil {
+			return nil, err
+		}
+		stocks[id] = stock
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, o := range orders {
+		if _, ok := stocks[o.ProductID]; !ok {
+			return nil, &inventoryAdjustError{
+				error:     fmt.Errorf("cannot modify stock quantity for product %d", o.ProductID),
+				productID: o.ProductID,
+			}
+		}
+	}
+
+	return stocks, nil
+}
+
+// stockCount of a product, 0 if the product not in DB
+func stockCount(ctx context.Context, q Querier, productID int64) (int64, error) {
+	rows, err := q.QueryContext(ctx, "SELECT COALESCE(stock_count, 0) FROM inventories WHERE id = $1", productID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var stock int64
+	if rows.Next() {
+		if err := rows.Scan(&stock); err != nil {
+			return 0, err
+		}
+	}
+
+	return stock, rows.Err()
+}
+
+// takeWarehouseStockStmt subtracts quantities of products in warehouses in one round trip like takeStockStmt,
+// quantities are looked up by keys of "product:warehouse" as array_position doesn't take tuples
+const takeWarehouseStockStmt = `UPDATE warehouse_stocks
+	SET stock_count = stock_count - ($3::INT[])[array_position($2::STRING[], product_id::STRING || ':' || warehouse_id::STRING)]
+	WHERE product_id = ANY ($1)
+	AND stock_count >= ($3::INT[])[array_position($2::STRING[], product_id::STRING || ':' || warehouse_id::STRING)]
+	RETURNING product_id, warehouse_id`
+
+// adjustWarehouseStocks takes allocations of orders from their warehouses, quantities of the same product
+// and warehouse are summed. It fails with inventoryAdjustError of the first allocation not taken
+func adjustWarehouseStocks(ctx context.Context, tx Tx, orders []repositories.Order) error {
+	ids := []int64{}
+	keys := []string{}
+	qtys := []int64{}
+	index := map[string]int{}
+	for _, o := range orders {
+		for _, a := range o.Allocations {
+			if a.Quantity == 0 {
+				// the warehouse may not even have a row of the product
+				continue
 			}
 
-			n, err := result.RowsAffected()
-			if err != nil {
-				return err
+			key := fmt.Sprintf("%d:%d", a.ProductID, a.WarehouseID)
+			i, ok := index[key]
+			if !ok {
+				i = len(keys)
+				index[key] = i
+				ids = append(ids, a.ProductID)
+				keys = append(keys, key)
+				qtys = append(qtys, 0)
 			}
+			qtys[i] += a.Quantity
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, takeWarehouseStockStmt, pq.Array(ids), pq.Array(keys), pq.Array(qtys))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool, len(keys))
+	for rows.Next() {
+		var productID, warehouseID int64
+		if err := rows.Scan(&productID, &warehouseID); err != nil {
+			return err
+		}
+		taken[fmt.Sprintf("%d:%d", productID, warehouseID)] = true
+	}
 
-			if n == 0 {
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		for _, a := range o.Allocations {
+			if a.Quantity != 0 && !taken[fmt.Sprintf("%d:%d", a.ProductID, a.WarehouseID)] {
 				return &inventoryAdjustError{
-					error:     fmt.Errorf("cannot modify stock quantity for product %d", o.ProductID),
-					productID: o.ProductID,
+					error: fmt.Errorf(
+						"cannot modify stock quantity for product %d in warehouse %d",
+						a.ProductID,
+						a.WarehouseID,
+					),
+					productID: a.ProductID,
 				}
 			}
 		}
+	}
 
+	return nil
+}
+
+// reserveBackordersStmt adds backordered quantities of products in one round trip like takeStockStmt
+const reserveBackordersStmt = `UPDATE fulfillment_policies
+	SET backordered_count = backordered_count + ($2::INT[])[array_position($1::INT[], product_id)]
+	WHERE product_id = ANY ($1)
+	AND backordered_count + ($2::INT[])[array_position($1::INT[], product_id)] <= backorder_limit
+	RETURNING product_id`
+
+// reserveBackorders of orders, quantities of the same product are summed. It fails with inventoryAdjustError
+// of the first order whose product reached its backorder limit
+func reserveBackorders(ctx context.Context, tx Tx, orders []repositories.Order) error {
+	ids := []int64{}
+	qtys := []int64{}
+	index := map[int64]int{}
+	for _, o := range orders {
+		if o.Backordered <= 0 {
+			continue
+		}
+
+		i, ok := index[o.ProductID]
+		if !ok {
+			i = len(ids)
+			index[o.ProductID] = i
+			ids = append(ids, o.ProductID)
+			qtys = append(qtys, 0)
+		}
+		qtys[i] += o.Backordered
+	}
+
+	if len(ids) == 0 {
 		return nil
-	})
+	}
+
+	reserved, err := queryIDs(ctx, tx, reserveBackordersStmt, pq.Array(ids), pq.Array(qtys))
+	if err != nil {
+		return err
+	}
+
+	for _, o := range orders {
+		if o.Backordered > 0 && !reserved[o.ProductID] {
+			return &inventoryAdjustError{
+				error:     fmt.Errorf("cannot backorder product %d", o.ProductID),
+				productID: o.ProductID,
+			}
+		}
+	}
+
+	return nil
+}
+
+// redeemStmt uses coupons in one round trip like takeStockStmt, rows are returned only for coupons
+// still valid and under their usage limit
+const redeemStmt = `UPDATE promotions
+	SET used_count = used_count + ($2::INT[])[array_position($1::STRING[], code)]
+	WHERE code = ANY ($1)
+	AND (usage_limit = 0 OR used_count + ($2::INT[])[array_position($1::STRING[], code)] <= usage_limit)
+	AND valid_from <= now() AND (valid_until IS NULL OR valid_until > now())
+	RETURNING code`
+
+// redeemByCustomerStmt counts uses of coupons by customer $2, rows are returned only for coupons the customer
+// is still under the limit of
+const redeemByCustomerStmt = `INSERT INTO promotion_redemptions (code, customer_id, used_count)
+	SELECT code, $2::INT, ($3::INT[])[array_position($1::STRING[], code)] FROM unnest($1::STRING[]) AS c (code)
+	ON CONFLICT (code, customer_id) DO UPDATE SET used_count = promotion_redemptions.used_count + excluded.used_count
+	WHERE promotion_redemptions.used_count + excluded.used_count <=
+		($4::INT[])[array_position($1::STRING[], promotion_redemptions.code)]
+	RETURNING code`
+
+// redeemPromotions of an order, so redemptions are of the same customer. Uses of the same coupon are summed,
+// it fails with promotionRedeemError of the first coupon cannot be redeemed
+func redeemPromotions(ctx context.Context, tx Tx, redemptions []repositories.Redemption) error {
+	if len(redemptions) == 0 {
+		return nil
+	}
+
+	codes, uses := []string{}, []int64{}
+	var limitedCodes []string
+	var limitedUses, limits []int64
+	index, limitedIndex := map[string]int{}, map[string]int{}
+	for _, rd := range redemptions {
+		i, ok := index[rd.Code]
+		if !ok {
+			i = len(codes)
+			index[rd.Code] = i
+			codes = append(codes, rd.Code)
+			uses = append(uses, 0)
+		}
+		uses[i]++
+
+		if rd.PerCustomerLimit <= 0 {
+			continue
+		}
+
+		j, ok := limitedIndex[rd.Code]
+		if !ok {
+			j = len(limitedCodes)
+			limitedIndex[rd.Code] = j
+			limitedCodes = append(limitedCodes, rd.Code)
+			limitedUses = append(limitedUses, 0)
+			limits = append(limits, rd.PerCustomerLimit)
+		}
+		limitedUses[j]++
+	}
+
+	redeemed, err := queryCodes(ctx, tx, redeemStmt, pq.Array(codes), pq.Array(uses))
+	if err != nil {
+		return err
+	}
+
+	if err := checkRedeemed(redemptions, redeemed, false); err != nil {
+		return err
+	}
+
+	if len(limitedCodes) == 0 {
+		return nil
+	}
+
+	redeemed, err = queryCodes(ctx, tx, redeemByCustomerStmt,
+		pq.Array(limitedCodes), redemptions[0].CustomerID, pq.Array(limitedUses), pq.Array(limits))
+	if err != nil {
+		return err
+	}
+
+	return checkRedeemed(redemptions, redeemed, true)
+}
+
+// checkRedeemed fails with promotionRedeemError of the first of redemptions not in redeemed, limited tells
+// only redemptions with a per customer limit were redeemed
+func checkRedeemed(redemptions []repositories.Redemption, redeemed map[string]bool, limited bool) error {
+	for _, rd := range redemptions {
+		if limited && rd.PerCustomerLimit <= 0 {
+			continue
+		}
+
+		if !redeemed[rd.Code] {
+			return &promotionRedeemError{
+				error: fmt.Errorf("coupon %s cannot be redeemed", rd.Code),
+				code:  rd.Code,
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryIDs returned by a statement updating rows of products
+func queryIDs(ctx context.Context, q Querier, stmt string, args ...interface{}) (map[int64]bool, error) {
+	rows, err := q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	ids := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+
+	return ids, rows.Err()
+}
+
+// queryCodes returned by a statement updating rows of coupons
+func queryCodes(ctx context.Context, q Querier, stmt string, args ...interface{}) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := map[string]bool{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		codes[code] = true
+	}
+
+	return codes, rows.Err()
 }
 
 // ListInventories by ID, omit items that not in DB
-func (r *CockroachRepo) ListInventories(ctx context.Context, IDs []int64) ([]repositories.Inventory, error) {
-	rows, err := r.querier.QueryContext(ctx, "SELECT id, stock_count FROM inventories WHERE id = ANY ($1)", pq.Array(IDs))
+func (r *CockroachRepo) ListInventories(ctx context.Context, IDs []int64, opts ...repositories.ReadOption) ([]repositories.Inventory, error) {
+	q, asOf := r.reader(opts)
+	return listInventories(ctx, q, asOf, IDs)
+}
+
+func listInventories(ctx context.Context, q Querier, asOf string, IDs []int64) ([]repositories.Inventory, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, stock_count, price FROM inventories"+asOf+" WHERE id = ANY ($1)", pq.Array(IDs))
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +533,7 @@ func (r *CockroachRepo) ListInventories(ctx context.Context, IDs []int64) ([]rep
 	results := make([]repositories.Inventory, 0, len(IDs))
 	for rows.Next() {
 		inv := repositories.Inventory{}
-		if err := rows.Scan(&inv.ProductID, &inv.StockCount); err != nil {
+		if err := rows.Scan(&inv.ProductID, &inv.StockCount, &inv.Price); err != nil {
 			return nil, err
 		}
 		results = append(results, inv)
@@ -88,11 +546,135 @@ func (r *CockroachRepo) ListInventories(ctx context.Context, IDs []int64) ([]rep
 	return results, nil
 }
 
+// ListWarehouseStocks of products, omit warehouses out of stock
+func (r *CockroachRepo) ListWarehouseStocks(ctx context.Context, IDs []int64, opts ...repositories.ReadOption) ([]repositories.WarehouseStock, error) {
+	q, asOf := r.reader(opts)
+	return listWarehouseStocks(ctx, q, asOf, IDs)
+}
+
+func listWarehouseStocks(ctx context.Context, q Querier, asOf string, IDs []int64) ([]repositories.WarehouseStock, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT s.product_id, s.warehouse_id, w.region, s.stock_count
+		FROM warehouse_stocks s JOIN warehouses w ON w.id = s.warehouse_id`+asOf+`
+		WHERE s.product_id = ANY ($1) AND s.stock_count > 0
+		ORDER BY s.product_id, s.warehouse_id`,
+		pq.Array(IDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []repositories.WarehouseStock{}
+	for rows.Next() {
+		ws := repositories.WarehouseStock{}
+		if err := rows.Scan(&ws.ProductID, &ws.WarehouseID, &ws.Region, &ws.StockCount); err != nil {
+			return nil, err
+		}
+		results = append(results, ws)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ListFulfillmentPolicies of products, omit products without policy
+func (r *CockroachRepo) ListFulfillmentPolicies(ctx context.Context, IDs []int64) ([]repositories.FulfillmentPolicy, error) {
+	return listFulfillmentPolicies(ctx, r.querier, IDs)
+}
+
+func listFulfillmentPolicies(ctx context.Context, q Querier, IDs []int64) ([]repositories.FulfillmentPolicy, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT product_id, policy, backorder_limit, backordered_count, release_at
+		FROM fulfillment_policies WHERE product_id = ANY ($1)`,
+		pq.Array(IDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]repositories.FulfillmentPolicy, 0, len(IDs))
+	for rows.Next() {
+		p := repositories.FulfillmentPolicy{}
+		releaseAt := pq.NullTime{}
+		if err := rows.Scan(&p.ProductID, &p.Kind, &p.Limit, &p.BackorderedCount, &releaseAt); err != nil {
+			return nil, err
+		}
+		p.ReleaseAt = releaseAt.Time
+		results = append(results, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ListPromotions by coupon code, omit codes that not in DB
+func (r *CockroachRepo) ListPromotions(ctx context.Context, codes []string) ([]repositories.Promotion, error) {
+	return listPromotions(ctx, r.querier, codes)
+}
+
+func listPromotions(ctx context.Context, q Querier, codes []string) ([]repositories.Promotion, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT code, kind, value, product_id, buy_quantity, free_quantity, usage_limit, used_count,
+		per_customer_limit, valid_from, valid_until FROM promotions WHERE code = ANY ($1)`,
+		pq.Array(codes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]repositories.Promotion, 0, len(codes))
+	for rows.Next() {
+		p := repositories.Promotion{}
+		validUntil := pq.NullTime{}
+		if err := rows.Scan(
+			&p.Code,
+			&p.Kind,
+			&p.Value,
+			&p.ProductID,
+			&p.BuyQuantity,
+			&p.FreeQuantity,
+			&p.UsageLimit,
+			&p.UsedCount,
+			&p.PerCustomerLimit,
+			&p.ValidFrom,
+			&validUntil,
+		); err != nil {
+			return nil, err
+		}
+		p.ValidUntil = validUntil.Time
+		results = append(results, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 // Querier implemented by sql.Stmt
 type Querier interface {
 	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
 
+// Tx implemented by sql.Tx
+type Tx interface {
+	crdb.Tx
+	Querier
+}
+
 type inventoryAdjustError struct {
 	error
 	productID int64
@@ -101,3 +683,12 @@ type inventoryAdjustError struct {
 func (e *inventoryAdjustError) ProductID() int64 {
 	return e.productID
 }
+
+type promotionRedeemError struct {
+	error
+	code string
+}
+
+func (e *promotionRedeemError) Code() string {
+	return e.code
+}
@@ -3,12 +3,14 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"tomshop/repositories"
 
-	"github.com/cockroachdb/cockroach-go/crdb"
+	"github.com/lib/pq"
 )
 
 func TestCockroachRepo_AdjustInventories(t *testing.T) {
@@ -17,6 +19,14 @@ func TestCockroachRepo_AdjustInventories(t *testing.T) {
 	t.Run("must Rollback if any error happen when calling Executor.ExecContext", errorInExecContext)
 	t.Run("must Rollback even if panic in execContext", panicInExecContext)
 	t.Run("must Rollback when cannot adjust any item", rollBackWhenNoRowUpdated)
+	t.Run("must Rollback when cannot redeem coupon", rollBackWhenCouponNotRedeemed)
+	t.Run("must Rollback when warehouse doesn't have enough items", rollBackWhenWarehouseNotEnough)
+	t.Run("must not take from warehouses allocations without quantity", skipZeroAllocations)
+	t.Run("must Rollback when backorder limit reached", rollBackWhenBackorderLimitReached)
+	t.Run("must record stock movements before commit", recordMovementsBeforeCommit)
+	t.Run("must take warehouses, backorders and coupons of all orders at once", setBasedWhenManyOrders)
+	t.Run("must tell stock left once committed", stockToldOnceCommitted)
+	t.Run("must write outbox events before commit", writeEventsBeforeCommit)
 }
 
 var testOrder = []repositories.Order{
@@ -33,7 +43,7 @@ var testOrder = []repositories.Order{
 func errorWhenStartTxn(tt *testing.T) {
 	dummyErr := fmt.Errorf("dummy error")
 	r := &CockroachRepo{
-		txnFactory: func(ctx context.Context, opts *sql.TxOptions) (crdb.Tx, error) {
+		txnFactory: func(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
 			return nil, dummyErr
 		},
 	}
@@ -45,7 +55,7 @@ func errorWhenStartTxn(tt *testing.T) {
 
 func inputEmptyNilError(tt *testing.T) {
 	r := &CockroachRepo{
-		txnFactory: func(ctx context.Context, opts *sql.TxOptions) (crdb.Tx, error) {
+		txnFactory: func(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
 			return &sql.Tx{}, nil
 		},
 	}
@@ -76,7 +86,7 @@ func errorInExecContext(tt *testing.T) {
 	}
 
 	r := &CockroachRepo{
-		txnFactory: func(c context.Context, opts *sql.TxOptions) (crdb.Tx, error) {
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
 			return mockTx{
 				commit: func() error {
 					expectedFnCall["commit"].called++
@@ -130,7 +140,7 @@ func panicInExecContext(tt *testing.T) {
 	}
 
 	r := &CockroachRepo{
-		txnFactory: func(c context.Context, opts *sql.TxOptions) (crdb.Tx, error) {
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
 			return mockTx{
 				commit: func() error {
 					expectedFnCall["commit"].called++
@@ -172,12 +182,15 @@ func rollBackWhenNoRowUpdated(tt *testing.T) {
 			expecting: 1,
 		},
 		"execContext": &expectingCall{
-			expecting: 3,
+			expecting: 1, // savepoint
+		},
+		"queryContext": &expectingCall{
+			expecting: 1,
 		},
 	}
 
 	r := &CockroachRepo{
-		txnFactory: func(c context.Context, opts *sql.TxOptions) (crdb.Tx, error) {
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
 			return mockTx{
 				commit: func() error {
 					expectedFnCall["commit"].called++
@@ -189,21 +202,26 @@ func rollBackWhenNoRowUpdated(tt *testing.T) {
 				},
 				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
 					expectedFnCall["execContext"].called++
-					x := "UPDATE inventories SET stock_count = stock_count - $1 WHERE id = $2 AND stock_count >= $3"
-					if q == x && expectedFnCall["execContext"].called == 3 {
-						return mockSQLResult{
-							rowsAffected: func() (int64, error) {
-								return 0, nil
-							},
-						}, nil
-					}
-
 					return mockSQLResult{
 						rowsAffected: func() (int64, error) {
 							return 1, nil
 						},
 					}, nil
 				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					expectedFnCall["queryContext"].called++
+					if q != takeStockStmt {
+						tt.Error("unexpected query", q)
+					}
+
+					ids, qtys := *args[0].(*pq.Int64Array), *args[1].(*pq.Int64Array)
+					if !reflect.DeepEqual(ids, pq.Int64Array{1, 2}) || !reflect.DeepEqual(qtys, pq.Int64Array{11, 22}) {
+						tt.Error("expecting all products taken in one statement, got", ids, qtys)
+					}
+
+					// only product 1 has enough stock
+					return mockRows([]string{"id", "stock_count"}, []driver.Value{int64(1), int64(0)}), nil
+				},
 			}, nil
 		},
 	}
@@ -222,10 +240,435 @@ func rollBackWhenNoRowUpdated(tt *testing.T) {
 	}
 }
 
+func rollBackWhenCouponNotRedeemed(tt *testing.T) {
+	type expectingCall struct {
+		expecting int
+		called    int
+	}
+	expectedFnCall := map[string]*expectingCall{
+		"commit": &expectingCall{
+			expecting: 0,
+		},
+		"rollback": &expectingCall{
+			expecting: 1,
+		},
+		"execContext": &expectingCall{
+			expecting: 2, // savepoint and movements
+		},
+	}
+
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					expectedFnCall["commit"].called++
+					return nil
+				},
+				rollback: func() error {
+					expectedFnCall["rollback"].called++
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					expectedFnCall["execContext"].called++
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					if q == redeemStmt {
+						return mockRows([]string{"code"}), nil
+					}
+					return mockUpdatedRows(q, args), nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, testOrder, repositories.WithRedemptions(repositories.Redemption{
+		Code:       "TEN",
+		CustomerID: 1,
+	}))
+	if ev, ok := err.(repositories.PromotionRedemptionError); !ok {
+		tt.Errorf("expecting error returned with repositories.PromotionRedemptionError type, got %T", err)
+	} else if ev.Code() != "TEN" {
+		tt.Error("expecting error returned with correct coupon code")
+	}
+
+	for k, v := range expectedFnCall {
+		if v.called != v.expecting {
+			tt.Errorf("expecting calling %s only %d time, got %d", k, v.expecting, v.called)
+		}
+	}
+}
+
+func rollBackWhenWarehouseNotEnough(tt *testing.T) {
+	rollbackCalled := 0
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					tt.Error("unexpected commit")
+					return nil
+				},
+				rollback: func() error {
+					rollbackCalled++
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					if q == takeWarehouseStockStmt {
+						return mockRows([]string{"product_id", "warehouse_id"}), nil
+					}
+					return mockUpdatedRows(q, args), nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, []repositories.Order{
+		{
+			ProductID: 1,
+			Quantity:  2,
+			Allocations: []repositories.Allocation{
+				{
+					ProductID:   1,
+					WarehouseID: 3,
+					Quantity:    2,
+				},
+			},
+		},
+	})
+	if ev, ok := err.(repositories.InventoryQuantityUpdateError); !ok {
+		tt.Errorf("expecting error returned with repositories.InventoryQuantityUpdateError type, got %T", err)
+	} else if ev.ProductID() != 1 {
+		tt.Error("expecting error returned with correct ProductID")
+	}
+
+	if rollbackCalled != 1 {
+		tt.Errorf("expecting calling rollback only 1 time, got %d", rollbackCalled)
+	}
+}
+
+func skipZeroAllocations(tt *testing.T) {
+	committed := false
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					committed = true
+					return nil
+				},
+				rollback: func() error {
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					if q == takeWarehouseStockStmt {
+						tt.Error("unexpected warehouse update")
+					}
+					return mockUpdatedRows(q, args), nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, []repositories.Order{
+		{
+			ProductID:   1,
+			Backordered: 2,
+			Allocations: []repositories.Allocation{
+				{
+					ProductID:   1,
+					WarehouseID: 3,
+				},
+			},
+		},
+	})
+	if err != nil || !committed {
+		tt.Error("expecting committed, got", err)
+	}
+}
+
+func rollBackWhenBackorderLimitReached(tt *testing.T) {
+	rollbackCalled := 0
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					tt.Error("unexpected commit")
+					return nil
+				},
+				rollback: func() error {
+					rollbackCalled++
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					if q == reserveBackordersStmt {
+						return mockRows([]string{"product_id"}), nil
+					}
+					return mockUpdatedRows(q, args), nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, []repositories.Order{
+		{
+			ProductID:   2,
+			Quantity:    1,
+			Backordered: 5,
+		},
+	})
+	if ev, ok := err.(repositories.InventoryQuantityUpdateError); !ok {
+		tt.Errorf("expecting error returned with repositories.InventoryQuantityUpdateError type, got %T", err)
+	} else if ev.ProductID() != 2 {
+		tt.Error("expecting error returned with correct ProductID")
+	}
+
+	if rollbackCalled != 1 {
+		tt.Errorf("expecting calling rollback only 1 time, got %d", rollbackCalled)
+	}
+}
+
+func recordMovementsBeforeCommit(tt *testing.T) {
+	movements := [][]interface{}{}
+	committed := false
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					committed = true
+					return nil
+				},
+				rollback: func() error {
+					tt.Error("unexpected rollback")
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					if strings.HasPrefix(q, "INSERT INTO stock_movements") {
+						if committed {
+							tt.Error("expecting movements recorded before commit")
+						}
+						movements = append(movements, args[0].([]interface{}))
+					}
+
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, testOrder, repositories.WithReference("order-1", "customer:1"))
+	if err != nil {
+		tt.Error("unexpected error", err)
+	}
+
+	expected := [][]interface{}{
+		{pq.Array([]int64{1, 2}), pq.Array([]int64{11, 22}), repositories.ReasonOrder, "order-1", "customer:1"},
+	}
+	if !reflect.DeepEqual(movements, expected) {
+		tt.Error("expecting movements of every product recorded at once, got", movements)
+	}
+
+	if !committed {
+		tt.Error("expecting commit")
+	}
+}
+
+func setBasedWhenManyOrders(tt *testing.T) {
+	queried := map[string][]interface{}{}
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					return nil
+				},
+				rollback: func() error {
+					tt.Error("unexpected rollback")
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					if _, ok := queried[q]; ok {
+						tt.Error("expecting one round trip per statement, got another", q)
+					}
+					queried[q] = args
+					return mockUpdatedRows(q, args), nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, []repositories.Order{
+		{
+			ProductID:   1,
+			Quantity:    2,
+			Backordered: 1,
+			Allocations: []repositories.Allocation{{ProductID: 1, WarehouseID: 3, Quantity: 2}},
+		},
+		{
+			ProductID:   1,
+			Quantity:    1,
+			Backordered: 2,
+			Allocations: []repositories.Allocation{{ProductID: 1, WarehouseID: 3, Quantity: 1}},
+		},
+		{
+			ProductID:   2,
+			Quantity:    1,
+			Allocations: []repositories.Allocation{{ProductID: 2, WarehouseID: 4, Quantity: 1}},
+		},
+	}, repositories.WithRedemptions(
+		repositories.Redemption{Code: "TEN", CustomerID: 7},
+		repositories.Redemption{Code: "ONCE", CustomerID: 7, PerCustomerLimit: 1},
+	))
+	if err != nil {
+		tt.Fatal("unexpected error", err)
+	}
+
+	expected := map[string][]interface{}{
+		takeStockStmt:          {pq.Array([]int64{1, 2}), pq.Array([]int64{3, 1})},
+		crossThresholdsStmt:    queried[crossThresholdsStmt],
+		takeWarehouseStockStmt: {pq.Array([]int64{1, 2}), pq.Array([]string{"1:3", "2:4"}), pq.Array([]int64{3, 1})},
+		reserveBackordersStmt:  {pq.Array([]int64{1}), pq.Array([]int64{3})},
+		redeemStmt:             {pq.Array([]string{"TEN", "ONCE"}), pq.Array([]int64{1, 1})},
+		redeemByCustomerStmt:   {pq.Array([]string{"ONCE"}), int64(7), pq.Array([]int64{1}), pq.Array([]int64{1})},
+	}
+	if !reflect.DeepEqual(queried, expected) {
+		tt.Error("expecting quantities summed by product and warehouse, got", queried)
+	}
+}
+
+func stockToldOnceCommitted(tt *testing.T) {
+	committed := false
+	var told []repositories.Inventory
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					committed = true
+					return nil
+				},
+				rollback: func() error {
+					tt.Error("unexpected rollback")
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					if q == takeStockStmt {
+						return mockRows([]string{"id", "stock_count"}, []driver.Value{int64(2), int64(8)}, []driver.Value{int64(1), int64(4)}), nil
+					}
+					return mockUpdatedRows(q, args), nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, testOrder, repositories.OnCommitted(func(stocks []repositories.Inventory) {
+		if !committed {
+			tt.Error("expecting stock told after commit")
+		}
+		told = stocks
+	}))
+	if err != nil {
+		tt.Fatal("unexpected error", err)
+	}
+
+	expected := []repositories.Inventory{{ProductID: 1, StockCount: 4}, {ProductID: 2, StockCount: 8}}
+	if !reflect.DeepEqual(told, expected) {
+		tt.Error("expecting stock of products in order, got", told)
+	}
+}
+
+func writeEventsBeforeCommit(tt *testing.T) {
+	events := []string{}
+	committed := false
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					committed = true
+					return nil
+				},
+				rollback: func() error {
+					tt.Error("unexpected rollback")
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					if strings.HasPrefix(q, "INSERT INTO outbox (") {
+						if committed {
+							tt.Error("expecting events written before commit")
+						}
+						a := args[0].([]interface{})
+						events = append(events, fmt.Sprintf("%s/%s %s", a[0], a[1], a[2]))
+					}
+
+					return mockSQLResult{
+						rowsAffected: func() (int64, error) {
+							return 1, nil
+						},
+					}, nil
+				},
+			}, nil
+		},
+	}
+
+	err := r.AdjustInventories(nil, testOrder, repositories.WithReference("order-1", "customer:1"))
+	if err != nil {
+		tt.Error("unexpected error", err)
+	}
+
+	expected := []string{
+		"order/order-1 order.placed",
+		"product/1 stock.changed",
+		"product/2 stock.changed",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		tt.Error("expecting order placed and stock changed events, got", events)
+	}
+}
+
 type mockTx struct {
-	commit      func() error
-	rollback    func() error
-	execContext func(context.Context, string, ...interface{}) (sql.Result, error)
+	commit       func() error
+	rollback     func() error
+	execContext  func(context.Context, string, ...interface{}) (sql.Result, error)
+	queryContext func(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
 
 func (m mockTx) Commit() error {
@@ -240,6 +683,14 @@ func (m mockTx) ExecContext(ctx context.Context, query string, args ...interface
 	return m.execContext(ctx, query, args)
 }
 
+// QueryContext returns every row of adjust as updated and no threshold crossed if not mocked
+func (m mockTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if m.queryContext == nil {
+		return mockUpdatedRows(query, args), nil
+	}
+	return m.queryContext(ctx, query, args...)
+}
+
 type mockSQLResult struct {
 	lastInsertID func() (int64, error)
 	rowsAffected func() (int64, error)
@@ -0,0 +1,149 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+
+	_ "github.com/lib/pq"
+)
+
+// simulatedRoundTrip is the latency of a statement when benchmarking without DB
+const simulatedRoundTrip = 200 * time.Microsecond
+
+var benchLines = []int{1, 10, 50}
+
+// takeStockByLoop is how stock was taken before takeStock, one round trip per line
+func takeStockByLoop(ctx context.Context, tx Tx, orders []repositories.Order) error {
+	updateStmt := "UPDATE inventories SET stock_count = stock_count - $1 WHERE id = $2 AND stock_count >= $3"
+	for _, o := range orders {
+		result, err := tx.ExecContext(ctx, updateStmt, o.Quantity, o.ProductID, o.Quantity)
+		if err != nil {
+			return err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			return &inventoryAdjustError{
+				error:     fmt.Errorf("cannot modify stock quantity for product %d", o.ProductID),
+				productID: o.ProductID,
+			}
+		}
+	}
+
+	return nil
+}
+
+var takeStockImpls = []struct {
+	name string
+	fn   func(context.Context, Tx, []repositories.Order) error
+}{
+	{"loop", takeStockByLoop},
+	{"set", func(ctx context.Context, tx Tx, orders []repositories.Order) error {
+		_, err := takeStock(ctx, tx, orders)
+		return err
+	}},
+}
+
+func benchOrders(lines int, firstID int64) []repositories.Order {
+	orders := make([]repositories.Order, lines)
+	for i := range orders {
+		orders[i] = repositories.Order{
+			ProductID: firstID + int64(i),
+			Quantity:  1,
+		}
+	}
+
+	return orders
+}
+
+// BenchmarkTakeStock_SimulatedLatency shows the cost of round trips without DB
+func BenchmarkTakeStock_SimulatedLatency(b *testing.B) {
+	tx := mockTx{
+		execContext: func(context.Context, string, ...interface{}) (sql.Result, error) {
+			time.Sleep(simulatedRoundTrip)
+			return mockSQLResult{
+				rowsAffected: func() (int64, error) {
+					return 1, nil
+				},
+			}, nil
+		},
+		queryContext: func(_ context.Context, _ string, args ...interface{}) (*sql.Rows, error) {
+			time.Sleep(simulatedRoundTrip)
+			return mockTakeStockRows(args), nil
+		},
+	}
+
+	for _, impl := range takeStockImpls {
+		for _, lines := range benchLines {
+			orders := benchOrders(lines, 1)
+			b.Run(fmt.Sprintf("%s/lines=%d", impl.name, lines), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := impl.fn(context.Background(), tx, orders); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkTakeStock_CockroachDB runs against DATABASE_ADDR, every iteration is rolled back
+func BenchmarkTakeStock_CockroachDB(b *testing.B) {
+	addr := os.Getenv("DATABASE_ADDR")
+	if addr == "" {
+		b.Skip("DATABASE_ADDR is not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	const firstID = 900000
+	b.Cleanup(func() {
+		_, err := db.Exec("DELETE FROM inventories WHERE id >= $1 AND id < $2", firstID, firstID+benchLines[len(benchLines)-1])
+		if err != nil {
+			b.Error("cannot delete fixture products", err)
+		}
+	})
+	for i := 0; i < benchLines[len(benchLines)-1]; i++ {
+		_, err := db.Exec("UPSERT INTO inventories (id, stock_count, version) VALUES ($1, 1000000, 0)", firstID+i)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	for _, impl := range takeStockImpls {
+		for _, lines := range benchLines {
+			orders := benchOrders(lines, firstID)
+			b.Run(fmt.Sprintf("%s/lines=%d", impl.name, lines), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tx, err := db.BeginTx(ctx, nil)
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					if err := impl.fn(ctx, tx, orders); err != nil {
+						b.Fatal(err)
+					}
+
+					if err := tx.Rollback(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
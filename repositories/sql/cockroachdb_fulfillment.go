@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"context"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+// SetFulfillmentPolicy of a product, BackorderedCount of p is ignored and items already backordered
+// are kept. Returns the policy set with its BackorderedCount
+func (r *CockroachRepo) SetFulfillmentPolicy(
+	ctx context.Context,
+	p repositories.FulfillmentPolicy,
+) (repositories.FulfillmentPolicy, error) {
+	tx, err := r.txnFactory(ctx, nil)
+	if err != nil {
+		return repositories.FulfillmentPolicy{}, err
+	}
+
+	releaseAt := pq.NullTime{Time: p.ReleaseAt, Valid: !p.ReleaseAt.IsZero()}
+	err = r.executeInTx(ctx, "SetFulfillmentPolicy", tx, func() error {
+		rows, err := tx.QueryContext(
+			ctx,
+			`INSERT INTO fulfillment_policies (product_id, policy, backorder_limit, release_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (product_id) DO UPDATE
+			SET policy = excluded.policy, backorder_limit = excluded.backorder_limit, release_at = excluded.release_at
+			RETURNING backordered_count`,
+			p.ProductID,
+			p.Kind,
+			p.Limit,
+			releaseAt,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		if rows.Next() {
+			if err := rows.Scan(&p.BackorderedCount); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return repositories.FulfillmentPolicy{}, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,70 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+func TestCockroachRepo_SetFulfillmentPolicy(t *testing.T) {
+	releaseAt := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	var args []interface{}
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					return nil
+				},
+				rollback: func() error {
+					t.Error("unexpected rollback")
+					return nil
+				},
+				execContext: func(c context.Context, q string, a ...interface{}) (sql.Result, error) {
+					return mockSQLResult{}, nil
+				},
+				queryContext: func(c context.Context, q string, a ...interface{}) (*sql.Rows, error) {
+					if !strings.HasPrefix(q, "INSERT INTO fulfillment_policies") || strings.Contains(q, "backordered_count =") {
+						t.Error("expecting policy upserted keeping backordered items, got", q)
+					}
+					args = a
+					return mockRows([]string{"backordered_count"}, []driver.Value{int64(3)}), nil
+				},
+			}, nil
+		},
+	}
+
+	p, err := r.SetFulfillmentPolicy(nil, repositories.FulfillmentPolicy{
+		ProductID:        1,
+		Kind:             repositories.Preorder,
+		Limit:            10,
+		BackorderedCount: 100,
+		ReleaseAt:        releaseAt,
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expectedArgs := []interface{}{int64(1), repositories.Preorder, int64(10), pq.NullTime{Time: releaseAt, Valid: true}}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Error("expecting policy args, got", args)
+	}
+
+	expected := repositories.FulfillmentPolicy{
+		ProductID:        1,
+		Kind:             repositories.Preorder,
+		Limit:            10,
+		BackorderedCount: 3,
+		ReleaseAt:        releaseAt,
+	}
+	if p != expected {
+		t.Error("expecting policy with backordered items in DB, got", p)
+	}
+}
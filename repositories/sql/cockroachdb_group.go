@@ -0,0 +1,214 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"tomshop/ctxlog"
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+// PlaceOrders places orders in one transaction, returns an error per placement.
+// Every plan sees the snapshot left by previous plans. If saving fails by an order of the group, e.g. out of
+// stock, the group is split and every order is placed in its own transaction, so one bad order never fails others.
+// Other errors are returned for every placement
+func (r *CockroachRepo) PlaceOrders(ctx context.Context, placements []repositories.OrderPlacement) []error {
+	errs := make([]error, len(placements))
+	if len(placements) == 0 {
+		return errs
+	}
+
+	tx, err := r.txnFactory(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	type planned struct {
+		orders  []repositories.Order
+		options repositories.AdjustOptions
+		stocks  map[int64]int64
+	}
+	var plans []planned
+	err = r.executeInTx(ctx, "PlaceOrders", tx, func() error {
+		// reset on every retry
+		for i := range errs {
+			errs[i] = nil
+		}
+
+		snapshot, err := readOrderSnapshot(ctx, tx, groupQuery(placements))
+		if err != nil {
+			return err
+		}
+
+		plans = make([]planned, 0, len(placements))
+		for i, p := range placements {
+			orders, opts, err := p.Plan(snapshotFor(snapshot, p.Query))
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			options := repositories.AdjustOptions{}
+			for _, opt := range opts {
+				opt(&options)
+			}
+			takeFromSnapshot(&snapshot, orders, options)
+			plans = append(plans, planned{orders: orders, options: options})
+		}
+
+		for i, p := range plans {
+			if len(p.orders) == 0 {
+				continue
+			}
+
+			stocks, err := adjust(ctx, tx, p.orders, p.options)
+			if err != nil {
+				return err
+			}
+			plans[i].stocks = stocks
+		}
+
+		return nil
+	})
+	if err == nil {
+		// in order of plans, so stock of a product taken by several of them ends at the latest
+		for _, p := range plans {
+			committed(p.options, p.stocks)
+		}
+		return errs
+	}
+
+	if !failedByAnOrder(err) {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	ctxlog.Println(ctx, "cannot place orders together, placing one by one:", err)
+	for i, p := range placements {
+		errs[i] = r.PlaceOrder(ctx, p.Query, p.Plan)
+	}
+
+	return errs
+}
+
+// failedByAnOrder tells whether err saving a group is of one of its orders, so others can be saved without it.
+// Errors of the transaction, e.g. it may or may not be committed or it was given up after retries, are not:
+// placing orders again could save them twice or add to contention of rows already hot
+func failedByAnOrder(err error) bool {
+	switch err.(type) {
+	case *inventoryAdjustError, *promotionRedeemError:
+		return true
+	}
+
+	pqErr, ok := err.(*pq.Error)
+	// integrity constraint violation
+	return ok && pqErr.Code.Class() == "23"
+}
+
+// groupQuery reads everything needed by all placements
+func groupQuery(placements []repositories.OrderPlacement) repositories.OrderQuery {
+	q := repositories.OrderQuery{}
+	seenProducts := map[int64]bool{}
+	seenCodes := map[string]bool{}
+	for _, p := range placements {
+		for _, id := range p.Query.ProductIDs {
+			if !seenProducts[id] {
+				seenProducts[id] = true
+				q.ProductIDs = append(q.ProductIDs, id)
+			}
+		}
+
+		for _, c := range p.Query.CouponCodes {
+			if !seenCodes[c] {
+				seenCodes[c] = true
+				q.CouponCodes = append(q.CouponCodes, c)
+			}
+		}
+
+		q.WarehouseStocks = q.WarehouseStocks || p.Query.WarehouseStocks
+	}
+
+	return q
+}
+
+// snapshotFor a placement, as if only what it asked for was read
+func snapshotFor(s repositories.OrderSnapshot, q repositories.OrderQuery) repositories.OrderSnapshot {
+	products := map[int64]bool{}
+	for _, id := range q.ProductIDs {
+		products[id] = true
+	}
+
+	codes := map[string]bool{}
+	for _, c := range q.CouponCodes {
+		codes[c] = true
+	}
+
+	view := repositories.OrderSnapshot{}
+	for _, inv := range s.Inventories {
+		if products[inv.ProductID] {
+			view.Inventories = append(view.Inventories, inv)
+		}
+	}
+
+	for _, p := range s.Policies {
+		if products[p.ProductID] {
+			view.Policies = append(view.Policies, p)
+		}
+	}
+
+	for _, p := range s.Promotions {
+		if codes[p.Code] {
+			view.Promotions = append(view.Promotions, p)
+		}
+	}
+
+	if q.WarehouseStocks {
+		for _, ws := range s.WarehouseStocks {
+			if products[ws.ProductID] {
+				view.WarehouseStocks = append(view.WarehouseStocks, ws)
+			}
+		}
+	}
+
+	return view
+}
+
+// takeFromSnapshot what adjust is going to write, so the next plan sees it
+func takeFromSnapshot(s *repositories.OrderSnapshot, orders []repositories.Order, options repositories.AdjustOptions) {
+	for _, o := range orders {
+		for i := range s.Inventories {
+			if s.Inventories[i].ProductID == o.ProductID {
+				s.Inventories[i].StockCount -= o.Quantity
+			}
+		}
+
+		for i := range s.Policies {
+			if s.Policies[i].ProductID == o.ProductID {
+				s.Policies[i].BackorderedCount += o.Backordered
+			}
+		}
+
+		for _, a := range o.Allocations {
+			for i := range s.WarehouseStocks {
+				if s.WarehouseStocks[i].ProductID == a.ProductID && s.WarehouseStocks[i].WarehouseID == a.WarehouseID {
+					s.WarehouseStocks[i].StockCount -= a.Quantity
+				}
+			}
+		}
+	}
+
+	for _, rd := range options.Redemptions {
+		for i := range s.Promotions {
+			if s.Promotions[i].Code == rd.Code {
+				s.Promotions[i].UsedCount++
+			}
+		}
+	}
+}
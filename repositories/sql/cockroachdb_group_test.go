@@ -0,0 +1,171 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+func TestCockroachRepo_PlaceOrders(t *testing.T) {
+	t.Run("must plan with stock left by previous plans", planWithStockLeft)
+	t.Run("must place one by one when saving the group fails by an order", placeOneByOneWhenGroupFails)
+	t.Run("must not place again when the commit may be done", notPlaceAgainWhenAmbiguous)
+	t.Run("must not place again when retries are exhausted", notPlaceAgainWhenRetriesExhausted)
+}
+
+// takeUpTo plans to take qty, fails if snapshot doesn't have enough
+func takeUpTo(qty int64, seen *[]int64) repositories.OrderPlan {
+	return func(s repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		*seen = append(*seen, s.Inventories[0].StockCount)
+		if s.Inventories[0].StockCount < qty {
+			return nil, nil, fmt.Errorf("not enough stock")
+		}
+		return []repositories.Order{{ProductID: 1, Quantity: qty}}, nil, nil
+	}
+}
+
+func planWithStockLeft(tt *testing.T) {
+	committed := false
+	txns := 0
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			txns++
+			return placeOrderTx(
+				func() int64 { return 5 },
+				func() error { return nil },
+				&committed,
+			), nil
+		},
+	}
+
+	var seen []int64
+	errs := r.PlaceOrders(nil, []repositories.OrderPlacement{
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(3, &seen)},
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(3, &seen)},
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(2, &seen)},
+	})
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		tt.Error("expecting only the second order failed, got", errs)
+	}
+
+	if !reflect.DeepEqual(seen, []int64{5, 2, 2}) {
+		tt.Error("expecting plans seeing stock 5, 2, 2, got", seen)
+	}
+
+	if !committed || txns != 1 {
+		tt.Errorf("expecting one committed transaction, got %d", txns)
+	}
+}
+
+func placeOneByOneWhenGroupFails(tt *testing.T) {
+	committed := false
+	txns := 0
+	takes := 0
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			txns++
+			return placeOrderTx(
+				func() int64 { return 5 },
+				func() error {
+					takes++
+					if takes == 1 {
+						return &inventoryAdjustError{error: fmt.Errorf("dummy error"), productID: 1}
+					}
+					return nil
+				},
+				&committed,
+			), nil
+		},
+	}
+
+	var seen []int64
+	errs := r.PlaceOrders(nil, []repositories.OrderPlacement{
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(1, &seen)},
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(1, &seen)},
+	})
+	if !reflect.DeepEqual(errs, []error{nil, nil}) {
+		tt.Error("expecting no error, got", errs)
+	}
+
+	if txns != 3 {
+		tt.Errorf("expecting the group and 2 orders transactions, got %d", txns)
+	}
+}
+
+func notPlaceAgainWhenAmbiguous(tt *testing.T) {
+	committed := false
+	txns := 0
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			txns++
+			tx := placeOrderTx(func() int64 { return 5 }, func() error { return nil }, &committed)
+			exec := tx.execContext
+			tx.execContext = func(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+				if q == "RELEASE SAVEPOINT cockroach_restart" {
+					return nil, driver.ErrBadConn
+				}
+				return exec(ctx, q, args...)
+			}
+			return tx, nil
+		},
+	}
+
+	var seen []int64
+	errs := r.PlaceOrders(nil, []repositories.OrderPlacement{
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(1, &seen)},
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(1, &seen)},
+	})
+	for _, err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "may or may not be committed") {
+			tt.Error("expecting every order failed by the ambiguous commit, got", err)
+		}
+	}
+
+	if txns != 1 {
+		tt.Errorf("expecting only the group transaction, got %d", txns)
+	}
+}
+
+func notPlaceAgainWhenRetriesExhausted(tt *testing.T) {
+	committed := false
+	txns := 0
+	takes := 0
+	r := &CockroachRepo{
+		Retry: RetryPolicy{MaxRetries: 2, InitialBackoff: time.Microsecond, MaxBackoff: time.Microsecond},
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			txns++
+			return placeOrderTx(
+				func() int64 { return 5 },
+				func() error {
+					takes++
+					return &pq.Error{Code: "40001"}
+				},
+				&committed,
+			), nil
+		},
+	}
+
+	var seen []int64
+	errs := r.PlaceOrders(context.Background(), []repositories.OrderPlacement{
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(1, &seen)},
+		{Query: repositories.OrderQuery{ProductIDs: []int64{1}}, Plan: takeUpTo(1, &seen)},
+	})
+	for _, err := range errs {
+		if _, ok := err.(*retriesExhaustedError); !ok {
+			tt.Errorf("expecting every order failed by retriesExhaustedError, got %T", err)
+		}
+	}
+
+	if txns != 1 || takes != 3 {
+		tt.Errorf("expecting one transaction taking stock 3 times, got %d transactions and %d takes", txns, takes)
+	}
+}
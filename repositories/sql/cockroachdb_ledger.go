@@ -0,0 +1,378 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tomshop/repositories"
+
+	"github.com/cockroachdb/cockroach-go/crdb"
+	"github.com/lib/pq"
+)
+
+// ChangeStock restocks, returns cancelled items or corrects stock of a product, product is created
+// if not in DB. Stock of the warehouse of the change is changed by the same delta so warehouses
+// still add up to the product stock. Restocked and returned items are held for backorders of the product
+// first, backorders of a cancelled order are released. The change is recorded in stock movements and its
+// threshold crossing is claimed in the same transaction. Movements of the change are returned in order,
+// the change first then items of it held for backorders if any
+func (r *CockroachRepo) ChangeStock(ctx context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+	tx, err := r.txnFactory(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, err
+	}
+
+	var movements []repositories.StockMovement
+	err = r.executeInTx(ctx, "ChangeStock", tx, func() error {
+		movements = nil
+		before, err := stockCount(ctx, tx, c.ProductID)
+		if err != nil {
+			return err
+		}
+
+		var held int64
+		if c.Reason == repositories.ReasonRestock || c.Reason == repositories.ReasonCancellation {
+			if held, err = releaseBackorders(ctx, tx, c); err != nil {
+				return err
+			}
+		}
+
+		// held items never reach stock, of the product nor of the warehouse
+		added := c
+		added.Quantity -= held
+		after := before + added.Quantity
+		if c.Reason == repositories.ReasonCorrection {
+			after = c.Quantity
+		}
+
+		if c.WarehouseID != 0 {
+			if after, err = changeWarehouseStock(ctx, tx, added, before); err != nil {
+				return err
+			}
+		}
+
+		if after < 0 {
+			return &inventoryAdjustError{
+				error:     fmt.Errorf("stock of product %d cannot be negative", c.ProductID),
+				productID: c.ProductID,
+			}
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO inventories (id, stock_count, version) VALUES ($1, $2, 0)
+			ON CONFLICT (id) DO UPDATE SET stock_count = excluded.stock_count`,
+			c.ProductID,
+			after,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := crossThresholds(ctx, tx, map[int64]int64{c.ProductID: after}); err != nil {
+			return err
+		}
+
+		movements = append(movements, repositories.StockMovement{
+			ProductID: c.ProductID,
+			Delta:     after + held - before,
+			Reason:    c.Reason,
+			Reference: c.Reference,
+			Actor:     c.Actor,
+			Before:    before,
+			After:     after + held,
+		})
+		if held > 0 {
+			movements = append(movements, repositories.StockMovement{
+				ProductID: c.ProductID,
+				Delta:     -held,
+				Reason:    repositories.ReasonBackordered,
+				Reference: c.Reference,
+				Actor:     c.Actor,
+				Before:    after + held,
+				After:     after,
+			})
+		}
+
+		for i := range movements {
+			if err := recordMovement(ctx, tx, &movements[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// releaseBackorders of a change returning items to stock, backorders of a cancelled order are released
+// first then returned items are held for backorders still open. Returns items held
+func releaseBackorders(ctx context.Context, tx Tx, c repositories.StockChange) (int64, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT backordered_count FROM fulfillment_policies WHERE product_id = $1", c.ProductID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var open int64
+	if rows.Next() {
+		if err := rows.Scan(&open); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if c.Backordered > open {
+		return 0, &inventoryAdjustError{
+			error:     fmt.Errorf("cannot release %d backordered items of product %d, %d are open", c.Backordered, c.ProductID, open),
+			productID: c.ProductID,
+		}
+	}
+
+	held := open - c.Backordered
+	if held > c.Quantity {
+		held = c.Quantity
+	}
+
+	if held == 0 && c.Backordered == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE fulfillment_policies SET backordered_count = $2 WHERE product_id = $1",
+		c.ProductID,
+		open-c.Backordered-held,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return held, nil
+}
+
+// recordMovement in the ledger and tells it by an outbox event, ID and CreatedAt of m are set
+func recordMovement(ctx context.Context, tx Tx, m *repositories.StockMovement) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		`INSERT INTO stock_movements (product_id, delta, reason, reference, actor, before_count, after_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		m.ProductID,
+		m.Delta,
+		m.Reason,
+		m.Reference,
+		m.Actor,
+		m.Before,
+		m.After,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&m.ID, &m.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return writeStockChangedEvent(ctx, tx, repositories.StockChangedPayload{
+		ProductID: m.ProductID,
+		Delta:     m.Delta,
+		Reason:    m.Reason,
+		Reference: m.Reference,
+		Actor:     m.Actor,
+	})
+}
+
+// changeWarehouseStock of c, returns the product stock after it
+func changeWarehouseStock(ctx context.Context, tx Tx, c repositories.StockChange, before int64) (int64, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT stock_count FROM warehouse_stocks WHERE product_id = $1 AND warehouse_id = $2",
+		c.ProductID,
+		c.WarehouseID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var warehouseBefore int64
+	if rows.Next() {
+		if err := rows.Scan(&warehouseBefore); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	warehouseAfter := warehouseBefore + c.Quantity
+	if c.Reason == repositories.ReasonCorrection {
+		warehouseAfter = c.Quantity
+	}
+
+	if warehouseAfter < 0 {
+		return 0, &inventoryAdjustError{
+			error:     fmt.Errorf("stock of product %d in warehouse %d cannot be negative", c.ProductID, c.WarehouseID),
+			productID: c.ProductID,
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO warehouse_stocks (product_id, warehouse_id, stock_count) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, warehouse_id) DO UPDATE SET stock_count = excluded.stock_count`,
+		c.ProductID,
+		c.WarehouseID,
+		warehouseAfter,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return before + warehouseAfter - warehouseBefore, nil
+}
+
+// ListStockMovements of a product, newest first
+func (r *CockroachRepo) ListStockMovements(
+	ctx context.Context,
+	productID int64,
+	limit int,
+	opts ...repositories.ReadOption,
+) ([]repositories.StockMovement, error) {
+	q, asOf := r.reader(opts)
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT id, product_id, delta, reason, reference, actor, before_count, after_count, created_at
+		FROM stock_movements`+asOf+` WHERE product_id = $1 ORDER BY id DESC LIMIT $2`,
+		productID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []repositories.StockMovement{}
+	for rows.Next() {
+		m := repositories.StockMovement{}
+		if err := rows.Scan(
+			&m.ID,
+			&m.ProductID,
+			&m.Delta,
+			&m.Reason,
+			&m.Reference,
+			&m.Actor,
+			&m.Before,
+			&m.After,
+			&m.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ReconcileStock recomputes stock of every product from the ledger, returns only products drifted
+func (r *CockroachRepo) ReconcileStock(ctx context.Context, opts ...repositories.ReadOption) ([]repositories.StockDrift, error) {
+	q, asOf := r.reader(opts)
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT i.id, COALESCE(i.stock_count, 0), COALESCE(SUM(m.delta), 0)
+		FROM inventories i LEFT JOIN stock_movements m ON m.product_id = i.id`+asOf+`
+		GROUP BY i.id, i.stock_count
+		HAVING COALESCE(i.stock_count, 0) <> COALESCE(SUM(m.delta), 0)
+		ORDER BY i.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []repositories.StockDrift{}
+	for rows.Next() {
+		d := repositories.StockDrift{}
+		if err := rows.Scan(&d.ProductID, &d.StockCount, &d.LedgerCount); err != nil {
+			return nil, err
+		}
+		results = append(results, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// recordOrderMovementsStmt records movements of products in one round trip, taken quantities are looked up
+// by array_position like takeStockStmt
+const recordOrderMovementsStmt = `INSERT INTO stock_movements (product_id, delta, reason, reference, actor, before_count, after_count)
+	SELECT id, -($2::INT[])[array_position($1::INT[], id)], $3::STRING, $4::STRING, $5::STRING,
+	stock_count + ($2::INT[])[array_position($1::INT[], id)], stock_count
+	FROM inventories WHERE id = ANY ($1)`
+
+// recordOrderMovements after stock taken for orders, one movement per product
+func recordOrderMovements(
+	ctx context.Context,
+	tx crdb.Tx,
+	orders []repositories.Order,
+	options repositories.AdjustOptions,
+) error {
+	ids := []int64{}
+	qtys := []int64{}
+	index := map[int64]int{}
+	for _, o := range orders {
+		if o.Quantity <= 0 {
+			continue
+		}
+
+		i, ok := index[o.ProductID]
+		if !ok {
+			i = len(ids)
+			index[o.ProductID] = i
+			ids = append(ids, o.ProductID)
+			qtys = append(qtys, 0)
+		}
+		qtys[i] += o.Quantity
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		recordOrderMovementsStmt,
+		pq.Array(ids),
+		pq.Array(qtys),
+		repositories.ReasonOrder,
+		options.Reference,
+		options.Actor,
+	)
+	return err
+}
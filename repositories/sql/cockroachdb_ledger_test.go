@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+func TestCockroachRepo_ChangeStock(t *testing.T) {
+	// product 1 has 10 items, 3 of them in warehouse 2, open of them are backordered
+	changeTx := func(open int64, saved map[string]int64) mockTx {
+		return mockTx{
+			commit:   func() error { return nil },
+			rollback: func() error { return nil },
+			execContext: func(_ context.Context, q string, args ...interface{}) (sql.Result, error) {
+				values := args[0].([]interface{})
+				switch {
+				case strings.HasPrefix(q, "INSERT INTO inventories"):
+					saved["product"] = values[1].(int64)
+				case strings.HasPrefix(q, "INSERT INTO warehouse_stocks"):
+					saved["warehouse"] = values[2].(int64)
+				case strings.HasPrefix(q, "UPDATE fulfillment_policies"):
+					saved["backordered"] = values[1].(int64)
+				}
+				return mockSQLResult{}, nil
+			},
+			queryContext: func(_ context.Context, q string, _ ...interface{}) (*sql.Rows, error) {
+				switch {
+				case q == crossThresholdsStmt:
+					return mockRows(nil), nil
+				case strings.Contains(q, "FROM inventories"):
+					return mockRows([]string{"stock_count"}, []driver.Value{int64(10)}), nil
+				case strings.Contains(q, "FROM warehouse_stocks"):
+					return mockRows([]string{"stock_count"}, []driver.Value{int64(3)}), nil
+				case strings.Contains(q, "FROM fulfillment_policies"):
+					return mockRows([]string{"backordered_count"}, []driver.Value{open}), nil
+				}
+				return mockRows([]string{"id", "created_at"}, []driver.Value{int64(1), time.Now()}), nil
+			},
+		}
+	}
+
+	cases := []struct {
+		name      string
+		change    repositories.StockChange
+		open      int64
+		product   int64
+		warehouse int64
+		// backordered left, -1 for not updated
+		backordered int64
+		movements   []repositories.StockMovement
+	}{
+		{
+			name:        "restock added to the warehouse",
+			change:      repositories.StockChange{ProductID: 1, Quantity: 5, Reason: repositories.ReasonRestock, WarehouseID: 2},
+			product:     15,
+			warehouse:   8,
+			backordered: -1,
+			movements: []repositories.StockMovement{
+				{ProductID: 1, Delta: 5, Reason: repositories.ReasonRestock, Before: 10, After: 15},
+			},
+		},
+		{
+			name:        "correction sets the warehouse",
+			change:      repositories.StockChange{ProductID: 1, Quantity: 1, Reason: repositories.ReasonCorrection, WarehouseID: 2},
+			open:        4,
+			product:     8,
+			warehouse:   1,
+			backordered: -1,
+			movements: []repositories.StockMovement{
+				{ProductID: 1, Delta: -2, Reason: repositories.ReasonCorrection, Before: 10, After: 8},
+			},
+		},
+		{
+			name:        "correction without warehouse sets the product",
+			change:      repositories.StockChange{ProductID: 1, Quantity: 1, Reason: repositories.ReasonCorrection},
+			product:     1,
+			backordered: -1,
+			movements: []repositories.StockMovement{
+				{ProductID: 1, Delta: -9, Reason: repositories.ReasonCorrection, Before: 10, After: 1},
+			},
+		},
+		{
+			name:        "restock held for backorders first",
+			change:      repositories.StockChange{ProductID: 1, Quantity: 5, Reason: repositories.ReasonRestock, WarehouseID: 2},
+			open:        2,
+			product:     13,
+			warehouse:   6,
+			backordered: 0,
+			movements: []repositories.StockMovement{
+				{ProductID: 1, Delta: 5, Reason: repositories.ReasonRestock, Before: 10, After: 15},
+				{ProductID: 1, Delta: -2, Reason: repositories.ReasonBackordered, Before: 15, After: 13},
+			},
+		},
+		{
+			name:        "restock all held when backorders exceed it",
+			change:      repositories.StockChange{ProductID: 1, Quantity: 5, Reason: repositories.ReasonRestock},
+			open:        7,
+			product:     10,
+			backordered: 2,
+			movements: []repositories.StockMovement{
+				{ProductID: 1, Delta: 5, Reason: repositories.ReasonRestock, Before: 10, After: 15},
+				{ProductID: 1, Delta: -5, Reason: repositories.ReasonBackordered, Before: 15, After: 10},
+			},
+		},
+		{
+			name: "cancellation releases its backorders then returned items are held for others",
+			change: repositories.StockChange{
+				ProductID:   1,
+				Quantity:    2,
+				Reason:      repositories.ReasonCancellation,
+				Backordered: 3,
+			},
+			open:        4,
+			product:     11,
+			backordered: 0,
+			movements: []repositories.StockMovement{
+				{ProductID: 1, Delta: 2, Reason: repositories.ReasonCancellation, Before: 10, After: 12},
+				{ProductID: 1, Delta: -1, Reason: repositories.ReasonBackordered, Before: 12, After: 11},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		saved := map[string]int64{"backordered": -1}
+		r := &CockroachRepo{
+			txnFactory: func(context.Context, *sql.TxOptions) (Tx, error) {
+				return changeTx(c.open, saved), nil
+			},
+		}
+
+		movements, err := r.ChangeStock(context.Background(), c.change)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+
+		if saved["product"] != c.product || saved["warehouse"] != c.warehouse || saved["backordered"] != c.backordered {
+			t.Errorf("%s: expecting product %d warehouse %d backordered %d, got %v",
+				c.name, c.product, c.warehouse, c.backordered, saved)
+		}
+
+		for i := range movements {
+			movements[i].ID, movements[i].CreatedAt = 0, time.Time{}
+		}
+		if !reflect.DeepEqual(movements, c.movements) {
+			t.Errorf("%s: expecting movements %+v, got %+v", c.name, c.movements, movements)
+		}
+	}
+
+	t.Run("must fail releasing more than backordered", func(tt *testing.T) {
+		r := &CockroachRepo{
+			txnFactory: func(context.Context, *sql.TxOptions) (Tx, error) {
+				return changeTx(2, map[string]int64{}), nil
+			},
+		}
+
+		_, err := r.ChangeStock(context.Background(), repositories.StockChange{
+			ProductID:   1,
+			Reason:      repositories.ReasonCancellation,
+			Backordered: 3,
+		})
+		if _, ok := err.(repositories.InventoryQuantityUpdateError); !ok {
+			tt.Error("expecting InventoryQuantityUpdateError, got", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"tomshop/repositories"
+
+	"github.com/cockroachdb/cockroach-go/crdb"
+	"github.com/lib/pq"
+)
+
+// ListUnpublishedEvents ordered by aggregate then Seq, so a batch always starts with
+// the oldest unpublished event of every aggregate it has. Events of excluded aggregates aren't listed
+func (r *CockroachRepo) ListUnpublishedEvents(
+	ctx context.Context,
+	limit int,
+	excluded []repositories.Aggregate,
+) ([]repositories.OutboxEvent, error) {
+	// excluded on one key, types never have a slash so keys of different aggregates never clash
+	keys := make([]string, len(excluded))
+	for i, a := range excluded {
+		keys[i] = a.Type + "/" + a.ID
+	}
+
+	rows, err := r.querier.QueryContext(
+		ctx,
+		`SELECT id, aggregate_type, aggregate_id, seq, event_type, payload::STRING, created_at
+		FROM outbox WHERE published_at IS NULL
+		AND NOT (aggregate_type || '/' || aggregate_id = ANY ($2::STRING[]))
+		ORDER BY aggregate_type, aggregate_id, seq LIMIT $1`,
+		limit,
+		pq.Array(keys),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []repositories.OutboxEvent{}
+	for rows.Next() {
+		e := repositories.OutboxEvent{}
+		var payload string
+		if err := rows.Scan(
+			&e.ID,
+			&e.AggregateType,
+			&e.AggregateID,
+			&e.Seq,
+			&e.Type,
+			&payload,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		results = append(results, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// MarkEventsPublished so they won't be listed again
+func (r *CockroachRepo) MarkEventsPublished(ctx context.Context, IDs []int64) error {
+	if len(IDs) == 0 {
+		return nil
+	}
+
+	tx, err := r.txnFactory(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.executeInTx(ctx, "MarkEventsPublished", tx, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE outbox SET published_at = now() WHERE id = ANY ($1)", pq.Array(IDs))
+		return err
+	})
+}
+
+// DeletePublishedEvents published before t, returns number of deleted events
+func (r *CockroachRepo) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := r.txnFactory(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = r.executeInTx(ctx, "DeletePublishedEvents", tx, func() error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM outbox WHERE published_at < $1", before)
+		if err != nil {
+			return err
+		}
+
+		n, err = result.RowsAffected()
+		return err
+	})
+
+	return n, err
+}
+
+// writeOrderEvents tells an order was placed and stock of its products changed
+func writeOrderEvents(
+	ctx context.Context,
+	tx crdb.Tx,
+	orders []repositories.Order,
+	options repositories.AdjustOptions,
+) error {
+	if options.Reference != "" {
+		codes := make([]string, len(options.Redemptions))
+		for i, rd := range options.Redemptions {
+			codes[i] = rd.Code
+		}
+
+		err := writeEvent(ctx, tx, repositories.AggregateOrder, options.Reference, repositories.EventOrderPlaced,
+			repositories.OrderPlacedPayload{
+				OrderID:     options.Reference,
+				Actor:       options.Actor,
+				Lines:       orders,
+				CouponCodes: codes,
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, o := range orders {
+		if o.Quantity <= 0 {
+			continue
+		}
+
+		err := writeStockChangedEvent(ctx, tx, repositories.StockChangedPayload{
+			ProductID: o.ProductID,
+			Delta:     -o.Quantity,
+			Reason:    repositories.ReasonOrder,
+			Reference: options.Reference,
+			Actor:     options.Actor,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeStockChangedEvent(ctx context.Context, tx crdb.Tx, payload repositories.StockChangedPayload) error {
+	return writeEvent(
+		ctx,
+		tx,
+		repositories.AggregateProduct,
+		strconv.FormatInt(payload.ProductID, 10),
+		repositories.EventStockChanged,
+		payload,
+	)
+}
+
+// writeLowStockEvents tells products have just crossed below their threshold
+func writeLowStockEvents(ctx context.Context, tx crdb.Tx, crossed []repositories.LowStock) error {
+	for _, l := range crossed {
+		err := writeEvent(
+			ctx,
+			tx,
+			repositories.AggregateProduct,
+			strconv.FormatInt(l.ProductID, 10),
+			repositories.EventStockLow,
+			l,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeEvent takes the next Seq of the aggregate, concurrent transactions writing events of the same
+// aggregate conflict on its sequence so Seq order is also commit order
+func writeEvent(
+	ctx context.Context,
+	tx crdb.Tx,
+	aggregateType string,
+	aggregateID string,
+	eventType string,
+	payload interface{},
+) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO outbox_sequences (aggregate_type, aggregate_id, seq) VALUES ($1, $2, 1)
+		ON CONFLICT (aggregate_type, aggregate_id) DO UPDATE SET seq = outbox_sequences.seq + 1`,
+		aggregateType,
+		aggregateID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO outbox (aggregate_type, aggregate_id, seq, event_type, payload)
+		SELECT $1, $2, seq, $3, $4 FROM outbox_sequences WHERE aggregate_type = $1 AND aggregate_id = $2`,
+		aggregateType,
+		aggregateID,
+		eventType,
+		string(data),
+	)
+
+	return err
+}
@@ -0,0 +1,124 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+func TestCockroachRepo_PlaceOrder(t *testing.T) {
+	t.Run("must Rollback and return plan error as is", rollBackWhenPlanFails)
+	t.Run("must plan again with new snapshot when transaction is retried", planAgainWhenRetried)
+}
+
+// placeOrderTx serves snapshot reads with stock from stocks, takeStock fails with takeStockErr if set
+func placeOrderTx(stocks func() int64, takeStockErr func() error, committed *bool) mockTx {
+	return mockTx{
+		commit: func() error {
+			*committed = true
+			return nil
+		},
+		rollback: func() error {
+			return nil
+		},
+		execContext: func(context.Context, string, ...interface{}) (sql.Result, error) {
+			return mockSQLResult{
+				rowsAffected: func() (int64, error) {
+					return 1, nil
+				},
+			}, nil
+		},
+		queryContext: func(_ context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+			switch {
+			case strings.HasPrefix(q, "SELECT id, stock_count, price FROM inventories"):
+				return mockRows(
+					[]string{"id", "stock_count", "price"},
+					[]driver.Value{int64(1), stocks(), int64(10)},
+				), nil
+			case q == takeStockStmt:
+				if err := takeStockErr(); err != nil {
+					return nil, err
+				}
+				return mockTakeStockRows(args), nil
+			default:
+				return mockRows(nil), nil
+			}
+		},
+	}
+}
+
+func rollBackWhenPlanFails(tt *testing.T) {
+	dummyErr := fmt.Errorf("dummy plan error")
+	committed := false
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return placeOrderTx(
+				func() int64 { return 5 },
+				func() error {
+					tt.Error("unexpected takeStock")
+					return nil
+				},
+				&committed,
+			), nil
+		},
+	}
+
+	err := r.PlaceOrder(nil, repositories.OrderQuery{ProductIDs: []int64{1}}, func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		return nil, nil, dummyErr
+	})
+	if err != dummyErr {
+		tt.Error("expecting dummyErr, got", err)
+	}
+
+	if committed {
+		tt.Error("unexpected commit")
+	}
+}
+
+func planAgainWhenRetried(tt *testing.T) {
+	stock := int64(5)
+	attempts := 0
+	committed := false
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return placeOrderTx(
+				func() int64 { return stock },
+				func() error {
+					attempts++
+					if attempts == 1 {
+						// another order took stock in the meantime
+						stock = 2
+						return &pq.Error{Code: "40001"}
+					}
+					return nil
+				},
+				&committed,
+			), nil
+		},
+	}
+
+	var planned []int64
+	err := r.PlaceOrder(context.Background(), repositories.OrderQuery{ProductIDs: []int64{1}}, func(s repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		planned = append(planned, s.Inventories[0].StockCount)
+		return []repositories.Order{{ProductID: 1, Quantity: s.Inventories[0].StockCount}}, nil, nil
+	})
+	if err != nil {
+		tt.Error("unexpected error", err)
+	}
+
+	if !reflect.DeepEqual(planned, []int64{5, 2}) {
+		tt.Error("expecting planned twice with stock 5 then 2, got", planned)
+	}
+
+	if !committed {
+		tt.Error("expecting commit")
+	}
+}
@@ -0,0 +1,184 @@
+package sql
+
+import (
+	"context"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+// SetStockThreshold of a product, threshold 0 removes it
+func (r *CockroachRepo) SetStockThreshold(ctx context.Context, productID, threshold int64) error {
+	tx, err := r.txnFactory(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.executeInTx(ctx, "SetStockThreshold", tx, func() error {
+		if threshold == 0 {
+			_, err := tx.ExecContext(ctx, "DELETE FROM stock_thresholds WHERE product_id = $1", productID)
+			return err
+		}
+
+		// a product already below the new threshold is alerted by the next check
+		_, err := tx.ExecContext(
+			ctx,
+			"UPSERT INTO stock_thresholds (product_id, threshold, below) VALUES ($1, $2, false)",
+			productID,
+			threshold,
+		)
+		return err
+	})
+}
+
+// ListLowStock lists every product currently below its threshold
+func (r *CockroachRepo) ListLowStock(ctx context.Context, opts ...repositories.ReadOption) ([]repositories.LowStock, error) {
+	q, asOf := r.reader(opts)
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT t.product_id, t.threshold, COALESCE(i.stock_count, 0)
+		FROM stock_thresholds t LEFT JOIN inventories i ON i.id = t.product_id`+asOf+`
+		WHERE COALESCE(i.stock_count, 0) < t.threshold
+		ORDER BY t.product_id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []repositories.LowStock{}
+	for rows.Next() {
+		l := repositories.LowStock{}
+		if err := rows.Scan(&l.ProductID, &l.Threshold, &l.StockCount); err != nil {
+			return nil, err
+		}
+		results = append(results, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// crossThresholdsStmt flips below of products whose new stock is on the other side of their threshold,
+// new stock is looked up by array_position as CockroachDB 2.1 has no UPDATE ... FROM
+const crossThresholdsStmt = `UPDATE stock_thresholds SET below = NOT below
+	WHERE product_id = ANY ($1) AND below != (($2::INT[])[array_position($1::INT[], product_id)] < threshold)
+	RETURNING product_id, threshold, below`
+
+// crossThresholds of products given their new stock, in the transaction changing it so only one of concurrent
+// changes claims a crossing. Products have just crossed below their threshold are told by an outbox event of
+// the same transaction, products back to or above threshold are rearmed for the next crossing
+func crossThresholds(ctx context.Context, tx Tx, stocks map[int64]int64) error {
+	if len(stocks) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(stocks))
+	counts := make([]int64, 0, len(stocks))
+	for id, count := range stocks {
+		ids = append(ids, id)
+		counts = append(counts, count)
+	}
+
+	rows, err := tx.QueryContext(ctx, crossThresholdsStmt, pq.Array(ids), pq.Array(counts))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var crossed []repositories.LowStock
+	for rows.Next() {
+		l := repositories.LowStock{}
+		var below bool
+		if err := rows.Scan(&l.ProductID, &l.Threshold, &below); err != nil {
+			return err
+		}
+
+		if below {
+			l.StockCount = stocks[l.ProductID]
+			crossed = append(crossed, l)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return writeLowStockEvents(ctx, tx, crossed)
+}
+
+// CheckStockThresholds of products, returns only products have just crossed below their threshold, they are
+// told by outbox events of the same transaction. Products back to or above threshold are rearmed for the next crossing
+func (r *CockroachRepo) CheckStockThresholds(ctx context.Context, IDs []int64) ([]repositories.LowStock, error) {
+	if len(IDs) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.txnFactory(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var crossed []repositories.LowStock
+	err = r.executeInTx(ctx, "CheckStockThresholds", tx, func() error {
+		crossed = nil
+		rows, err := tx.QueryContext(
+			ctx,
+			`SELECT t.product_id, t.threshold, COALESCE(i.stock_count, 0), t.below
+			FROM stock_thresholds t LEFT JOIN inventories i ON i.id = t.product_id
+			WHERE t.product_id = ANY ($1)`,
+			pq.Array(IDs),
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var flipped []int64
+		for rows.Next() {
+			l := repositories.LowStock{}
+			var below bool
+			if err := rows.Scan(&l.ProductID, &l.Threshold, &l.StockCount, &below); err != nil {
+				return err
+			}
+
+			if isBelow := l.StockCount < l.Threshold; isBelow != below {
+				flipped = append(flipped, l.ProductID)
+				if isBelow {
+					crossed = append(crossed, l)
+				}
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		if len(flipped) == 0 {
+			return nil
+		}
+
+		// serializable transaction makes sure only one of concurrent checks flips a product
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE stock_thresholds SET below = NOT below WHERE product_id = ANY ($1)",
+			pq.Array(flipped),
+		)
+		if err != nil {
+			return err
+		}
+
+		return writeLowStockEvents(ctx, tx, crossed)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return crossed, nil
+}
@@ -0,0 +1,154 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+func TestCockroachRepo_SetStockThreshold(t *testing.T) {
+	t.Run("must delete threshold when setting 0", deleteWhenThresholdZero)
+	t.Run("must upsert rearmed threshold", upsertRearmedThreshold)
+}
+
+func setThresholdQuery(tt *testing.T, threshold int64) (string, []interface{}) {
+	var query string
+	var args []interface{}
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					return nil
+				},
+				rollback: func() error {
+					tt.Error("unexpected rollback")
+					return nil
+				},
+				execContext: func(c context.Context, q string, a ...interface{}) (sql.Result, error) {
+					if !strings.HasPrefix(q, "SAVEPOINT") && !strings.HasPrefix(q, "RELEASE") {
+						query, args = q, a[0].([]interface{})
+					}
+					return mockSQLResult{}, nil
+				},
+			}, nil
+		},
+	}
+
+	if err := r.SetStockThreshold(nil, 1, threshold); err != nil {
+		tt.Error("unexpected error", err)
+	}
+
+	return query, args
+}
+
+func deleteWhenThresholdZero(tt *testing.T) {
+	query, args := setThresholdQuery(tt, 0)
+	if query != "DELETE FROM stock_thresholds WHERE product_id = $1" || !reflect.DeepEqual(args, []interface{}{int64(1)}) {
+		tt.Error("expecting threshold deleted, got", query, args)
+	}
+}
+
+func upsertRearmedThreshold(tt *testing.T) {
+	query, args := setThresholdQuery(tt, 5)
+	if !strings.HasPrefix(query, "UPSERT INTO stock_thresholds") || !reflect.DeepEqual(args, []interface{}{int64(1), int64(5)}) {
+		tt.Error("expecting threshold upserted, got", query, args)
+	}
+}
+
+func TestCockroachRepo_CheckStockThresholds(t *testing.T) {
+	dummyErr := fmt.Errorf("dummy queryContext error")
+	rollbackCalled := 0
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			return mockTx{
+				commit: func() error {
+					t.Error("unexpected commit")
+					return nil
+				},
+				rollback: func() error {
+					rollbackCalled++
+					return nil
+				},
+				execContext: func(c context.Context, q string, args ...interface{}) (sql.Result, error) {
+					if strings.HasPrefix(q, "UPDATE") {
+						t.Error("unexpected update", q)
+					}
+					return mockSQLResult{}, nil
+				},
+				queryContext: func(c context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+					return nil, dummyErr
+				},
+			}, nil
+		},
+	}
+
+	crossed, err := r.CheckStockThresholds(nil, []int64{1, 2})
+	if !reflect.DeepEqual(err, dummyErr) {
+		t.Error("expecting dummyErr, got", err)
+	}
+
+	if crossed != nil || rollbackCalled != 1 {
+		t.Error("expecting nothing crossed and rollback, got", crossed, rollbackCalled)
+	}
+}
+
+func TestCockroachRepo_PlaceOrder_lowStock(t *testing.T) {
+	committed := false
+	var crossArgs []interface{}
+	var events []string
+	r := &CockroachRepo{
+		txnFactory: func(c context.Context, opts *sql.TxOptions) (Tx, error) {
+			tx := placeOrderTx(func() int64 { return 5 }, func() error { return nil }, &committed)
+			tx.execContext = func(_ context.Context, q string, args ...interface{}) (sql.Result, error) {
+				a := args[0].([]interface{})
+				if strings.HasPrefix(q, "INSERT INTO outbox (") && a[2] == repositories.EventStockLow {
+					if committed {
+						t.Error("expecting low stock event written before commit")
+					}
+					events = append(events, fmt.Sprintf("%s/%s %s", a[0], a[1], a[3]))
+				}
+				return mockSQLResult{}, nil
+			}
+			query := tx.queryContext
+			tx.queryContext = func(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+				if q != crossThresholdsStmt {
+					return query(ctx, q, args...)
+				}
+
+				crossArgs = args
+				// product 1 crossed below, product 2 rearmed
+				return mockRows(
+					[]string{"product_id", "threshold", "below"},
+					[]driver.Value{int64(1), int64(5), true},
+					[]driver.Value{int64(2), int64(3), false},
+				), nil
+			}
+			return tx, nil
+		},
+	}
+
+	err := r.PlaceOrder(nil, repositories.OrderQuery{ProductIDs: []int64{1}}, func(repositories.OrderSnapshot) ([]repositories.Order, []repositories.AdjustOption, error) {
+		return []repositories.Order{{ProductID: 1, Quantity: 2}}, nil, nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	// mockTakeStockRows leaves every product without stock
+	if !reflect.DeepEqual(crossArgs, []interface{}{pq.Array([]int64{1}), pq.Array([]int64{0})}) {
+		t.Error("expecting thresholds crossed with stock after the order, got", crossArgs)
+	}
+
+	expected := []string{`product/1 {"productID":1,"threshold":5,"stockCount":0}`}
+	if !reflect.DeepEqual(events, expected) {
+		t.Error("expecting low stock event of product 1 only, got", events)
+	}
+}
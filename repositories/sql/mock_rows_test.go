@@ -0,0 +1,119 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+
+	"github.com/lib/pq"
+)
+
+// mockRows builds *sql.Rows of given columns and values, sql.Rows cannot be created without a driver
+func mockRows(columns []string, values ...[]driver.Value) *sql.Rows {
+	db := sql.OpenDB(mockConnector{columns: columns, values: values})
+	rows, err := db.QueryContext(context.Background(), "mock")
+	if err != nil {
+		panic(err)
+	}
+
+	return rows
+}
+
+// mockTakeStockRows returns every product of takeStockStmt as updated
+func mockTakeStockRows(args []interface{}) *sql.Rows {
+	ids := *args[0].(*pq.Int64Array)
+	values := make([][]driver.Value, len(ids))
+	for i, id := range ids {
+		values[i] = []driver.Value{id, int64(0)}
+	}
+
+	return mockRows([]string{"id", "stock_count"}, values...)
+}
+
+// mockUpdatedRows returns every row of a set-based statement of adjust as updated, nil for other statements
+func mockUpdatedRows(query string, args []interface{}) *sql.Rows {
+	switch query {
+	case takeStockStmt:
+		return mockTakeStockRows(args)
+	case crossThresholdsStmt:
+		return mockRows(nil)
+	case takeWarehouseStockStmt:
+		var values [][]driver.Value
+		for _, key := range *args[1].(*pq.StringArray) {
+			var productID, warehouseID int64
+			fmt.Sscanf(key, "%d:%d", &productID, &warehouseID)
+			values = append(values, []driver.Value{productID, warehouseID})
+		}
+		return mockRows([]string{"product_id", "warehouse_id"}, values...)
+	case reserveBackordersStmt:
+		var values [][]driver.Value
+		for _, id := range *args[0].(*pq.Int64Array) {
+			values = append(values, []driver.Value{id})
+		}
+		return mockRows([]string{"product_id"}, values...)
+	case redeemStmt, redeemByCustomerStmt:
+		var values [][]driver.Value
+		for _, code := range *args[0].(*pq.StringArray) {
+			values = append(values, []driver.Value{code})
+		}
+		return mockRows([]string{"code"}, values...)
+	}
+
+	return nil
+}
+
+type mockConnector struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (c mockConnector) Connect(context.Context) (driver.Conn, error) {
+	return mockConn(c), nil
+}
+
+func (c mockConnector) Driver() driver.Driver {
+	return nil
+}
+
+type mockConn mockConnector
+
+func (c mockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (c mockConn) Close() error {
+	return nil
+}
+
+func (c mockConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (c mockConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &mockDriverRows{columns: c.columns, values: c.values}, nil
+}
+
+type mockDriverRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *mockDriverRows) Columns() []string {
+	return r.columns
+}
+
+func (r *mockDriverRows) Close() error {
+	return nil
+}
+
+func (r *mockDriverRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,70 @@
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"tomshop/repositories"
+)
+
+const defaultStaleness = 10 * time.Second
+
+// ReadPolicy tells how reads allowing staleness are served.
+//
+// Only policies of the CockroachDB v2.1.6 pinned by docker-compose.yml are offered: follower reads need 19.1+
+// with an enterprise license and bounded staleness reads need 21.2+, so ReadExactStaleness serves stale reads,
+// they are as stale as configured rather than as fresh as the nearest replica allows
+type ReadPolicy int
+
+const (
+	// ReadStrong serves every read by the leaseholder
+	ReadStrong ReadPolicy = iota
+	// ReadExactStaleness reads data as of exactly Staleness ago, which any replica can serve
+	// once the closed timestamp passed it
+	ReadExactStaleness
+)
+
+// ParseReadPolicy of "strong" or "exact", empty is "strong"
+func ParseReadPolicy(s string) (ReadPolicy, error) {
+	switch s {
+	case "", "strong":
+		return ReadStrong, nil
+	case "exact":
+		return ReadExactStaleness, nil
+	}
+
+	return ReadStrong, fmt.Errorf("unknown read policy %q", s)
+}
+
+// ReadRouting of reads allowing staleness, reads in transactions and writes are always strong
+type ReadRouting struct {
+	Policy ReadPolicy
+	// Staleness of ReadExactStaleness, default 10s
+	Staleness time.Duration
+	// Replica serves stale reads if set, e.g. a connection to a read only DSN. ReadStrong never uses it
+	Replica Querier
+}
+
+// reader of a read with opts, asOf goes after the FROM clause of its query
+func (r *CockroachRepo) reader(opts []repositories.ReadOption) (q Querier, asOf string) {
+	options := repositories.ReadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if !options.AllowStale || r.Reads.Policy == ReadStrong {
+		return r.querier, ""
+	}
+
+	q = r.querier
+	if r.Reads.Replica != nil {
+		q = r.Reads.Replica
+	}
+
+	staleness := r.Reads.Staleness
+	if staleness <= 0 {
+		staleness = defaultStaleness
+	}
+
+	return q, fmt.Sprintf(" AS OF SYSTEM TIME '-%dms'", staleness/time.Millisecond)
+}
@@ -0,0 +1,82 @@
+package sql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+func TestCockroachRepo_ListInventories_routed(t *testing.T) {
+	ids := []int64{1, 2, 3}
+	strongQuery := "SELECT id, stock_count, price FROM inventories WHERE id = ANY ($1)"
+	args := []interface{}{pq.Array(ids)}
+
+	tests := []struct {
+		name    string
+		reads   ReadRouting
+		opts    []repositories.ReadOption
+		replica bool
+		query   string
+	}{
+		{
+			name:  "must read strong without AllowStale",
+			reads: ReadRouting{Policy: ReadExactStaleness},
+			query: strongQuery,
+		},
+		{
+			name:  "must read strong with ReadStrong",
+			reads: ReadRouting{Policy: ReadStrong},
+			opts:  []repositories.ReadOption{repositories.AllowStale()},
+			query: strongQuery,
+		},
+		{
+			name:  "must read as of Staleness ago with ReadExactStaleness",
+			reads: ReadRouting{Policy: ReadExactStaleness, Staleness: 5 * time.Second},
+			opts:  []repositories.ReadOption{repositories.AllowStale()},
+			query: "SELECT id, stock_count, price FROM inventories AS OF SYSTEM TIME '-5000ms' WHERE id = ANY ($1)",
+		},
+		{
+			name:    "must read from replica as of the default staleness with ReadExactStaleness",
+			reads:   ReadRouting{Policy: ReadExactStaleness},
+			opts:    []repositories.ReadOption{repositories.AllowStale()},
+			replica: true,
+			query:   "SELECT id, stock_count, price FROM inventories AS OF SYSTEM TIME '-10000ms' WHERE id = ANY ($1)",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(tt *testing.T) {
+			r := &CockroachRepo{
+				Reads:   test.reads,
+				querier: mockQuerier{t: tt, expectingQuery: test.query, expectingArgs: args},
+			}
+			if test.replica {
+				r.querier = mockQuerier{t: tt, expectingQuery: "unexpected read from primary"}
+				r.Reads.Replica = mockQuerier{t: tt, expectingQuery: test.query, expectingArgs: args}
+			}
+
+			if _, err := r.ListInventories(context.Background(), ids, test.opts...); err == nil || err.Error() != "dummyError" {
+				tt.Error("expecting dummyError, got", err)
+			}
+		})
+	}
+}
+
+func TestParseReadPolicy(t *testing.T) {
+	for s, want := range map[string]ReadPolicy{"": ReadStrong, "strong": ReadStrong, "exact": ReadExactStaleness} {
+		if got, err := ParseReadPolicy(s); err != nil || got != want {
+			t.Errorf("expecting %d for %q, got %d %v", want, s, got, err)
+		}
+	}
+
+	for _, s := range []string{"eventual", "follower", "bounded"} {
+		if _, err := ParseReadPolicy(s); err == nil {
+			t.Errorf("expecting error of unsupported policy %q", s)
+		}
+	}
+}
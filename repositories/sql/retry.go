@@ -0,0 +1,142 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"tomshop/ctxlog"
+	"tomshop/metrics"
+
+	"github.com/cockroachdb/cockroach-go/crdb"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultMaxRetries     = 10
+	defaultInitialBackoff = 10 * time.Millisecond
+	defaultMaxBackoff     = time.Second
+)
+
+// RetryPolicy bounds retries of transactions failed by contention, zero fields use defaults
+type RetryPolicy struct {
+	// MaxRetries after the first attempt, default 10, negative for never retrying
+	MaxRetries int
+	// InitialBackoff before the first retry, doubled by every retry, default 10ms
+	InitialBackoff time.Duration
+	// MaxBackoff between retries, default 1s
+	MaxBackoff time.Duration
+}
+
+func (p RetryPolicy) maxRetries() int {
+	switch {
+	case p.MaxRetries < 0:
+		return 0
+	case p.MaxRetries == 0:
+		return defaultMaxRetries
+	}
+
+	return p.MaxRetries
+}
+
+// backoff before retry, starting at 1, with full jitter so retries of conflicting transactions spread out
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	initial, max := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	ceiling := max
+	if retry < 32 && initial<<uint(retry-1) < max {
+		ceiling = initial << uint(retry-1)
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+type retriesExhaustedError struct {
+	error
+	retries int
+}
+
+func (e *retriesExhaustedError) Retries() int {
+	return e.retries
+}
+
+// executeInTx is crdb.ExecuteInTx bounded by r.Retry, method names the transaction in logs and metrics.
+// tx is committed if fn succeeds, otherwise rolled back
+func (r *CockroachRepo) executeInTx(ctx context.Context, method string, tx Tx, fn func() error) (err error) {
+	defer func() {
+		if err == nil {
+			// already committed by RELEASE
+			_ = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT cockroach_restart"); err != nil {
+		return err
+	}
+
+	for retries := 0; ; retries++ {
+		released := false
+		err = fn()
+		if err == nil {
+			released = true
+			if _, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT cockroach_restart"); err == nil {
+				if retries > 0 {
+					ctxlog.Printf(ctx, "%s committed after %d retries", method, retries)
+				}
+				return nil
+			}
+		}
+
+		if !retryable(err) {
+			if released {
+				return fmt.Errorf("transaction may or may not be committed: %s", err)
+			}
+			return err
+		}
+
+		if retries >= r.Retry.maxRetries() {
+			metrics.TxnRetriesExhausted.Add(method, 1)
+			ctxlog.Printf(ctx, "%s given up after %d retries: %s", method, retries, err)
+			return &retriesExhaustedError{
+				error:   fmt.Errorf("transaction given up after %d retries: %s", retries, err),
+				retries: retries,
+			}
+		}
+
+		if _, restartErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT cockroach_restart"); restartErr != nil {
+			return fmt.Errorf("restarting transaction failed: %s, original error: %s", restartErr, err)
+		}
+
+		metrics.TxnRetries.Add(method, 1)
+		timer := time.NewTimer(r.Retry.backoff(retries + 1))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// retryable errors are serialization failures, the transaction can be run again
+func retryable(err error) bool {
+	for {
+		cause, ok := err.(crdb.ErrorCauser)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+
+	pqErr, ok := err.(*pq.Error)
+	return ok && (pqErr.Code == "40001" || pqErr.Code == "CR000")
+}
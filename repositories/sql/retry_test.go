@@ -0,0 +1,112 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+
+	"github.com/lib/pq"
+)
+
+func TestCockroachRepo_executeInTx(t *testing.T) {
+	t.Run("must give up after MaxRetries", giveUpAfterMaxRetries)
+	t.Run("must not retry errors other than serialization failures", notRetryOtherErrors)
+	t.Run("must stop retrying when ctx is done", stopRetryingWhenCtxDone)
+}
+
+// retryTx counts statements and rollbacks, RELEASE fails with releaseErr
+func retryTx(releaseErr error, stmts map[string]int, rolledBack *bool) mockTx {
+	return mockTx{
+		commit: func() error {
+			return nil
+		},
+		rollback: func() error {
+			*rolledBack = true
+			return nil
+		},
+		execContext: func(_ context.Context, q string, _ ...interface{}) (sql.Result, error) {
+			stmts[q]++
+			if q == "RELEASE SAVEPOINT cockroach_restart" {
+				return nil, releaseErr
+			}
+			return mockSQLResult{}, nil
+		},
+	}
+}
+
+func giveUpAfterMaxRetries(tt *testing.T) {
+	stmts := map[string]int{}
+	rolledBack := false
+	r := &CockroachRepo{
+		Retry: RetryPolicy{MaxRetries: 3, InitialBackoff: time.Microsecond},
+	}
+
+	attempts := 0
+	err := r.executeInTx(context.Background(), "test", retryTx(&pq.Error{Code: "40001"}, stmts, &rolledBack), func() error {
+		attempts++
+		return nil
+	})
+	exhausted, ok := err.(repositories.RetriesExhaustedError)
+	if !ok {
+		tt.Fatal("expecting RetriesExhaustedError, got", err)
+	}
+
+	if exhausted.Retries() != 3 || attempts != 4 || stmts["ROLLBACK TO SAVEPOINT cockroach_restart"] != 3 {
+		tt.Errorf("expecting 3 retries of 4 attempts, got %d retries of %d attempts", exhausted.Retries(), attempts)
+	}
+
+	if !rolledBack {
+		tt.Error("expecting rollback")
+	}
+}
+
+func notRetryOtherErrors(tt *testing.T) {
+	stmts := map[string]int{}
+	rolledBack := false
+	r := &CockroachRepo{}
+
+	dummyErr := &pq.Error{Code: "23505"}
+	attempts := 0
+	err := r.executeInTx(context.Background(), "test", retryTx(nil, stmts, &rolledBack), func() error {
+		attempts++
+		return dummyErr
+	})
+	if err != dummyErr || attempts != 1 {
+		tt.Errorf("expecting dummyErr after 1 attempt, got %v after %d", err, attempts)
+	}
+
+	if !rolledBack {
+		tt.Error("expecting rollback")
+	}
+}
+
+func stopRetryingWhenCtxDone(tt *testing.T) {
+	stmts := map[string]int{}
+	rolledBack := false
+	r := &CockroachRepo{
+		Retry: RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := r.executeInTx(ctx, "test", retryTx(&pq.Error{Code: "40001"}, stmts, &rolledBack), func() error {
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		tt.Error("expecting context.DeadlineExceeded, got", err)
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	for retry, ceiling := range map[int]time.Duration{1: time.Millisecond, 3: 4 * time.Millisecond, 4: 5 * time.Millisecond, 100: 5 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(retry); d < 0 || d > ceiling {
+				t.Fatalf("expecting backoff of retry %d within [0, %s], got %s", retry, ceiling, d)
+			}
+		}
+	}
+}
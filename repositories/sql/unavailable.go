@@ -0,0 +1,53 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+
+	"github.com/cockroachdb/cockroach-go/crdb"
+	"github.com/lib/pq"
+)
+
+// unavailableClasses of SQLSTATE: connection exception, insufficient resources, operator intervention
+// and system error
+var unavailableClasses = map[pq.ErrorClass]bool{
+	"08": true,
+	"53": true,
+	"57": true,
+	"58": true,
+}
+
+// Unavailable tells whether err is of the database being unreachable or unable to serve any query,
+// unlike errors of a query, e.g. a constraint violated, or of stock
+func Unavailable(err error) bool {
+	for {
+		cause, ok := err.(crdb.ErrorCauser)
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+
+	if pqErr, ok := err.(*pq.Error); ok {
+		return unavailableClasses[pqErr.Code.Class()]
+	}
+
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		// given up by the caller, context.DeadlineExceeded is a net.Error too
+		return false
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	switch err {
+	case driver.ErrBadConn, sql.ErrConnDone, io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,34 @@
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestUnavailable(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected bool
+	}{
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "57P01"}, true},
+		{&pq.Error{Code: "53300"}, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{driver.ErrBadConn, true},
+		{context.DeadlineExceeded, false},
+		{&pq.Error{Code: "23505"}, false},
+		{&pq.Error{Code: "40001"}, false},
+		{&retriesExhaustedError{error: errors.New("given up"), retries: 3}, false},
+		{&inventoryAdjustError{error: errors.New("not enough stock"), productID: 1}, false},
+		{context.Canceled, false},
+	} {
+		if got := Unavailable(tc.err); got != tc.expected {
+			t.Errorf("expecting Unavailable(%v) %v, got %v", tc.err, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package repositories
+
+// LowStock is a product with stock below its reorder threshold
+type LowStock struct {
+	ProductID  int64 `json:"productID"`
+	Threshold  int64 `json:"threshold"`
+	StockCount int64 `json:"stockCount"`
+}
@@ -0,0 +1,16 @@
+package repositories
+
+// WarehouseStock is stock of a product in a warehouse, sum of them equals Inventory.StockCount
+type WarehouseStock struct {
+	ProductID   int64
+	WarehouseID int64
+	Region      string
+	StockCount  int64
+}
+
+// Allocation tells which warehouse ships how many items of a product
+type Allocation struct {
+	ProductID   int64 `json:"productID"`
+	WarehouseID int64 `json:"warehouseID"`
+	Quantity    int64 `json:"quantity"`
+}
@@ -1,3 +1,5 @@
 #!/bin/sh
-# for regenerate protoc files when need
-protoc -I=./grpc -I=$GOPATH/src -I=$GOPATH/src/github.com/gogo/protobuf/protobuf --gogoslick_out=plugins=grpc:./grpc ./grpc/service.proto
\ No newline at end of file
+# for regenerate protoc files when need, see buf.gen.yaml
+buf generate
+# check changes don't break clients of protos on main
+buf breaking --against '.git#branch=main'
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+
+	"tomshop/alerts"
+	pb "tomshop/grpc"
+	"tomshop/repositories"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	alertsUnavailableErr = status.Error(codes.Unimplemented, "watching low stock is not enabled")
+)
+
+// AlertService implements low stock alerts of grpc tomshop.v1.TomShop service
+type AlertService struct {
+	Repo interface {
+		SetStockThreshold(context.Context, int64, int64) error
+		ListLowStock(context.Context, ...repositories.ReadOption) ([]repositories.LowStock, error)
+	}
+	// Alerts checks a product right after its threshold is set, can be nil
+	Alerts *alerts.Checker
+	// Stream feeds WatchLowStock, nil for not watching
+	Stream *alerts.StreamNotifier
+}
+
+// SetStockThreshold of a product, a product already below the new threshold is alerted
+func (s *AlertService) SetStockThreshold(ctx context.Context, in *pb.StockThreshold) (*pb.StockThreshold, error) {
+	if err := s.Repo.SetStockThreshold(ctx, in.ProductID, in.Threshold); err != nil {
+		return nil, repoErr(ctx, err, "setting threshold")
+	}
+	s.Alerts.Check(ctx, in.ProductID)
+
+	return in, nil
+}
+
+// ListLowStock lists every product currently below its threshold, it may be a few seconds stale
+func (s *AlertService) ListLowStock(ctx context.Context, _ *pb.ListLowStockRequest) (*pb.ListLowStockResponse, error) {
+	products, err := s.Repo.ListLowStock(ctx, repositories.AllowStale())
+	if err != nil {
+		return nil, repoErr(ctx, err, "listing low stock")
+	}
+
+	resp := &pb.ListLowStockResponse{
+		Products: make([]*pb.LowStock, len(products)),
+	}
+	for i, l := range products {
+		resp.Products[i] = toPbLowStock(l)
+	}
+
+	return resp, nil
+}
+
+// WatchLowStock sends alerts until client goes away
+func (s *AlertService) WatchLowStock(_ *pb.WatchLowStockRequest, stream pb.TomShop_WatchLowStockServer) error {
+	if s.Stream == nil {
+		return alertsUnavailableErr
+	}
+
+	lows, cancel := s.Stream.Subscribe()
+	defer cancel()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case l := <-lows:
+			if err := stream.Send(toPbLowStock(l)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toPbLowStock(l repositories.LowStock) *pb.LowStock {
+	return &pb.LowStock{
+		ProductID:  l.ProductID,
+		Threshold:  l.Threshold,
+		StockCount: l.StockCount,
+	}
+}
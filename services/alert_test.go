@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"tomshop/alerts"
+	pb "tomshop/grpc"
+	"tomshop/repositories"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAlertService_SetStockThreshold(t *testing.T) {
+	t.Run("expecting product checked after threshold set", checkAfterThresholdSet)
+}
+
+func checkAfterThresholdSet(t *testing.T) {
+	var set, checked []int64
+	repo := mockAlertRepo{
+		setStockThreshold: func(_ context.Context, productID, threshold int64) error {
+			set = []int64{productID, threshold}
+			return nil
+		},
+		checkStockThresholds: func(_ context.Context, ids []int64) ([]repositories.LowStock, error) {
+			checked = ids
+			return nil, nil
+		},
+	}
+	s := &AlertService{
+		Repo:   repo,
+		Alerts: &alerts.Checker{Repo: repo, Notifier: alerts.LogNotifier{}},
+	}
+
+	if _, err := s.SetStockThreshold(context.Background(), &pb.StockThreshold{ProductID: 1, Threshold: 5}); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if !reflect.DeepEqual(set, []int64{1, 5}) || !reflect.DeepEqual(checked, []int64{1}) {
+		t.Error("expecting threshold 5 set and product 1 checked, got", set, checked)
+	}
+}
+
+func TestAlertService_ListLowStock(t *testing.T) {
+	s := &AlertService{
+		Repo: mockAlertRepo{
+			listLowStock: func(context.Context) ([]repositories.LowStock, error) {
+				return []repositories.LowStock{{ProductID: 1, Threshold: 5, StockCount: 2}}, nil
+			},
+		},
+	}
+
+	resp, err := s.ListLowStock(context.Background(), &pb.ListLowStockRequest{})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := []*pb.LowStock{{ProductID: 1, Threshold: 5, StockCount: 2}}
+	if !proto.Equal(resp, &pb.ListLowStockResponse{Products: expected}) {
+		t.Error("expecting low stock products mapped, got", resp.Products)
+	}
+}
+
+type mockAlertRepo struct {
+	setStockThreshold    func(context.Context, int64, int64) error
+	listLowStock         func(context.Context) ([]repositories.LowStock, error)
+	checkStockThresholds func(context.Context, []int64) ([]repositories.LowStock, error)
+}
+
+func (r mockAlertRepo) SetStockThreshold(ctx context.Context, productID, threshold int64) error {
+	return r.setStockThreshold(ctx, productID, threshold)
+}
+
+func (r mockAlertRepo) ListLowStock(ctx context.Context, _ ...repositories.ReadOption) ([]repositories.LowStock, error) {
+	return r.listLowStock(ctx)
+}
+
+func (r mockAlertRepo) CheckStockThresholds(ctx context.Context, ids []int64) ([]repositories.LowStock, error) {
+	return r.checkStockThresholds(ctx, ids)
+}
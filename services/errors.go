@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+
+	"tomshop/repositories"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// repoErr maps errors of repositories not handled by a RPC itself, doing tells what failed
+func repoErr(ctx context.Context, err error, doing string) error {
+	switch e := err.(type) {
+	case repositories.RetriesExhaustedError:
+		return status.Errorf(codes.Aborted, "too much contention when %s after %d retries, try again", doing, e.Retries())
+	case repositories.UnavailableError:
+		return status.Errorf(codes.Unavailable, "database unavailable when %s, retry after %s", doing, e.RetryAfter())
+	}
+
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return status.Errorf(codes.DeadlineExceeded, "deadline exceeded when %s", doing)
+	case context.Canceled:
+		return status.Errorf(codes.Canceled, "canceled when %s", doing)
+	}
+
+	return status.Errorf(codes.Internal, "internal error when %s: %s", doing, err.Error())
+}
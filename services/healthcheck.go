@@ -3,16 +3,28 @@ package services
 import (
 	"context"
 
+	"tomshop/breaker"
+
 	"google.golang.org/grpc/codes"
 	health "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/status"
 )
 
 // HealthcheckService implement gRPC Healthcheck standard
-type HealthcheckService struct{}
+type HealthcheckService struct {
+	health.UnimplementedHealthServer
+	// Breaker around the database, the server is not serving while it is open, can be nil
+	Breaker *breaker.Breaker
+}
+
+// Check will ensure migration already success and the database is not considered down
+func (s *HealthcheckService) Check(context.Context, *health.HealthCheckRequest) (*health.HealthCheckResponse, error) {
+	if s.Breaker.State() == breaker.Open {
+		return &health.HealthCheckResponse{
+			Status: health.HealthCheckResponse_NOT_SERVING,
+		}, nil
+	}
 
-// Check will ensure migration already success
-func (*HealthcheckService) Check(context.Context, *health.HealthCheckRequest) (*health.HealthCheckResponse, error) {
 	return &health.HealthCheckResponse{
 		Status: health.HealthCheckResponse_SERVING,
 	}, nil
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"tomshop/breaker"
+
+	health "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthcheckService_Check(t *testing.T) {
+	t.Run("expecting serving without breaker", func(tt *testing.T) {
+		resp, _ := (&HealthcheckService{}).Check(nil, &health.HealthCheckRequest{})
+		if resp.Status != health.HealthCheckResponse_SERVING {
+			tt.Error("expecting SERVING, got", resp.Status)
+		}
+	})
+
+	t.Run("expecting not serving while breaker open", func(tt *testing.T) {
+		b := breaker.NewBreaker()
+		b.FailureThreshold = 1
+		done, _ := b.Allow()
+		done(errors.New("connection refused"))
+
+		resp, _ := (&HealthcheckService{Breaker: b}).Check(nil, &health.HealthCheckRequest{})
+		if resp.Status != health.HealthCheckResponse_NOT_SERVING {
+			tt.Error("expecting NOT_SERVING, got", resp.Status)
+		}
+	})
+}
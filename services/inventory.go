@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"tomshop/ctxlog"
+	pb "tomshop/grpc"
+	"tomshop/repositories"
+	"tomshop/stockcache"
+	"tomshop/watch"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultHistoryLimit = 100
+
+var (
+	invalidStockChangeErr = status.Error(codes.InvalidArgument, "restocked or cancelled quantity must be positive")
+	invalidBackorderedErr = status.Error(codes.InvalidArgument, "only cancellations release backordered items")
+	invalidReleaseAtErr   = status.Error(codes.InvalidArgument, "releaseAt must be in RFC 3339 format")
+	negativeStockErr      = status.Error(codes.FailedPrecondition, "stock and backorders cannot be negative")
+	warehouseRequiredErr  = status.Error(codes.InvalidArgument, "warehouse of stock change required")
+	watchUnavailableErr   = status.Error(codes.Unimplemented, "watching inventory is not enabled")
+)
+
+var reasonToPb = map[repositories.MovementReason]pb.StockMovementReason{
+	repositories.ReasonOrder:          pb.StockMovementReason_ORDER,
+	repositories.ReasonRestock:        pb.StockMovementReason_RESTOCK,
+	repositories.ReasonCancellation:   pb.StockMovementReason_CANCELLATION,
+	repositories.ReasonCorrection:     pb.StockMovementReason_CORRECTION,
+	repositories.ReasonOpeningBalance: pb.StockMovementReason_OPENING_BALANCE,
+	repositories.ReasonBackordered:    pb.StockMovementReason_BACKORDERED,
+}
+
+// InventoryService implements stock management of grpc tomshop.v1.TomShop service
+type InventoryService struct {
+	Repo interface {
+		ChangeStock(context.Context, repositories.StockChange) ([]repositories.StockMovement, error)
+		ListStockMovements(context.Context, int64, int, ...repositories.ReadOption) ([]repositories.StockMovement, error)
+		ListInventories(context.Context, []int64, ...repositories.ReadOption) ([]repositories.Inventory, error)
+		SetFulfillmentPolicy(context.Context, repositories.FulfillmentPolicy) (repositories.FulfillmentPolicy, error)
+	}
+	// Broadcaster is told about stock changes and feeds WatchInventory, nil for not watching
+	Broadcaster *watch.Broadcaster
+	// Cache serves ListInventories and is invalidated by stock changes, nil for reading Repo
+	Cache *stockcache.Cache
+	// Warehouses requires stock changes to tell their warehouse, set when orders are allocated to warehouses
+	Warehouses bool
+}
+
+// ChangeStock restocks, returns cancelled items or corrects stock of a product, ranges of its fields
+// are validated by the rules of the proto. Movement of the change is returned, items of it held for
+// backorders are recorded by a BACKORDERED movement after it
+func (s *InventoryService) ChangeStock(ctx context.Context, in *pb.ChangeStockRequest) (*pb.StockMovement, error) {
+	if s.Warehouses && in.WarehouseID <= 0 {
+		return nil, warehouseRequiredErr
+	}
+
+	if in.Backordered > 0 && in.Reason != pb.StockMovementReason_CANCELLATION {
+		return nil, invalidBackorderedErr
+	}
+
+	change := repositories.StockChange{
+		ProductID:   in.ProductID,
+		Quantity:    in.Quantity,
+		Reference:   in.Reference,
+		Actor:       in.Actor,
+		WarehouseID: in.WarehouseID,
+		Backordered: in.Backordered,
+	}
+	switch in.Reason {
+	case pb.StockMovementReason_RESTOCK, pb.StockMovementReason_CANCELLATION:
+		// a cancelled order may be all backordered
+		if in.Quantity+in.Backordered <= 0 {
+			return nil, invalidStockChangeErr
+		}
+		change.Reason = repositories.ReasonRestock
+		if in.Reason == pb.StockMovementReason_CANCELLATION {
+			change.Reason = repositories.ReasonCancellation
+		}
+	case pb.StockMovementReason_CORRECTION:
+		change.Reason = repositories.ReasonCorrection
+	}
+
+	movements, err := s.Repo.ChangeStock(ctx, change)
+	if _, ok := err.(repositories.InventoryQuantityUpdateError); ok {
+		ctxlog.Println(ctx, "cannot change stock:", err)
+		return nil, negativeStockErr
+	}
+
+	if err != nil {
+		return nil, repoErr(ctx, err, "changing stock")
+	}
+	last := movements[len(movements)-1]
+	s.Cache.Invalidate(last.ProductID)
+	s.Broadcaster.Publish(repositories.Inventory{ProductID: last.ProductID, StockCount: last.After})
+
+	return toPbMovement(movements[0]), nil
+}
+
+// SetFulfillmentPolicy of a product, returns the policy set with items currently backordered
+func (s *InventoryService) SetFulfillmentPolicy(ctx context.Context, in *pb.FulfillmentPolicy) (*pb.FulfillmentPolicy, error) {
+	p := repositories.FulfillmentPolicy{
+		ProductID: in.ProductID,
+		Kind:      repositories.FulfillmentPolicyKind(in.Kind),
+		Limit:     in.Limit,
+	}
+	if in.ReleaseAt != "" {
+		releaseAt, err := time.Parse(time.RFC3339, in.ReleaseAt)
+		if err != nil {
+			return nil, invalidReleaseAtErr
+		}
+		p.ReleaseAt = releaseAt
+	}
+
+	p, err := s.Repo.SetFulfillmentPolicy(ctx, p)
+	if err != nil {
+		return nil, repoErr(ctx, err, "setting fulfillment policy")
+	}
+
+	return toPbFulfillmentPolicy(p), nil
+}
+
+// GetStockHistory of a product, newest first, it may be a few seconds stale
+func (s *InventoryService) GetStockHistory(ctx context.Context, in *pb.StockHistoryRequest) (*pb.StockHistoryResponse, error) {
+	limit := int(in.Limit)
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	movements, err := s.Repo.ListStockMovements(ctx, in.ProductID, limit, repositories.AllowStale())
+	if err != nil {
+		return nil, repoErr(ctx, err, "listing stock movements")
+	}
+
+	resp := &pb.StockHistoryResponse{
+		Movements: make([]*pb.StockMovement, len(movements)),
+	}
+	for i, m := range movements {
+		resp.Movements[i] = toPbMovement(m)
+	}
+
+	return resp, nil
+}
+
+// ListInventories lists stock of products from Cache if set
+func (s *InventoryService) ListInventories(ctx context.Context, in *pb.ListInventoriesRequest) (*pb.ListInventoriesResponse, error) {
+	var inventories []repositories.Inventory
+	var err error
+	if s.Cache != nil {
+		// misses read strong, a stale read after invalidation would be cached again
+		inventories, err = s.Cache.ListInventories(ctx, in.ProductIDs)
+	} else {
+		inventories, err = s.Repo.ListInventories(ctx, in.ProductIDs, repositories.AllowStale())
+	}
+	if err != nil {
+		return nil, repoErr(ctx, err, "listing inventories")
+	}
+
+	return &pb.ListInventoriesResponse{
+		Inventories: toPbInventories(in.ProductIDs, inventories),
+	}, nil
+}
+
+// WatchInventory sends current stock of products then their latest stock whenever changed, changed stock is
+// sent as committed without reading it back. Only changes made through this server instance are seen.
+func (s *InventoryService) WatchInventory(in *pb.WatchInventoryRequest, stream pb.TomShop_WatchInventoryServer) error {
+	if s.Broadcaster == nil {
+		return watchUnavailableErr
+	}
+
+	// subscribe before reading the snapshot so changes in between are not missed
+	sub := s.Broadcaster.Subscribe(in.ProductIDs)
+	defer sub.Close()
+
+	ctx := stream.Context()
+	// strong, a stale read could miss a change published before subscribing and never sent again
+	inventories, err := s.Repo.ListInventories(ctx, in.ProductIDs)
+	if err != nil {
+		return repoErr(ctx, err, "listing inventories")
+	}
+
+	if err := sendInventories(stream, toPbInventories(in.ProductIDs, inventories)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Ready():
+			changed := sub.Take()
+			ids := make([]int64, len(changed))
+			for i, inv := range changed {
+				ids[i] = inv.ProductID
+			}
+
+			if err := sendInventories(stream, toPbInventories(ids, changed)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func sendInventories(stream pb.TomShop_WatchInventoryServer, inventories []*pb.Inventory) error {
+	for _, inv := range inventories {
+		if err := stream.Send(inv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toPbInventories of ids once each in order, products not in inventories have zero stock
+func toPbInventories(ids []int64, inventories []repositories.Inventory) []*pb.Inventory {
+	stockMap := make(map[int64]int64, len(inventories))
+	for _, inv := range inventories {
+		stockMap[inv.ProductID] = inv.StockCount
+	}
+
+	results := make([]*pb.Inventory, 0, len(ids))
+	sent := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := sent[id]; ok {
+			continue
+		}
+		sent[id] = struct{}{}
+		results = append(results, &pb.Inventory{ProductID: id, StockCount: stockMap[id]})
+	}
+
+	return results
+}
+
+func toPbFulfillmentPolicy(p repositories.FulfillmentPolicy) *pb.FulfillmentPolicy {
+	resp := &pb.FulfillmentPolicy{
+		ProductID:        p.ProductID,
+		Kind:             pb.FulfillmentPolicyKind(p.Kind),
+		Limit:            p.Limit,
+		BackorderedCount: p.BackorderedCount,
+	}
+	if !p.ReleaseAt.IsZero() {
+		resp.ReleaseAt = p.ReleaseAt.Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+func toPbMovement(m repositories.StockMovement) *pb.StockMovement {
+	return &pb.StockMovement{
+		Id:        m.ID,
+		ProductID: m.ProductID,
+		Delta:     m.Delta,
+		Reason:    reasonToPb[m.Reason],
+		Reference: m.Reference,
+		Actor:     m.Actor,
+		Before:    m.Before,
+		After:     m.After,
+		CreatedAt: m.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "tomshop/grpc"
+	"tomshop/repositories"
+	"tomshop/stockcache"
+	"tomshop/watch"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestInventoryService_ChangeStock(t *testing.T) {
+	t.Run("expecting gRPC InvalidArgument error if restock zero qty",
+		errorWhenRestockZeroQty)
+	t.Run("expecting gRPC FailedPrecondition error if stock becomes negative",
+		errorWhenStockBecomesNegative)
+	t.Run("expecting movement returned when correcting stock",
+		movementWhenCorrectStock)
+	t.Run("expecting gRPC InvalidArgument error if warehouse required but missing",
+		errorWhenWarehouseMissing)
+	t.Run("expecting backordered items released only by cancellation",
+		releaseBackorderedOnlyWhenCancel)
+}
+
+func releaseBackorderedOnlyWhenCancel(t *testing.T) {
+	var changed repositories.StockChange
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			changeStock: func(_ context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+				changed = c
+				return []repositories.StockMovement{{ProductID: c.ProductID, Reason: c.Reason}}, nil
+			},
+		},
+	}
+
+	in := &pb.ChangeStockRequest{
+		ProductID:   1,
+		Quantity:    1,
+		Reason:      pb.StockMovementReason_RESTOCK,
+		Backordered: 2,
+	}
+	if _, err := s.ChangeStock(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Error("expecting gRPC InvalidArgument error, got", err)
+	}
+
+	// an order all backordered returns nothing to stock
+	in.Reason, in.Quantity = pb.StockMovementReason_CANCELLATION, 0
+	resp, err := s.ChangeStock(context.Background(), in)
+	if err != nil || changed.Backordered != 2 || resp.Reason != pb.StockMovementReason_CANCELLATION {
+		t.Error("expecting 2 backordered items released, got", changed, err)
+	}
+}
+
+func errorWhenWarehouseMissing(t *testing.T) {
+	var changed repositories.StockChange
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			changeStock: func(_ context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+				changed = c
+				return []repositories.StockMovement{{ProductID: c.ProductID}}, nil
+			},
+		},
+		Warehouses: true,
+	}
+
+	in := &pb.ChangeStockRequest{
+		ProductID: 1,
+		Quantity:  1,
+		Reason:    pb.StockMovementReason_RESTOCK,
+	}
+	if _, err := s.ChangeStock(context.Background(), in); status.Code(err) != codes.InvalidArgument {
+		t.Error("expecting gRPC InvalidArgument error, got", err)
+	}
+
+	in.WarehouseID = 2
+	if _, err := s.ChangeStock(context.Background(), in); err != nil || changed.WarehouseID != 2 {
+		t.Error("expecting stock of warehouse 2 changed, got", changed, err)
+	}
+}
+
+func TestInventoryService_GetStockHistory(t *testing.T) {
+	var limit int
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			listStockMovements: func(_ context.Context, productID int64, l int) ([]repositories.StockMovement, error) {
+				limit = l
+				return []repositories.StockMovement{
+					{
+						ID:        2,
+						ProductID: productID,
+						Delta:     -1,
+						Reason:    repositories.ReasonOrder,
+					},
+					{
+						ID:        1,
+						ProductID: productID,
+						Delta:     5,
+						Reason:    repositories.ReasonOpeningBalance,
+					},
+				}, nil
+			},
+		},
+	}
+
+	resp, err := s.GetStockHistory(context.Background(), &pb.StockHistoryRequest{ProductID: 1})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if limit != defaultHistoryLimit {
+		t.Errorf("expecting default limit %d, got %d", defaultHistoryLimit, limit)
+	}
+
+	if len(resp.Movements) != 2 || resp.Movements[0].Reason != pb.StockMovementReason_ORDER || resp.Movements[1].Reason != pb.StockMovementReason_OPENING_BALANCE {
+		t.Error("expecting 2 movements with reasons mapped, got", resp.Movements)
+	}
+}
+
+func errorWhenRestockZeroQty(t *testing.T) {
+	s := &InventoryService{Repo: mockInventoryRepo{}}
+
+	_, err := s.ChangeStock(context.Background(), &pb.ChangeStockRequest{
+		ProductID: 1,
+		Quantity:  0,
+		Reason:    pb.StockMovementReason_RESTOCK,
+	})
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("expecting gRPC InvalidArgument error, got", err)
+	}
+}
+
+func errorWhenStockBecomesNegative(t *testing.T) {
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			changeStock: func(context.Context, repositories.StockChange) ([]repositories.StockMovement, error) {
+				return nil, mockAdjustError{}
+			},
+		},
+	}
+
+	_, err := s.ChangeStock(context.Background(), &pb.ChangeStockRequest{
+		ProductID: 1,
+		Quantity:  1,
+		Reason:    pb.StockMovementReason_CANCELLATION,
+	})
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Error("expecting gRPC FailedPrecondition error, got", err)
+	}
+}
+
+func movementWhenCorrectStock(t *testing.T) {
+	createdAt := time.Date(2019, 4, 25, 11, 0, 0, 0, time.UTC)
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			changeStock: func(_ context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+				if c.Reason != repositories.ReasonCorrection || c.Quantity != 3 || c.Actor != "admin" {
+					t.Error("unexpected stock change", c)
+				}
+
+				return []repositories.StockMovement{{
+					ID:        1,
+					ProductID: c.ProductID,
+					Delta:     -2,
+					Reason:    c.Reason,
+					Actor:     c.Actor,
+					Before:    5,
+					After:     3,
+					CreatedAt: createdAt,
+				}}, nil
+			},
+		},
+	}
+
+	resp, err := s.ChangeStock(context.Background(), &pb.ChangeStockRequest{
+		ProductID: 1,
+		Quantity:  3,
+		Reason:    pb.StockMovementReason_CORRECTION,
+		Actor:     "admin",
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := &pb.StockMovement{
+		Id:        1,
+		ProductID: 1,
+		Delta:     -2,
+		Reason:    pb.StockMovementReason_CORRECTION,
+		Actor:     "admin",
+		Before:    5,
+		After:     3,
+		CreatedAt: "2019-04-25T11:00:00Z",
+	}
+	if !proto.Equal(resp, expected) {
+		t.Error("expecting correction movement, got", resp)
+	}
+}
+
+func TestInventoryService_SetFulfillmentPolicy(t *testing.T) {
+	releaseAt := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	var set repositories.FulfillmentPolicy
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			setFulfillmentPolicy: func(_ context.Context, p repositories.FulfillmentPolicy) (repositories.FulfillmentPolicy, error) {
+				set = p
+				p.BackorderedCount = 3
+				return p, nil
+			},
+		},
+	}
+
+	t.Run("expecting policy set with backordered items returned", func(tt *testing.T) {
+		resp, err := s.SetFulfillmentPolicy(context.Background(), &pb.FulfillmentPolicy{
+			ProductID:        1,
+			Kind:             pb.FulfillmentPolicyKind_PREORDER,
+			Limit:            10,
+			BackorderedCount: 100,
+			ReleaseAt:        "2019-06-01T00:00:00Z",
+		})
+		if err != nil {
+			tt.Fatal("unexpected error", err)
+		}
+
+		expectedSet := repositories.FulfillmentPolicy{ProductID: 1, Kind: repositories.Preorder, Limit: 10, ReleaseAt: releaseAt}
+		if set != expectedSet {
+			tt.Error("unexpected policy set", set)
+		}
+
+		expected := &pb.FulfillmentPolicy{
+			ProductID:        1,
+			Kind:             pb.FulfillmentPolicyKind_PREORDER,
+			Limit:            10,
+			BackorderedCount: 3,
+			ReleaseAt:        "2019-06-01T00:00:00Z",
+		}
+		if !proto.Equal(resp, expected) {
+			tt.Error("expecting policy with backordered items, got", resp)
+		}
+	})
+
+	t.Run("expecting gRPC InvalidArgument error if releaseAt malformed", func(tt *testing.T) {
+		_, err := s.SetFulfillmentPolicy(context.Background(), &pb.FulfillmentPolicy{
+			ProductID: 1,
+			Kind:      pb.FulfillmentPolicyKind_PREORDER,
+			ReleaseAt: "tomorrow",
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			tt.Error("expecting gRPC InvalidArgument error, got", err)
+		}
+	})
+}
+
+func TestInventoryService_ListInventories(t *testing.T) {
+	t.Run("expecting cached stock until changed", func(tt *testing.T) {
+		stock := int64(5)
+		reads := 0
+		repo := mockInventoryRepo{
+			listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+				reads++
+				return []repositories.Inventory{{ProductID: 1, StockCount: stock}}, nil
+			},
+			changeStock: func(_ context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+				stock += c.Quantity
+				return []repositories.StockMovement{{ProductID: c.ProductID, Delta: c.Quantity}}, nil
+			},
+		}
+		s := &InventoryService{
+			Repo:  repo,
+			Cache: stockcache.NewCache(repo),
+		}
+
+		list := func(expected ...*pb.Inventory) {
+			resp, err := s.ListInventories(context.Background(), &pb.ListInventoriesRequest{ProductIDs: []int64{1, 2}})
+			if err != nil {
+				tt.Fatal("unexpected error", err)
+			}
+			if !proto.Equal(resp, &pb.ListInventoriesResponse{Inventories: expected}) {
+				tt.Error("unexpected inventories", resp.Inventories)
+			}
+		}
+
+		list(&pb.Inventory{ProductID: 1, StockCount: 5}, &pb.Inventory{ProductID: 2})
+		list(&pb.Inventory{ProductID: 1, StockCount: 5}, &pb.Inventory{ProductID: 2})
+		if _, err := s.ChangeStock(context.Background(), &pb.ChangeStockRequest{ProductID: 1, Quantity: 2, Reason: pb.StockMovementReason_RESTOCK}); err != nil {
+			tt.Fatal("unexpected error", err)
+		}
+		list(&pb.Inventory{ProductID: 1, StockCount: 7}, &pb.Inventory{ProductID: 2})
+
+		if reads != 2 {
+			tt.Errorf("expecting stock read twice, got %d", reads)
+		}
+	})
+}
+
+func TestInventoryService_WatchInventory(t *testing.T) {
+	t.Run("expecting snapshot then committed stock sent without reading it", snapshotThenChangesWhenWatch)
+}
+
+func snapshotThenChangesWhenWatch(t *testing.T) {
+	stock := map[int64]int64{1: 5, 2: 3}
+	reads := 0
+	b := watch.NewBroadcaster()
+	s := &InventoryService{
+		Repo: mockInventoryRepo{
+			listInventories: func(_ context.Context, ids []int64) ([]repositories.Inventory, error) {
+				reads++
+				var inventories []repositories.Inventory
+				for _, id := range ids {
+					inventories = append(inventories, repositories.Inventory{ProductID: id, StockCount: stock[id]})
+				}
+				return inventories, nil
+			},
+			changeStock: func(_ context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+				// 1 item held for a backorder
+				stock[c.ProductID] += c.Quantity
+				return []repositories.StockMovement{
+					{ProductID: c.ProductID, Reason: c.Reason, After: stock[c.ProductID]},
+					{ProductID: c.ProductID, Reason: repositories.ReasonBackordered, After: stock[c.ProductID] - 1},
+				}, nil
+			},
+		},
+		Broadcaster: b,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &mockWatchStream{ctx: ctx, sent: make(chan *pb.Inventory, 10)}
+	done := make(chan error)
+	go func() {
+		done <- s.WatchInventory(&pb.WatchInventoryRequest{ProductIDs: []int64{1, 2}}, stream)
+	}()
+
+	for _, expected := range []*pb.Inventory{{ProductID: 1, StockCount: 5}, {ProductID: 2, StockCount: 3}} {
+		if got := <-stream.sent; !proto.Equal(got, expected) {
+			t.Error("expecting snapshot", expected, "got", got)
+		}
+	}
+
+	_, err := s.ChangeStock(ctx, &pb.ChangeStockRequest{ProductID: 2, Quantity: 4, Reason: pb.StockMovementReason_RESTOCK})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if got := <-stream.sent; got.ProductID != 2 || got.StockCount != 6 {
+		t.Error("expecting product 2 with stock after the held item, got", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error("expecting no error when client goes away, got", err)
+	}
+
+	if reads != 1 {
+		t.Errorf("expecting stock read once for the snapshot, got %d", reads)
+	}
+}
+
+type mockWatchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *pb.Inventory
+}
+
+func (s *mockWatchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockWatchStream) Send(inv *pb.Inventory) error {
+	s.sent <- inv
+	return nil
+}
+
+type mockInventoryRepo struct {
+	changeStock          func(context.Context, repositories.StockChange) ([]repositories.StockMovement, error)
+	listStockMovements   func(context.Context, int64, int) ([]repositories.StockMovement, error)
+	listInventories      func(context.Context, []int64) ([]repositories.Inventory, error)
+	setFulfillmentPolicy func(context.Context, repositories.FulfillmentPolicy) (repositories.FulfillmentPolicy, error)
+}
+
+func (r mockInventoryRepo) ChangeStock(ctx context.Context, c repositories.StockChange) ([]repositories.StockMovement, error) {
+	return r.changeStock(ctx, c)
+}
+
+func (r mockInventoryRepo) ListStockMovements(ctx context.Context, productID int64, limit int, _ ...repositories.ReadOption) ([]repositories.StockMovement, error) {
+	return r.listStockMovements(ctx, productID, limit)
+}
+
+func (r mockInventoryRepo) ListInventories(ctx context.Context, ids []int64, _ ...repositories.ReadOption) ([]repositories.Inventory, error) {
+	return r.listInventories(ctx, ids)
+}
+
+func (r mockInventoryRepo) SetFulfillmentPolicy(
+	ctx context.Context,
+	p repositories.FulfillmentPolicy,
+) (repositories.FulfillmentPolicy, error) {
+	return r.setFulfillmentPolicy(ctx, p)
+}
@@ -2,84 +2,118 @@ package services
 
 import (
 	"context"
-	"log"
 
-	pb "tomshop/grpc"
-	"tomshop/repositories"
+	"tomshop/admission"
+	"tomshop/domain"
+	pbv2 "tomshop/grpc/v2"
 
+	"buf.build/go/protovalidate"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 var (
-	notEnoughStockErr  = status.Error(codes.FailedPrecondition, "not enough stock to fullfil order")
-	invalidOrderQtyErr = status.Error(codes.InvalidArgument, "invalid quantity for order")
+	notEnoughStockErr      = status.Error(codes.FailedPrecondition, "not enough stock to fullfil order")
+	invalidCouponErr       = status.Error(codes.InvalidArgument, "invalid coupon code")
+	couponNotApplicableErr = status.Error(codes.FailedPrecondition, "coupon cannot be applied to order")
 )
 
-// OrderService implements grpc tomshop.v1.TomShop service
+// OrderService implements ordering of grpc tomshop.v2.TomShop service by domain.OrderService,
+// tomshop.v1.TomShop ordering is adapted from it
 type OrderService struct {
-	Repo interface {
-		ListInventories(context.Context, []int64) ([]repositories.Inventory, error)
-		AdjustInventories(context.Context, []repositories.Order) error
-	}
+	Orders *domain.OrderService
+	// Validator of every order of a batch, nil for not validating
+	Validator protovalidate.Validator
+	// Admission of every batch of MakeOrders and StreamOrders as a critical request, nil for not limiting
+	Admission *admission.Limiter
+}
+
+// PlaceOrder maps orders refused by business rules to rejected responses
+func (s *OrderService) PlaceOrder(ctx context.Context, in *pbv2.PlaceOrderRequest) (*pbv2.PlaceOrderResponse, error) {
+	result, err := s.Orders.PlaceOrder(ctx, toOrderCommand(in))
+	return toPlaceOrderResponse(ctx, result, err)
 }
 
-// MakeOrder simply rely on repository
-func (s *OrderService) MakeOrder(ctx context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
-	ids := make([]int64, len(in.Purchases))
-	purchaseMap := make(map[int64]int64, len(in.Purchases))
-	for i, order := range in.Purchases {
-		ids[i] = order.ProductID
-		purchaseMap[order.ProductID] = order.Quantity
+// toPlaceOrderResponse of an order placed by the domain
+func toPlaceOrderResponse(ctx context.Context, result domain.OrderResult, err error) (*pbv2.PlaceOrderResponse, error) {
+	switch err := err.(type) {
+	case nil:
+	case domain.OutOfStockError:
+		return rejected(pbv2.RejectionReason_OUT_OF_STOCK, err.ProductID, err), nil
+	case domain.UnknownCouponError:
+		return rejected(pbv2.RejectionReason_UNKNOWN_COUPON, 0, err), nil
+	case domain.CouponNotApplicableError:
+		return rejected(pbv2.RejectionReason_COUPON_NOT_APPLICABLE, 0, err), nil
+	case domain.InvalidOrderError:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return nil, repoErr(ctx, err, "saving order")
 	}
 
-	availableInventories, err := s.Repo.ListInventories(ctx, ids)
-	if err != nil {
-		return &pb.OrderResponse{
-			Successful: false,
-		}, status.Errorf(codes.Internal, "internal error when checking for available stock: %s", err.Error())
+	return toPbOrderResponse(result), nil
+}
+
+func rejected(reason pbv2.RejectionReason, productID int64, err error) *pbv2.PlaceOrderResponse {
+	return &pbv2.PlaceOrderResponse{
+		Status: pbv2.OrderStatus_REJECTED,
+		Rejection: &pbv2.Rejection{
+			Reason:    reason,
+			ProductID: productID,
+			Message:   err.Error(),
+		},
 	}
+}
 
-	if len(availableInventories) != len(in.Purchases) {
-		log.Println("not enough products")
-		return &pb.OrderResponse{
-			Successful: false,
-		}, notEnoughStockErr
+func toOrderCommand(in *pbv2.PlaceOrderRequest) domain.OrderCommand {
+	lines := make([]domain.OrderLine, len(in.Lines))
+	for i, l := range in.Lines {
+		lines[i] = domain.OrderLine{
+			ProductID:   l.ProductID,
+			Quantity:    l.Quantity,
+			MinQuantity: l.MinQuantity,
+		}
 	}
 
-	orders := make([]repositories.Order, len(availableInventories))
+	return domain.OrderCommand{
+		Lines:                lines,
+		CustomerID:           in.CustomerID,
+		CouponCodes:          in.CouponCodes,
+		RegionHint:           in.RegionHint,
+		PreferredWarehouseID: in.PreferredWarehouseID,
+		AllowBackorder:       in.AllowBackorder,
+		// values of proto and domain are the same
+		FulfillmentMode: domain.FulfillmentMode(in.FulfillmentMode),
+	}
+}
 
-	for i := range availableInventories {
-		productID := availableInventories[i].ProductID
-		requestQty := purchaseMap[productID]
-		if requestQty <= 0 {
-			return &pb.OrderResponse{
-				Successful: false,
-			}, invalidOrderQtyErr
+func toPbOrderResponse(result domain.OrderResult) *pbv2.PlaceOrderResponse {
+	lines := make([]*pbv2.LineResult, len(result.Lines))
+	for i, l := range result.Lines {
+		lines[i] = &pbv2.LineResult{
+			ProductID:   l.ProductID,
+			Requested:   l.Requested,
+			Immediate:   l.Immediate,
+			Backordered: l.Backordered,
+			Preorder:    l.Preorder,
+			Unfulfilled: l.Unfulfilled,
 		}
 
-		if requestQty > availableInventories[i].StockCount {
-			log.Printf(
-				"not enough items for product %d, reuested: %d, available: %d",
-				productID,
-				requestQty,
-				availableInventories[i].StockCount,
-			)
-			return &pb.OrderResponse{
-				Successful: false,
-			}, notEnoughStockErr
+		for _, a := range l.Allocations {
+			lines[i].Allocations = append(lines[i].Allocations, &pbv2.Allocation{
+				WarehouseID: a.WarehouseID,
+				Quantity:    a.Quantity,
+			})
 		}
-
-		orders[i].ProductID = productID
-		orders[i].Quantity = requestQty
 	}
 
-	err = s.Repo.AdjustInventories(ctx, orders)
-	if err != nil {
-		return &pb.OrderResponse{
-			Successful: false,
-		}, status.Errorf(codes.Internal, "internal error when saving order: %s", err.Error())
+	return &pbv2.PlaceOrderResponse{
+		OrderID: result.OrderID,
+		Status:  pbv2.OrderStatus_PLACED,
+		Totals: &pbv2.Totals{
+			Subtotal: result.Subtotal,
+			Discount: result.Discount,
+			Total:    result.Total,
+		},
+		Lines: lines,
 	}
-
-	return &pb.OrderResponse{Successful: true}, nil
 }
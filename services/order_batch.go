@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"tomshop/admission"
+	"tomshop/domain"
+	pb "tomshop/grpc"
+	"tomshop/interceptors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxBatchSize = 500
+	// streamBatchSize orders are read from a stream before making them, the client is held back
+	// by gRPC flow control meanwhile
+	streamBatchSize = 100
+	// maxStreamedOrders bounds results held until the stream is closed, so they fit in a reply
+	// of the default 4MB
+	maxStreamedOrders = 5000
+)
+
+var (
+	batchTooLargeErr = status.Errorf(codes.InvalidArgument, "batch cannot have more than %d orders", maxBatchSize)
+	emptyOrderErr    = status.Error(codes.InvalidArgument, "empty order")
+	streamTooLongErr = status.Errorf(codes.ResourceExhausted, "stream cannot have more than %d orders", maxStreamedOrders)
+)
+
+// MakeOrders makes every order independently in request order
+func (s *OrderService) MakeOrders(ctx context.Context, in *pb.MakeOrdersRequest) (*pb.MakeOrdersResponse, error) {
+	if len(in.Orders) > maxBatchSize {
+		return nil, batchTooLargeErr
+	}
+
+	return &pb.MakeOrdersResponse{
+		Results: s.makeBatch(ctx, in.Orders),
+	}, nil
+}
+
+// StreamOrders makes orders in batches of streamBatchSize as they are received, the stream is refused
+// past maxStreamedOrders. Orders made before the stream is broken or refused are not rolled back
+func (s *OrderService) StreamOrders(stream pb.TomShop_StreamOrdersServer) error {
+	ctx := stream.Context()
+	resp := &pb.MakeOrdersResponse{}
+	batch := make([]*pb.BatchOrder, 0, streamBatchSize)
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(resp.Results)+len(batch) == maxStreamedOrders {
+			return streamTooLongErr
+		}
+
+		batch = append(batch, in)
+		if len(batch) == streamBatchSize {
+			resp.Results = append(resp.Results, s.makeBatch(ctx, batch)...)
+			batch = batch[:0]
+		}
+	}
+	resp.Results = append(resp.Results, s.makeBatch(ctx, batch)...)
+
+	return stream.SendAndClose(resp)
+}
+
+// makeBatch makes orders in request order so they are served first come first serve,
+// they are saved together by domain.OrderService.PlaceOrders once the batch is admitted
+func (s *OrderService) makeBatch(ctx context.Context, orders []*pb.BatchOrder) []*pb.BatchOrderResult {
+	results := make([]*pb.BatchOrderResult, len(orders))
+	var cmds []domain.OrderCommand
+	var placed []int
+	for i, o := range orders {
+		results[i] = &pb.BatchOrderResult{ClientID: o.ClientID}
+
+		var err error
+		switch {
+		case ctx.Err() == context.Canceled:
+			err = status.Error(codes.Canceled, ctx.Err().Error())
+		case ctx.Err() == context.DeadlineExceeded:
+			err = status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+		case o.Order == nil:
+			err = emptyOrderErr
+		default:
+			if err = s.validate(o.Order); err == nil {
+				cmds = append(cmds, toOrderCommand(toV2OrderRequest(o.Order)))
+				placed = append(placed, i)
+				continue
+			}
+		}
+		setBatchResult(results[i], nil, err)
+	}
+
+	if len(cmds) == 0 {
+		return results
+	}
+
+	done := func(error) {}
+	if s.Admission != nil {
+		method, _ := grpc.Method(ctx)
+		var err error
+		if done, err = interceptors.Admit(s.Admission, method, admission.Critical); err != nil {
+			for _, i := range placed {
+				setBatchResult(results[i], nil, err)
+			}
+			return results
+		}
+	}
+
+	// the batch counts as overloaded if any of its orders timed out or found the DB unavailable
+	var overloaded error
+	defer func() { done(overloaded) }()
+
+	placedResults, errs := s.Orders.PlaceOrders(ctx, cmds)
+	for j, i := range placed {
+		resp, err := toMakeOrderResponse(toPlaceOrderResponse(ctx, placedResults[j], errs[j]))
+		setBatchResult(results[i], resp, err)
+		if c := status.Code(err); c == codes.DeadlineExceeded || c == codes.Unavailable {
+			overloaded = err
+		}
+	}
+
+	return results
+}
+
+// validate an order of a batch, interceptors skip batches so an invalid order fails alone
+func (s *OrderService) validate(o *pb.OrderRequest) error {
+	if s.Validator == nil {
+		return nil
+	}
+
+	return interceptors.ValidateMessage(s.Validator, o)
+}
+
+func setBatchResult(result *pb.BatchOrderResult, resp *pb.OrderResponse, err error) {
+	result.Response = resp
+	if err != nil {
+		st := status.Convert(err)
+		result.Code = int32(st.Code())
+		result.Message = st.Message()
+	}
+}
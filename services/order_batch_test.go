@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"tomshop/admission"
+	"tomshop/domain"
+	pb "tomshop/grpc"
+	"tomshop/repositories"
+
+	"buf.build/go/protovalidate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOrderService_MakeOrders(t *testing.T) {
+	t.Run("expecting gRPC InvalidArgument error if batch too large", errorWhenBatchTooLarge)
+	t.Run("expecting independent results in request order", independentResultsInBatch)
+	t.Run("expecting one by one without batch repo", oneByOneWithoutBatchRepo)
+	t.Run("expecting invalid order failing alone", invalidOrderFailingAlone)
+}
+
+func TestOrderService_StreamOrders(t *testing.T) {
+	t.Run("expecting orders made in batches", streamInBatches)
+	t.Run("expecting gRPC ResourceExhausted error if stream too long", errorWhenStreamTooLong)
+	t.Run("expecting batches refused at the admission limit", batchRefusedAtAdmissionLimit)
+}
+
+func streamInBatches(t *testing.T) {
+	repo, listed := newStockRepo(map[int64]int64{1: 250})
+	s := &OrderService{Orders: &domain.OrderService{Repo: repo, Batches: mockBatchRepo{repo}}}
+
+	stream := &mockOrderStream{ctx: context.Background()}
+	for i := 0; i < streamBatchSize+50; i++ {
+		stream.orders = append(stream.orders, &pb.BatchOrder{
+			ClientID: "order",
+			Order:    &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}}},
+		})
+	}
+
+	if err := s.StreamOrders(stream); err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if len(stream.resp.Results) != streamBatchSize+50 {
+		t.Fatal("expecting a result per order, got", len(stream.resp.Results))
+	}
+
+	for i, r := range stream.resp.Results {
+		expected := codes.OK
+		if i >= 125 {
+			expected = codes.FailedPrecondition
+		}
+
+		if codes.Code(r.Code) != expected {
+			t.Errorf("expecting order %d %s, got %s", i, expected, codes.Code(r.Code))
+		}
+	}
+
+	if len(*listed) != 2 {
+		t.Error("expecting inventories listed once per batch, got", len(*listed))
+	}
+}
+
+func batchRefusedAtAdmissionLimit(t *testing.T) {
+	repo, listed := newStockRepo(map[int64]int64{1: 10})
+	l := admission.NewLimiter(time.Second)
+	s := &OrderService{Orders: &domain.OrderService{Repo: repo, Batches: mockBatchRepo{repo}}, Admission: l}
+
+	var held []func(bool)
+	for {
+		done, ok := l.Acquire(admission.Critical)
+		if !ok {
+			break
+		}
+		held = append(held, done)
+	}
+
+	stream := func() *pb.MakeOrdersResponse {
+		stream := &mockOrderStream{ctx: context.Background(), orders: []*pb.BatchOrder{
+			{ClientID: "order", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}}}},
+		}}
+		if err := s.StreamOrders(stream); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		return stream.resp
+	}
+
+	if resp := stream(); codes.Code(resp.Results[0].Code) != codes.Unavailable || len(*listed) != 0 {
+		t.Error("expecting order refused as Unavailable without reading stock, got", resp.Results, *listed)
+	}
+
+	held[0](false)
+	if resp := stream(); resp.Results[0].Code != 0 || len(*listed) != 1 {
+		t.Error("expecting order made once admitted, got", resp.Results, *listed)
+	}
+}
+
+func errorWhenStreamTooLong(t *testing.T) {
+	s := &OrderService{Orders: &domain.OrderService{}}
+
+	// empty orders fail without the repo
+	stream := &mockOrderStream{ctx: context.Background(), orders: make([]*pb.BatchOrder, maxStreamedOrders+1)}
+	for i := range stream.orders {
+		stream.orders[i] = &pb.BatchOrder{}
+	}
+
+	if err := s.StreamOrders(stream); status.Code(err) != codes.ResourceExhausted {
+		t.Error("expecting ResourceExhausted, got", err)
+	}
+
+	if stream.resp != nil {
+		t.Error("unexpected reply", len(stream.resp.Results))
+	}
+}
+
+func errorWhenBatchTooLarge(t *testing.T) {
+	s := &OrderService{Orders: &domain.OrderService{}}
+
+	_, err := s.MakeOrders(context.Background(), &pb.MakeOrdersRequest{
+		Orders: make([]*pb.BatchOrder, maxBatchSize+1),
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("expecting InvalidArgument, got", err)
+	}
+}
+
+func independentResultsInBatch(t *testing.T) {
+	repo, listed := newStockRepo(map[int64]int64{1: 3, 2: 1})
+	s := &OrderService{Orders: &domain.OrderService{Repo: repo, Batches: mockBatchRepo{repo}}}
+
+	resp, err := s.MakeOrders(context.Background(), &pb.MakeOrdersRequest{
+		Orders: []*pb.BatchOrder{
+			{ClientID: "a", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}}}},
+			{ClientID: "b", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 2, Quantity: 1}}}},
+			{ClientID: "c", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}}}},
+			{ClientID: "d"},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := []struct {
+		clientID string
+		code     codes.Code
+	}{
+		{"a", codes.OK},
+		{"b", codes.OK},
+		{"c", codes.FailedPrecondition},
+		{"d", codes.InvalidArgument},
+	}
+	for i, e := range expected {
+		r := resp.Results[i]
+		if r.ClientID != e.clientID || codes.Code(r.Code) != e.code {
+			t.Errorf("expecting %s %s, got %s %s", e.clientID, e.code, r.ClientID, codes.Code(r.Code))
+		}
+	}
+
+	// the batch is read once, "d" is not even read
+	if !reflect.DeepEqual(*listed, [][]int64{{1, 2}}) {
+		t.Error("expecting inventories of the batch listed once, got", *listed)
+	}
+}
+
+func oneByOneWithoutBatchRepo(t *testing.T) {
+	repo, listed := newStockRepo(map[int64]int64{1: 3, 2: 1})
+	s := &OrderService{Orders: &domain.OrderService{Repo: repo}}
+
+	_, err := s.MakeOrders(context.Background(), &pb.MakeOrdersRequest{
+		Orders: []*pb.BatchOrder{
+			{ClientID: "a", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}}}},
+			{ClientID: "b", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 2, Quantity: 1}}}},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if !reflect.DeepEqual(*listed, [][]int64{{1}, {2}}) {
+		t.Error("expecting inventories listed per order, got", *listed)
+	}
+}
+
+func invalidOrderFailingAlone(t *testing.T) {
+	v, err := protovalidate.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, _ := newStockRepo(map[int64]int64{1: 3})
+	s := &OrderService{Orders: &domain.OrderService{Repo: repo, Batches: mockBatchRepo{repo}}, Validator: v}
+
+	resp, err := s.MakeOrders(context.Background(), &pb.MakeOrdersRequest{
+		Orders: []*pb.BatchOrder{
+			{ClientID: "a", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 1}}}},
+			{ClientID: "b", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1}}}},
+			{ClientID: "c", Order: &pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 1, Quantity: 2}}}},
+		},
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := []codes.Code{codes.OK, codes.InvalidArgument, codes.OK}
+	for i, e := range expected {
+		if r := resp.Results[i]; codes.Code(r.Code) != e {
+			t.Errorf("expecting %s %s, got %s %s", r.ClientID, e, codes.Code(r.Code), r.Message)
+		}
+	}
+}
+
+// newStockRepo takes stock by orders, listed records ids of every ListInventories
+func newStockRepo(stock map[int64]int64) (mockRepo, *[][]int64) {
+	listed := &[][]int64{}
+	return mockRepo{
+		listInventories: func(_ context.Context, ids []int64) ([]repositories.Inventory, error) {
+			*listed = append(*listed, ids)
+			var inventories []repositories.Inventory
+			for _, id := range ids {
+				if count, ok := stock[id]; ok {
+					inventories = append(inventories, repositories.Inventory{ProductID: id, StockCount: count})
+				}
+			}
+			return inventories, nil
+		},
+		adjustInventories: func(_ context.Context, orders []repositories.Order, _ ...repositories.AdjustOption) error {
+			for _, o := range orders {
+				if stock[o.ProductID] < o.Quantity {
+					return mockAdjustError{}
+				}
+				stock[o.ProductID] -= o.Quantity
+			}
+			return nil
+		},
+	}, listed
+}
+
+// mockBatchRepo reads stock of the whole batch once, every plan sees what previous ones took
+type mockBatchRepo struct {
+	mockRepo
+}
+
+func (r mockBatchRepo) PlaceOrders(ctx context.Context, placements []repositories.OrderPlacement) []error {
+	errs := make([]error, len(placements))
+	var ids []int64
+	seen := map[int64]bool{}
+	for _, p := range placements {
+		for _, id := range p.Query.ProductIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	inventories, err := r.ListInventories(ctx, ids)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	for i, p := range placements {
+		snapshot := repositories.OrderSnapshot{}
+		for _, inv := range inventories {
+			for _, id := range p.Query.ProductIDs {
+				if inv.ProductID == id {
+					snapshot.Inventories = append(snapshot.Inventories, inv)
+				}
+			}
+		}
+
+		orders, opts, err := p.Plan(snapshot)
+		if err == nil {
+			err = r.AdjustInventories(ctx, orders, opts...)
+		}
+		if errs[i] = err; err != nil {
+			continue
+		}
+
+		for _, o := range orders {
+			for j := range inventories {
+				if inventories[j].ProductID == o.ProductID {
+					inventories[j].StockCount -= o.Quantity
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+type mockOrderStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	orders []*pb.BatchOrder
+	resp   *pb.MakeOrdersResponse
+}
+
+func (s *mockOrderStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockOrderStream) Recv() (*pb.BatchOrder, error) {
+	if len(s.orders) == 0 {
+		return nil, io.EOF
+	}
+
+	o := s.orders[0]
+	s.orders = s.orders[1:]
+	return o, nil
+}
+
+func (s *mockOrderStream) SendAndClose(resp *pb.MakeOrdersResponse) error {
+	s.resp = resp
+	return nil
+}
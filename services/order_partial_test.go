@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"tomshop/allocation"
+	"tomshop/domain"
+	pb "tomshop/grpc"
+	"tomshop/repositories"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOrderService_MakeOrder_Partial(t *testing.T) {
+	t.Run("expecting per line outcomes in BEST_EFFORT mode",
+		partialBestEffort)
+	t.Run("expecting whole quantity as minimum if not set in PER_LINE_MINIMUM mode",
+		partialDefaultMinimum)
+	t.Run("expecting gRPC FailedPrecondition error if nothing can be taken",
+		partialNothingTaken)
+	t.Run("expecting gRPC InvalidArgument error if minimum greater than quantity",
+		partialInvalidMinimum)
+	t.Run("expecting taken quantities allocated when using warehouses",
+		partialWithWarehouses)
+	t.Run("expecting coupons no longer applying to taken lines not redeemed",
+		partialCouponsNotRedeemed)
+}
+
+var partialInventories = func(context.Context, []int64) ([]repositories.Inventory, error) {
+	return []repositories.Inventory{
+		{
+			ProductID:  1,
+			StockCount: 3,
+			Price:      10,
+		},
+		{
+			ProductID: 2,
+			Price:     20,
+		},
+	}, nil
+}
+
+func partialBestEffort(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: partialInventories,
+				adjustInventories: func(_ context.Context, orders []repositories.Order, _ ...repositories.AdjustOption) error {
+					if !reflect.DeepEqual(orders, []repositories.Order{{ProductID: 1, Quantity: 3}}) {
+						t.Error("expecting only what in stock taken, got", orders)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  5,
+			},
+			{
+				ProductID: 2,
+				Quantity:  1,
+			},
+		},
+		FulfillmentMode: pb.FulfillmentMode_BEST_EFFORT,
+	})
+
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	expected := &pb.OrderResponse{
+		Successful: true,
+		Subtotal:   30,
+		Total:      30,
+		Lines: []*pb.LineResult{
+			{
+				ProductID:   1,
+				Immediate:   3,
+				Unfulfilled: 2,
+			},
+			{
+				ProductID:   2,
+				Unfulfilled: 1,
+			},
+		},
+	}
+	if resp.OrderID == "" {
+		t.Error("expecting order ID")
+	}
+	expected.OrderID = resp.OrderID
+	if !proto.Equal(resp, expected) {
+		t.Error("expecting partial response, got", resp)
+	}
+}
+
+func partialCouponsNotRedeemed(t *testing.T) {
+	var saved repositories.AdjustOptions
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: partialInventories,
+				listPromotions: func(context.Context, []string) ([]repositories.Promotion, error) {
+					return []repositories.Promotion{
+						{Code: "ALL", Kind: repositories.PercentageDiscount, Value: 10},
+						{Code: "TWO", Kind: repositories.FixedDiscount, Value: 5, ProductID: 2, UsageLimit: 1},
+					}, nil
+				},
+				adjustInventories: func(_ context.Context, _ []repositories.Order, opts ...repositories.AdjustOption) error {
+					for _, opt := range opts {
+						opt(&saved)
+					}
+					return nil
+				},
+			},
+		},
+	}
+
+	// product 2 is out of stock so its coupon doesn't apply to what is taken
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  3,
+			},
+			{
+				ProductID: 2,
+				Quantity:  1,
+			},
+		},
+		CouponCodes:     []string{"ALL", "TWO"},
+		FulfillmentMode: pb.FulfillmentMode_BEST_EFFORT,
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if resp.Discount != 3 || resp.Total != 27 {
+		t.Error("expecting discount of ALL only, got", resp.Discount, resp.Total)
+	}
+
+	if len(saved.Redemptions) != 1 || saved.Redemptions[0].Code != "ALL" {
+		t.Error("expecting only ALL redeemed, got", saved.Redemptions)
+	}
+}
+
+func partialDefaultMinimum(t *testing.T) {
+	var saved []repositories.Order
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: partialInventories,
+				adjustInventories: func(_ context.Context, orders []repositories.Order, _ ...repositories.AdjustOption) error {
+					saved = orders
+					return nil
+				},
+			},
+		},
+	}
+
+	_, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID:   1,
+				Quantity:    5,
+				MinQuantity: 2,
+			},
+			{
+				ProductID: 2,
+				Quantity:  3,
+			},
+		},
+		FulfillmentMode: pb.FulfillmentMode_PER_LINE_MINIMUM,
+	})
+
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	// product 2 has no stock for its default minimum 3
+	if !reflect.DeepEqual(saved, []repositories.Order{{ProductID: 1, Quantity: 3}}) {
+		t.Error("expecting 3 items of product 1 taken, got", saved)
+	}
+}
+
+func partialNothingTaken(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: partialInventories,
+				adjustInventories: func(context.Context, []repositories.Order, ...repositories.AdjustOption) error {
+					t.Error("unexpected adjust")
+					return nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 2,
+				Quantity:  5,
+			},
+		},
+		FulfillmentMode: pb.FulfillmentMode_BEST_EFFORT,
+	})
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Error("expecting gRPC FailedPrecondition error, got", err)
+	}
+
+	if resp.Successful {
+		t.Error("expecting failed response, got", resp)
+	}
+}
+
+func partialInvalidMinimum(t *testing.T) {
+	s := &OrderService{Orders: &domain.OrderService{Repo: mockRepo{}}}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID:   1,
+				Quantity:    1,
+				MinQuantity: 2,
+			},
+		},
+		FulfillmentMode: pb.FulfillmentMode_PER_LINE_MINIMUM,
+	})
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("expecting gRPC InvalidArgument error, got", err)
+	}
+
+	if resp.Successful {
+		t.Error("expecting failed response, got", resp)
+	}
+}
+
+func partialWithWarehouses(t *testing.T) {
+	var saved []repositories.Order
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: partialInventories,
+				listWarehouseStocks: func(context.Context, []int64) ([]repositories.WarehouseStock, error) {
+					return []repositories.WarehouseStock{
+						{
+							ProductID:   1,
+							WarehouseID: 7,
+							StockCount:  3,
+						},
+					}, nil
+				},
+				adjustInventories: func(_ context.Context, orders []repositories.Order, _ ...repositories.AdjustOption) error {
+					saved = orders
+					return nil
+				},
+			},
+			Allocator: allocation.FewestShipments{},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  5,
+			},
+		},
+		FulfillmentMode: pb.FulfillmentMode_BEST_EFFORT,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	expected := []*pb.Allocation{{ProductID: 1, WarehouseID: 7, Quantity: 3}}
+	if !proto.Equal(&pb.OrderResponse{Allocations: resp.Allocations}, &pb.OrderResponse{Allocations: expected}) {
+		t.Error("expecting taken quantity allocated, got", resp.Allocations)
+	}
+
+	if len(saved) != 1 || len(saved[0].Allocations) != 1 {
+		t.Error("expecting allocations saved, got", saved)
+	}
+}
+
+type mockAdjustError struct{}
+
+func (mockAdjustError) Error() string {
+	return "dummyAdjustError"
+}
+
+func (mockAdjustError) ProductID() int64 {
+	return 1
+}
@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"testing"
 
+	"tomshop/allocation"
+	"tomshop/domain"
 	pb "tomshop/grpc"
+	pbv2 "tomshop/grpc/v2"
 	"tomshop/repositories"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestOrderService_MakeOrder(t *testing.T) {
@@ -17,19 +21,205 @@ func TestOrderService_MakeOrder(t *testing.T) {
 		errorWhenListInventories)
 	t.Run("expecting gRPC Internal error if got error when AdjustInventories",
 		errorWhenAdjustInventories)
+	t.Run("expecting gRPC Aborted error if AdjustInventories gave up retrying",
+		abortedWhenRetriesExhausted)
 	t.Run("expecting gRPC FailedPrecondition error if don't have enough products",
 		errorWhenAvailableInventoriesMissingProduct)
 	t.Run("expecting gRPC FailedPrecondition error if products don't have enough items",
 		errorWhenAvailableInventoriesNotEnough)
 	t.Run("expecting gRPC InvalidArgument error if request negative qty",
 		errorWhenRequestNegativeQty)
+	t.Run("expecting gRPC InvalidArgument error if coupon not found",
+		errorWhenCouponNotFound)
+	t.Run("expecting gRPC FailedPrecondition error if coupon cannot be redeemed",
+		errorWhenCouponRedeemFailed)
+	t.Run("expecting discounted totals and redemptions when using coupon",
+		discountWhenUsingCoupon)
+	t.Run("expecting gRPC FailedPrecondition error if warehouses don't have enough items",
+		errorWhenWarehousesNotEnough)
+	t.Run("expecting allocations saved and returned when using warehouses",
+		allocationsWhenUsingWarehouses)
+	t.Run("expecting backordered quantity when client allows backorder",
+		backorderWhenAllowed)
+}
+
+func TestOrderService_PlaceOrder(t *testing.T) {
+	t.Run("expecting rejection of the line products don't have enough items",
+		rejectedWhenLineOutOfStock)
+	t.Run("expecting rejection if coupon not found",
+		rejectedWhenCouponNotFound)
+	t.Run("expecting totals and allocations of every line when placed",
+		placedWithLineAllocations)
+}
+
+func rejectedWhenLineOutOfStock(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+						},
+						{
+							ProductID:  2,
+							StockCount: 21,
+						},
+					}, nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.PlaceOrder(context.Background(), &pbv2.PlaceOrderRequest{
+		Lines: []*pbv2.OrderLine{
+			{
+				ProductID: 1,
+				Quantity:  11,
+			},
+			{
+				ProductID: 2,
+				Quantity:  22,
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if resp.Status != pbv2.OrderStatus_REJECTED || resp.OrderID != "" ||
+		resp.Rejection.Reason != pbv2.RejectionReason_OUT_OF_STOCK || resp.Rejection.ProductID != 2 {
+		t.Error("expecting rejected product 2, got", resp)
+	}
+}
+
+func rejectedWhenCouponNotFound(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Price:      100,
+						},
+					}, nil
+				},
+				listPromotions: func(context.Context, []string) ([]repositories.Promotion, error) {
+					return nil, nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.PlaceOrder(context.Background(), &pbv2.PlaceOrderRequest{
+		Lines: []*pbv2.OrderLine{
+			{
+				ProductID: 1,
+				Quantity:  1,
+			},
+		},
+		CouponCodes: []string{"NOPE"},
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if resp.Status != pbv2.OrderStatus_REJECTED || resp.Rejection.Reason != pbv2.RejectionReason_UNKNOWN_COUPON || resp.Rejection.Message == "" {
+		t.Error("expecting unknown coupon rejection, got", resp)
+	}
+}
+
+func placedWithLineAllocations(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Price:      100,
+						},
+					}, nil
+				},
+				listWarehouseStocks: func(context.Context, []int64) ([]repositories.WarehouseStock, error) {
+					return []repositories.WarehouseStock{
+						{
+							ProductID:   1,
+							WarehouseID: 1,
+							StockCount:  1,
+						},
+						{
+							ProductID:   1,
+							WarehouseID: 2,
+							StockCount:  10,
+						},
+					}, nil
+				},
+				adjustInventories: func(context.Context, []repositories.Order, ...repositories.AdjustOption) error {
+					return nil
+				},
+			},
+			Allocator: allocation.PreferredWarehouse{},
+		},
+	}
+
+	resp, err := s.PlaceOrder(context.Background(), &pbv2.PlaceOrderRequest{
+		Lines: []*pbv2.OrderLine{
+			{
+				ProductID: 1,
+				Quantity:  2,
+			},
+		},
+		PreferredWarehouseID: 1,
+	})
+
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+
+	if resp.Status != pbv2.OrderStatus_PLACED || resp.OrderID == "" || resp.Rejection != nil {
+		t.Error("expecting placed order, got", resp)
+	}
+
+	if !proto.Equal(resp.Totals, &pbv2.Totals{Subtotal: 200, Total: 200}) {
+		t.Error("expecting totals of 2 items, got", resp.Totals)
+	}
+
+	expected := []*pbv2.LineResult{
+		{
+			ProductID: 1,
+			Requested: 2,
+			Immediate: 2,
+			Allocations: []*pbv2.Allocation{
+				{
+					WarehouseID: 1,
+					Quantity:    1,
+				},
+				{
+					WarehouseID: 2,
+					Quantity:    1,
+				},
+			},
+		},
+	}
+	if !proto.Equal(&pbv2.PlaceOrderResponse{Lines: resp.Lines}, &pbv2.PlaceOrderResponse{Lines: expected}) {
+		t.Error("expecting allocations in line, got", resp.Lines)
+	}
 }
 
 func errorWhenListInventories(t *testing.T) {
 	s := &OrderService{
-		Repo: mockRepo{
-			listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
-				return nil, fmt.Errorf("dummyListInventoriesError")
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return nil, fmt.Errorf("dummyListInventoriesError")
+				},
 			},
 		},
 	}
@@ -54,18 +244,20 @@ func errorWhenListInventories(t *testing.T) {
 
 func errorWhenAdjustInventories(t *testing.T) {
 	s := &OrderService{
-		Repo: mockRepo{
-			listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
-				return []repositories.Inventory{
-					{
-						ProductID:  1,
-						StockCount: 11,
-						Version:    111,
-					},
-				}, nil
-			},
-			adjustInventories: func(context.Context, []repositories.Order) error {
-				return fmt.Errorf("dummyAdjustInventoriesError")
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Version:    111,
+						},
+					}, nil
+				},
+				adjustInventories: func(context.Context, []repositories.Order, ...repositories.AdjustOption) error {
+					return fmt.Errorf("dummyAdjustInventoriesError")
+				},
 			},
 		},
 	}
@@ -88,17 +280,42 @@ func errorWhenAdjustInventories(t *testing.T) {
 	}
 }
 
+func abortedWhenRetriesExhausted(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{{ProductID: 1, StockCount: 11}}, nil
+				},
+				adjustInventories: func(context.Context, []repositories.Order, ...repositories.AdjustOption) error {
+					return mockRetriesExhaustedError{}
+				},
+			},
+		},
+	}
+
+	_, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{{ProductID: 1, Quantity: 1}},
+	})
+
+	if status.Code(err) != codes.Aborted {
+		t.Error("expecting gRPC Aborted error, got", err)
+	}
+}
+
 func errorWhenAvailableInventoriesMissingProduct(t *testing.T) {
 	s := &OrderService{
-		Repo: mockRepo{
-			listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
-				return []repositories.Inventory{
-					{
-						ProductID:  1,
-						StockCount: 11,
-						Version:    111,
-					},
-				}, nil
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Version:    111,
+						},
+					}, nil
+				},
 			},
 		},
 	}
@@ -127,20 +344,22 @@ func errorWhenAvailableInventoriesMissingProduct(t *testing.T) {
 
 func errorWhenAvailableInventoriesNotEnough(t *testing.T) {
 	s := &OrderService{
-		Repo: mockRepo{
-			listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
-				return []repositories.Inventory{
-					{
-						ProductID:  1,
-						StockCount: 11,
-						Version:    111,
-					},
-					{
-						ProductID:  2,
-						StockCount: 21,
-						Version:    222,
-					},
-				}, nil
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Version:    111,
+						},
+						{
+							ProductID:  2,
+							StockCount: 21,
+							Version:    222,
+						},
+					}, nil
+				},
 			},
 		},
 	}
@@ -169,13 +388,15 @@ func errorWhenAvailableInventoriesNotEnough(t *testing.T) {
 
 func errorWhenRequestNegativeQty(t *testing.T) {
 	s := &OrderService{
-		Repo: mockRepo{
-			listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
-				return []repositories.Inventory{
-					{
-						ProductID:  1,
-						StockCount: 11},
-				}, nil
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11},
+					}, nil
+				},
 			},
 		},
 	}
@@ -198,15 +419,425 @@ func errorWhenRequestNegativeQty(t *testing.T) {
 	}
 }
 
+func errorWhenCouponNotFound(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Price:      100,
+						},
+					}, nil
+				},
+				listPromotions: func(context.Context, []string) ([]repositories.Promotion, error) {
+					return nil, nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  1,
+			},
+		},
+		CouponCodes: []string{"NOPE"},
+	})
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Error("expecting gRPC InvalidArgument error, got", err)
+	}
+
+	if resp.Successful {
+		t.Error("expecting failed response, got", resp)
+	}
+}
+
+func errorWhenCouponRedeemFailed(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Price:      100,
+						},
+					}, nil
+				},
+				listPromotions: func(context.Context, []string) ([]repositories.Promotion, error) {
+					return []repositories.Promotion{
+						{
+							Code:       "TEN",
+							Kind:       repositories.PercentageDiscount,
+							Value:      10,
+							UsageLimit: 1,
+						},
+					}, nil
+				},
+				adjustInventories: func(context.Context, []repositories.Order, ...repositories.AdjustOption) error {
+					return mockRedeemError{}
+				},
+			},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  1,
+			},
+		},
+		CouponCodes: []string{"TEN"},
+	})
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Error("expecting gRPC FailedPrecondition error, got", err)
+	}
+
+	if resp.Successful {
+		t.Error("expecting failed response, got", resp)
+	}
+}
+
+func discountWhenUsingCoupon(t *testing.T) {
+	var redemptions []repositories.Redemption
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+							Price:      100,
+						},
+					}, nil
+				},
+				listPromotions: func(context.Context, []string) ([]repositories.Promotion, error) {
+					return []repositories.Promotion{
+						{
+							Code:             "TEN",
+							Kind:             repositories.PercentageDiscount,
+							Value:            10,
+							PerCustomerLimit: 1,
+						},
+					}, nil
+				},
+				adjustInventories: func(_ context.Context, _ []repositories.Order, opts ...repositories.AdjustOption) error {
+					options := repositories.AdjustOptions{}
+					for _, opt := range opts {
+						opt(&options)
+					}
+					redemptions = options.Redemptions
+					return nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  2,
+			},
+		},
+		CustomerID:  7,
+		CouponCodes: []string{"TEN", "TEN"},
+	})
+
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	expected := &pb.OrderResponse{
+		Successful: true,
+		Subtotal:   200,
+		Discount:   20,
+		Total:      180,
+		Lines: []*pb.LineResult{
+			{
+				ProductID: 1,
+				Immediate: 2,
+			},
+		},
+	}
+	if resp.OrderID == "" {
+		t.Error("expecting order ID")
+	}
+	expected.OrderID = resp.OrderID
+	if !proto.Equal(resp, expected) {
+		t.Error("expecting discounted response, got", resp)
+	}
+
+	if len(redemptions) != 1 || redemptions[0].Code != "TEN" || redemptions[0].CustomerID != 7 {
+		t.Error("expecting one redemption of TEN for customer 7, got", redemptions)
+	}
+}
+
+func errorWhenWarehousesNotEnough(t *testing.T) {
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+						},
+					}, nil
+				},
+				listWarehouseStocks: func(context.Context, []int64) ([]repositories.WarehouseStock, error) {
+					return []repositories.WarehouseStock{
+						{
+							ProductID:   1,
+							WarehouseID: 1,
+							StockCount:  1,
+						},
+					}, nil
+				},
+			},
+			Allocator: allocation.FewestShipments{},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  2,
+			},
+		},
+	})
+
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Error("expecting gRPC FailedPrecondition error, got", err)
+	}
+
+	if resp.Successful {
+		t.Error("expecting failed response, got", resp)
+	}
+}
+
+func allocationsWhenUsingWarehouses(t *testing.T) {
+	var saved []repositories.Order
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 11,
+						},
+					}, nil
+				},
+				listWarehouseStocks: func(context.Context, []int64) ([]repositories.WarehouseStock, error) {
+					return []repositories.WarehouseStock{
+						{
+							ProductID:   1,
+							WarehouseID: 1,
+							StockCount:  1,
+						},
+						{
+							ProductID:   1,
+							WarehouseID: 2,
+							StockCount:  10,
+						},
+					}, nil
+				},
+				adjustInventories: func(_ context.Context, orders []repositories.Order, _ ...repositories.AdjustOption) error {
+					saved = orders
+					return nil
+				},
+			},
+			Allocator: allocation.PreferredWarehouse{},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  2,
+			},
+		},
+		PreferredWarehouseID: 1,
+	})
+
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	expected := []*pb.Allocation{
+		{
+			ProductID:   1,
+			WarehouseID: 1,
+			Quantity:    1,
+		},
+		{
+			ProductID:   1,
+			WarehouseID: 2,
+			Quantity:    1,
+		},
+	}
+	if !proto.Equal(&pb.OrderResponse{Allocations: resp.Allocations}, &pb.OrderResponse{Allocations: expected}) {
+		t.Error("expecting allocations from preferred warehouse first, got", resp.Allocations)
+	}
+
+	if len(saved) != 1 || len(saved[0].Allocations) != 2 {
+		t.Error("expecting allocations passed to repository, got", saved)
+	}
+}
+
+func backorderWhenAllowed(t *testing.T) {
+	var saved []repositories.Order
+	s := &OrderService{
+		Orders: &domain.OrderService{
+			Repo: mockRepo{
+				listInventories: func(context.Context, []int64) ([]repositories.Inventory, error) {
+					return []repositories.Inventory{
+						{
+							ProductID:  1,
+							StockCount: 2,
+						},
+					}, nil
+				},
+				listPolicies: func(context.Context, []int64) ([]repositories.FulfillmentPolicy, error) {
+					return []repositories.FulfillmentPolicy{
+						{
+							ProductID: 1,
+							Kind:      repositories.Backorder,
+							Limit:     10,
+						},
+					}, nil
+				},
+				adjustInventories: func(_ context.Context, orders []repositories.Order, _ ...repositories.AdjustOption) error {
+					saved = orders
+					return nil
+				},
+			},
+		},
+	}
+
+	resp, err := s.MakeOrder(context.Background(), &pb.OrderRequest{
+		Purchases: []*pb.Order{
+			{
+				ProductID: 1,
+				Quantity:  5,
+			},
+		},
+		AllowBackorder: true,
+	})
+
+	if err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	expected := []*pb.LineResult{
+		{
+			ProductID:   1,
+			Immediate:   2,
+			Backordered: 3,
+		},
+	}
+	if !proto.Equal(&pb.OrderResponse{Lines: resp.Lines}, &pb.OrderResponse{Lines: expected}) {
+		t.Error("expecting 2 immediate and 3 backordered items, got", resp.Lines)
+	}
+
+	if len(saved) != 1 || saved[0].Quantity != 2 || saved[0].Backordered != 3 {
+		t.Error("expecting backordered quantity passed to repository, got", saved)
+	}
+}
+
+type mockRedeemError struct{}
+
+func (mockRedeemError) Error() string {
+	return "dummyRedeemError"
+}
+
+func (mockRedeemError) Code() string {
+	return "TEN"
+}
+
+type mockRetriesExhaustedError struct{}
+
+func (mockRetriesExhaustedError) Error() string {
+	return "dummyRetriesExhaustedError"
+}
+
+func (mockRetriesExhaustedError) Retries() int {
+	return 10
+}
+
 type mockRepo struct {
-	listInventories   func(context.Context, []int64) ([]repositories.Inventory, error)
-	adjustInventories func(context.Context, []repositories.Order) error
+	listInventories     func(context.Context, []int64) ([]repositories.Inventory, error)
+	adjustInventories   func(context.Context, []repositories.Order, ...repositories.AdjustOption) error
+	listPromotions      func(context.Context, []string) ([]repositories.Promotion, error)
+	listWarehouseStocks func(context.Context, []int64) ([]repositories.WarehouseStock, error)
+	listPolicies        func(context.Context, []int64) ([]repositories.FulfillmentPolicy, error)
 }
 
 func (r mockRepo) ListInventories(ctx context.Context, ids []int64) ([]repositories.Inventory, error) {
 	return r.listInventories(ctx, ids)
 }
 
-func (r mockRepo) AdjustInventories(ctx context.Context, i []repositories.Order) error {
-	return r.adjustInventories(ctx, i)
+func (r mockRepo) AdjustInventories(ctx context.Context, i []repositories.Order, opts ...repositories.AdjustOption) error {
+	return r.adjustInventories(ctx, i, opts...)
+}
+
+func (r mockRepo) ListWarehouseStocks(ctx context.Context, ids []int64) ([]repositories.WarehouseStock, error) {
+	return r.listWarehouseStocks(ctx, ids)
+}
+
+// ListFulfillmentPolicies returns no policy if not mocked
+func (r mockRepo) ListFulfillmentPolicies(ctx context.Context, ids []int64) ([]repositories.FulfillmentPolicy, error) {
+	if r.listPolicies == nil {
+		return nil, nil
+	}
+	return r.listPolicies(ctx, ids)
+}
+
+func (r mockRepo) ListPromotions(ctx context.Context, codes []string) ([]repositories.Promotion, error) {
+	return r.listPromotions(ctx, codes)
+}
+
+// PlaceOrder plans with a snapshot read by the list functions then saves by adjustInventories
+func (r mockRepo) PlaceOrder(ctx context.Context, q repositories.OrderQuery, plan repositories.OrderPlan) error {
+	snapshot := repositories.OrderSnapshot{}
+	var err error
+	if snapshot.Inventories, err = r.ListInventories(ctx, q.ProductIDs); err != nil {
+		return err
+	}
+
+	if snapshot.Policies, err = r.ListFulfillmentPolicies(ctx, q.ProductIDs); err != nil {
+		return err
+	}
+
+	if len(q.CouponCodes) > 0 {
+		if snapshot.Promotions, err = r.ListPromotions(ctx, q.CouponCodes); err != nil {
+			return err
+		}
+	}
+
+	if q.WarehouseStocks {
+		if snapshot.WarehouseStocks, err = r.ListWarehouseStocks(ctx, q.ProductIDs); err != nil {
+			return err
+		}
+	}
+
+	orders, opts, err := plan(snapshot)
+	if err != nil || len(orders) == 0 {
+		return err
+	}
+
+	return r.AdjustInventories(ctx, orders, opts...)
 }
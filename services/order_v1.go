@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+
+	pb "tomshop/grpc"
+	pbv2 "tomshop/grpc/v2"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// v1Errors of rejections, v1 reports every unsuccessful order as an error
+var v1Errors = map[pbv2.RejectionReason]error{
+	pbv2.RejectionReason_OUT_OF_STOCK:          notEnoughStockErr,
+	pbv2.RejectionReason_UNKNOWN_COUPON:        invalidCouponErr,
+	pbv2.RejectionReason_COUPON_NOT_APPLICABLE: couponNotApplicableErr,
+}
+
+// MakeOrder of v1 is PlaceOrder of v2 returning rejections as errors
+func (s *OrderService) MakeOrder(ctx context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
+	return toMakeOrderResponse(s.PlaceOrder(ctx, toV2OrderRequest(in)))
+}
+
+// toMakeOrderResponse of v2 PlaceOrder
+func toMakeOrderResponse(resp *pbv2.PlaceOrderResponse, err error) (*pb.OrderResponse, error) {
+	if err != nil {
+		return &pb.OrderResponse{
+			Successful: false,
+		}, err
+	}
+
+	if resp.Status == pbv2.OrderStatus_REJECTED {
+		err, ok := v1Errors[resp.Rejection.Reason]
+		if !ok {
+			err = status.Error(codes.FailedPrecondition, resp.Rejection.Message)
+		}
+
+		return &pb.OrderResponse{
+			Successful: false,
+		}, err
+	}
+
+	return toV1OrderResponse(resp), nil
+}
+
+func toV2OrderRequest(in *pb.OrderRequest) *pbv2.PlaceOrderRequest {
+	lines := make([]*pbv2.OrderLine, len(in.Purchases))
+	for i, p := range in.Purchases {
+		lines[i] = &pbv2.OrderLine{
+			ProductID:   p.ProductID,
+			Quantity:    p.Quantity,
+			MinQuantity: p.MinQuantity,
+		}
+	}
+
+	return &pbv2.PlaceOrderRequest{
+		Lines:                lines,
+		CustomerID:           in.CustomerID,
+		CouponCodes:          in.CouponCodes,
+		RegionHint:           in.RegionHint,
+		PreferredWarehouseID: in.PreferredWarehouseID,
+		AllowBackorder:       in.AllowBackorder,
+		// values of both versions are the same
+		FulfillmentMode: pbv2.FulfillmentMode(in.FulfillmentMode),
+	}
+}
+
+func toV1OrderResponse(resp *pbv2.PlaceOrderResponse) *pb.OrderResponse {
+	v1 := &pb.OrderResponse{
+		Successful: resp.Status == pbv2.OrderStatus_PLACED,
+		OrderID:    resp.OrderID,
+		Lines:      make([]*pb.LineResult, len(resp.Lines)),
+	}
+	if resp.Totals != nil {
+		v1.Subtotal = resp.Totals.Subtotal
+		v1.Discount = resp.Totals.Discount
+		v1.Total = resp.Totals.Total
+	}
+
+	for i, l := range resp.Lines {
+		v1.Lines[i] = &pb.LineResult{
+			ProductID:   l.ProductID,
+			Immediate:   l.Immediate,
+			Backordered: l.Backordered,
+			Preorder:    l.Preorder,
+			Unfulfilled: l.Unfulfilled,
+		}
+
+		for _, a := range l.Allocations {
+			v1.Allocations = append(v1.Allocations, &pb.Allocation{
+				ProductID:   l.ProductID,
+				WarehouseID: a.WarehouseID,
+				Quantity:    a.Quantity,
+			})
+		}
+	}
+
+	return v1
+}
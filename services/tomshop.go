@@ -0,0 +1,25 @@
+package services
+
+import (
+	pb "tomshop/grpc"
+	pbv2 "tomshop/grpc/v2"
+)
+
+// TomShop implements grpc tomshop.v1.TomShop and tomshop.v2.TomShop services by combining services,
+// RPCs none of them implements answer Unimplemented
+type TomShop struct {
+	*OrderService
+	*InventoryService
+	*AlertService
+	unimplementedV1
+	unimplementedV2
+}
+
+// unimplementedV1 is embedded a level deeper than services so methods of services take precedence
+type unimplementedV1 struct {
+	pb.UnimplementedTomShopServer
+}
+
+type unimplementedV2 struct {
+	pbv2.UnimplementedTomShopServer
+}
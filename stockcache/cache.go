@@ -0,0 +1,253 @@
+// Package stockcache caches stock of products for reads tolerating a short staleness, e.g. catalog pages.
+// Never use it to decide whether stock can be taken, orders read stock in their transaction
+package stockcache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"tomshop/metrics"
+	"tomshop/repositories"
+)
+
+const (
+	defaultTTL         = time.Second
+	defaultMaxEntries  = 10000
+	defaultReadTimeout = 5 * time.Second
+)
+
+// Repo reads stock on cache misses
+type Repo interface {
+	ListInventories(context.Context, []int64, ...repositories.ReadOption) ([]repositories.Inventory, error)
+}
+
+// Cache reads stock through Repo and keeps it for TTL, the least recently used entries are evicted
+// beyond MaxEntries. Concurrent misses of a product are read once. Writes in this process invalidate
+// what they touch, writes of other processes are seen after TTL at most
+type Cache struct {
+	Repo Repo
+	// TTL of an entry, default 1s
+	TTL time.Duration
+	// MaxEntries kept, default 10000
+	MaxEntries int
+	// ReadTimeout bounds a read shared by concurrent misses, it doesn't end with the caller started it. Default 5s
+	ReadTimeout time.Duration
+
+	mu       sync.Mutex
+	entries  map[int64]*list.Element
+	lru      *list.List
+	inflight map[int64]*call
+	now      func() time.Time
+}
+
+type entry struct {
+	inv     repositories.Inventory
+	found   bool
+	expires time.Time
+}
+
+// cached entry in lru
+type cached struct {
+	entry
+	id int64
+}
+
+// call reads products missed by a ListInventories, others missing the same products wait for it
+type call struct {
+	done    chan struct{}
+	results map[int64]entry
+	err     error
+	// stale is set if the products were invalidated during the read, results are not kept
+	stale map[int64]bool
+}
+
+// NewCache with default TTL and size
+func NewCache(r Repo) *Cache {
+	return &Cache{
+		Repo:        r,
+		TTL:         defaultTTL,
+		MaxEntries:  defaultMaxEntries,
+		ReadTimeout: defaultReadTimeout,
+	}
+}
+
+// ListInventories by ID, omit items that not in DB. Products not cached are read with opts
+func (c *Cache) ListInventories(ctx context.Context, IDs []int64, opts ...repositories.ReadOption) ([]repositories.Inventory, error) {
+	c.mu.Lock()
+	c.init()
+	now := c.now()
+	found := make(map[int64]entry, len(IDs))
+	waits := map[*call]struct{}{}
+	var missed []int64
+	for _, id := range IDs {
+		if _, ok := found[id]; ok {
+			continue
+		}
+
+		if el, ok := c.entries[id]; ok {
+			e := el.Value.(*cached)
+			if now.Before(e.expires) {
+				c.lru.MoveToFront(el)
+				found[id] = e.entry
+				metrics.StockCacheHits.Add(1)
+				continue
+			}
+			c.remove(el)
+		}
+
+		metrics.StockCacheMisses.Add(1)
+		found[id] = entry{}
+		if cl, ok := c.inflight[id]; ok {
+			waits[cl] = struct{}{}
+			continue
+		}
+		missed = append(missed, id)
+	}
+
+	var mine *call
+	if len(missed) > 0 {
+		mine = &call{done: make(chan struct{}), stale: map[int64]bool{}}
+		for _, id := range missed {
+			c.inflight[id] = mine
+		}
+		waits[mine] = struct{}{}
+	}
+	c.mu.Unlock()
+
+	if mine != nil {
+		// others may wait for the read after this caller gave up
+		go c.read(context.WithoutCancel(ctx), mine, missed, opts)
+	}
+
+	for cl := range waits {
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if cl.err != nil {
+			return nil, cl.err
+		}
+
+		for id, e := range cl.results {
+			if _, ok := found[id]; ok {
+				found[id] = e
+			}
+		}
+	}
+
+	results := make([]repositories.Inventory, 0, len(found))
+	seen := make(map[int64]bool, len(found))
+	for _, id := range IDs {
+		if e := found[id]; e.found && !seen[id] {
+			seen[id] = true
+			results = append(results, e.inv)
+		}
+	}
+
+	return results, nil
+}
+
+// Invalidate products changed by this process, it is safe to call on nil Cache
+func (c *Cache) Invalidate(IDs ...int64) {
+	if c == nil || len(IDs) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	for _, id := range IDs {
+		if el, ok := c.entries[id]; ok {
+			c.remove(el)
+		}
+
+		// a read in flight may have seen stock before the change, later misses read again
+		if cl, ok := c.inflight[id]; ok {
+			cl.stale[id] = true
+			delete(c.inflight, id)
+		}
+	}
+}
+
+func (c *Cache) init() {
+	if c.entries == nil {
+		c.entries = make(map[int64]*list.Element)
+		c.lru = list.New()
+		c.inflight = make(map[int64]*call)
+	}
+
+	if c.now == nil {
+		c.now = time.Now
+	}
+}
+
+// read missed products of cl and keep those not invalidated meanwhile
+func (c *Cache) read(ctx context.Context, cl *call, missed []int64, opts []repositories.ReadOption) {
+	timeout := c.ReadTimeout
+	if timeout <= 0 {
+		timeout = defaultReadTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	inventories, err := c.Repo.ListInventories(ctx, missed, opts...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(cl.done)
+	for _, id := range missed {
+		if c.inflight[id] == cl {
+			delete(c.inflight, id)
+		}
+	}
+
+	if err != nil {
+		cl.err = err
+		return
+	}
+
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	expires := c.now().Add(ttl)
+	cl.results = make(map[int64]entry, len(missed))
+	for _, id := range missed {
+		cl.results[id] = entry{expires: expires}
+	}
+	for _, inv := range inventories {
+		cl.results[inv.ProductID] = entry{inv: inv, found: true, expires: expires}
+	}
+
+	for id, e := range cl.results {
+		if !cl.stale[id] {
+			c.store(id, e)
+		}
+	}
+}
+
+func (c *Cache) store(id int64, e entry) {
+	if el, ok := c.entries[id]; ok {
+		el.Value.(*cached).entry = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[id] = c.lru.PushFront(&cached{entry: e, id: id})
+	max := c.MaxEntries
+	if max <= 0 {
+		max = defaultMaxEntries
+	}
+	for c.lru.Len() > max {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cached).id)
+}
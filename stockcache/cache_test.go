@@ -0,0 +1,217 @@
+package stockcache
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"tomshop/repositories"
+)
+
+type mockRepo struct {
+	listInventories func(context.Context, []int64) ([]repositories.Inventory, error)
+}
+
+func (r mockRepo) ListInventories(ctx context.Context, ids []int64, _ ...repositories.ReadOption) ([]repositories.Inventory, error) {
+	return r.listInventories(ctx, ids)
+}
+
+// countingRepo has stock of every product equal to its ID, reads records ids of every read
+func countingRepo(mu *sync.Mutex, reads *[][]int64) mockRepo {
+	return mockRepo{
+		listInventories: func(_ context.Context, ids []int64) ([]repositories.Inventory, error) {
+			mu.Lock()
+			*reads = append(*reads, ids)
+			mu.Unlock()
+
+			results := []repositories.Inventory{}
+			for _, id := range ids {
+				if id < 100 {
+					results = append(results, repositories.Inventory{ProductID: id, StockCount: id})
+				}
+			}
+			return results, nil
+		},
+	}
+}
+
+func TestCache_ListInventories(t *testing.T) {
+	t.Run("expecting products read once until TTL passed", func(tt *testing.T) {
+		var mu sync.Mutex
+		var reads [][]int64
+		c := NewCache(countingRepo(&mu, &reads))
+		now := time.Now()
+		c.now = func() time.Time { return now }
+
+		for i := 0; i < 2; i++ {
+			invs, err := c.ListInventories(context.Background(), []int64{1, 2, 100, 1})
+			if err != nil {
+				tt.Fatal(err)
+			}
+			want := []repositories.Inventory{{ProductID: 1, StockCount: 1}, {ProductID: 2, StockCount: 2}}
+			if !reflect.DeepEqual(invs, want) {
+				tt.Fatalf("must list %v, got %v", want, invs)
+			}
+		}
+
+		now = now.Add(time.Second)
+		if _, err := c.ListInventories(context.Background(), []int64{2, 3}); err != nil {
+			tt.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(reads, [][]int64{{1, 2, 100}, {2, 3}}) {
+			tt.Error("must read missing and expired products only, got", reads)
+		}
+	})
+
+	t.Run("expecting invalidated products read again", func(tt *testing.T) {
+		var mu sync.Mutex
+		var reads [][]int64
+		c := NewCache(countingRepo(&mu, &reads))
+
+		c.ListInventories(context.Background(), []int64{1, 2})
+		c.Invalidate(2)
+		c.ListInventories(context.Background(), []int64{1, 2})
+
+		if !reflect.DeepEqual(reads, [][]int64{{1, 2}, {2}}) {
+			tt.Error("must read invalidated product again, got", reads)
+		}
+	})
+
+	t.Run("expecting least recently used products evicted", func(tt *testing.T) {
+		var mu sync.Mutex
+		var reads [][]int64
+		c := NewCache(countingRepo(&mu, &reads))
+		c.MaxEntries = 2
+
+		c.ListInventories(context.Background(), []int64{1, 2})
+		c.ListInventories(context.Background(), []int64{1})
+		c.ListInventories(context.Background(), []int64{3})
+		c.ListInventories(context.Background(), []int64{1, 2})
+
+		if !reflect.DeepEqual(reads, [][]int64{{1, 2}, {3}, {2}}) {
+			tt.Error("must evict product 2, got", reads)
+		}
+	})
+
+	t.Run("expecting concurrent misses read once", func(tt *testing.T) {
+		release := make(chan struct{})
+		reads := 0
+		c := NewCache(mockRepo{
+			listInventories: func(_ context.Context, ids []int64) ([]repositories.Inventory, error) {
+				reads++
+				<-release
+				return []repositories.Inventory{{ProductID: 1, StockCount: 5}}, nil
+			},
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				invs, err := c.ListInventories(context.Background(), []int64{1})
+				if err != nil || len(invs) != 1 || invs[0].StockCount != 5 {
+					tt.Error("must list stock 5, got", invs, err)
+				}
+			}()
+		}
+
+		for {
+			c.mu.Lock()
+			waiting := c.inflight[1] != nil
+			c.mu.Unlock()
+			if waiting {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if reads != 1 {
+			tt.Errorf("must read once, got %d", reads)
+		}
+	})
+
+	t.Run("expecting waiters served after the caller started the read gave up", func(tt *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		c := NewCache(mockRepo{
+			listInventories: func(ctx context.Context, ids []int64) ([]repositories.Inventory, error) {
+				close(started)
+				select {
+				case <-release:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				return []repositories.Inventory{{ProductID: 1, StockCount: 5}}, nil
+			},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		leaderErr := make(chan error)
+		go func() {
+			_, err := c.ListInventories(ctx, []int64{1})
+			leaderErr <- err
+		}()
+		<-started
+
+		waited := make(chan []repositories.Inventory)
+		go func() {
+			invs, err := c.ListInventories(context.Background(), []int64{1})
+			if err != nil {
+				tt.Error("unexpected error", err)
+			}
+			waited <- invs
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		cancel()
+		if err := <-leaderErr; err != context.Canceled {
+			tt.Error("expecting caller canceled, got", err)
+		}
+
+		close(release)
+		if invs := <-waited; len(invs) != 1 || invs[0].StockCount != 5 {
+			tt.Error("must list stock 5, got", invs)
+		}
+	})
+
+	t.Run("expecting read in flight while invalidated not kept", func(tt *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		calls := 0
+		c := NewCache(mockRepo{
+			listInventories: func(_ context.Context, ids []int64) ([]repositories.Inventory, error) {
+				calls++
+				if calls == 1 {
+					close(started)
+					<-release
+					return []repositories.Inventory{{ProductID: 1, StockCount: 5}}, nil
+				}
+				return []repositories.Inventory{{ProductID: 1, StockCount: 4}}, nil
+			},
+		})
+
+		done := make(chan struct{})
+		go func() {
+			c.ListInventories(context.Background(), []int64{1})
+			close(done)
+		}()
+
+		// an order took stock while the read was in flight
+		<-started
+		c.Invalidate(1)
+		close(release)
+		<-done
+
+		invs, _ := c.ListInventories(context.Background(), []int64{1})
+		if len(invs) != 1 || invs[0].StockCount != 4 {
+			tt.Error("must read stock 4 again, got", invs)
+		}
+	})
+}
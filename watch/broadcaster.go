@@ -0,0 +1,116 @@
+package watch
+
+import (
+	"sort"
+	"sync"
+
+	"tomshop/repositories"
+)
+
+// Broadcaster tells subscribers the stock products are left with by committed changes, so subscribers never
+// read it back. Publish never blocks: changes not yet taken by a subscriber are coalesced into the latest stock
+// of every product, so the buffer of a slow subscriber is bounded by the number of products it watches.
+// Changes of a product committed concurrently may be published out of order, the next change corrects its stock
+type Broadcaster struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBroadcaster without subscribers
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives changes of the watched products until closed
+type Subscription struct {
+	b       *Broadcaster
+	watched map[int64]struct{}
+
+	mu      sync.Mutex
+	changed map[int64]int64
+	ready   chan struct{}
+}
+
+// Subscribe to changes of productIDs
+func (b *Broadcaster) Subscribe(productIDs []int64) *Subscription {
+	sub := &Subscription{
+		b:       b,
+		watched: make(map[int64]struct{}, len(productIDs)),
+		changed: make(map[int64]int64),
+		ready:   make(chan struct{}, 1),
+	}
+	for _, id := range productIDs {
+		sub.watched[id] = struct{}{}
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Publish stock of products once their change is committed, it is safe to call on nil Broadcaster
+func (b *Broadcaster) Publish(stocks ...repositories.Inventory) {
+	if b == nil || len(stocks) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		sub.notify(stocks)
+	}
+}
+
+func (sub *Subscription) notify(stocks []repositories.Inventory) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	notified := false
+	for _, s := range stocks {
+		if _, ok := sub.watched[s.ProductID]; ok {
+			sub.changed[s.ProductID] = s.StockCount
+			notified = true
+		}
+	}
+
+	if !notified {
+		return
+	}
+
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+		// subscriber has not taken the previous changes, they are coalesced
+	}
+}
+
+// Ready receives when there are changes to Take
+func (sub *Subscription) Ready() <-chan struct{} {
+	return sub.ready
+}
+
+// Take the latest stock of products changed since the last Take in ascending order of product ID
+func (sub *Subscription) Take() []repositories.Inventory {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	stocks := make([]repositories.Inventory, 0, len(sub.changed))
+	for id, count := range sub.changed {
+		stocks = append(stocks, repositories.Inventory{ProductID: id, StockCount: count})
+		delete(sub.changed, id)
+	}
+	sort.Slice(stocks, func(i, j int) bool { return stocks[i].ProductID < stocks[j].ProductID })
+
+	return stocks
+}
+
+// Close stops receiving changes
+func (sub *Subscription) Close() {
+	sub.b.mu.Lock()
+	delete(sub.b.subs, sub)
+	sub.b.mu.Unlock()
+}
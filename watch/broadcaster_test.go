@@ -0,0 +1,82 @@
+package watch
+
+import (
+	"reflect"
+	"testing"
+
+	"tomshop/repositories"
+)
+
+func stock(id, count int64) repositories.Inventory {
+	return repositories.Inventory{ProductID: id, StockCount: count}
+}
+
+func TestBroadcaster(t *testing.T) {
+	t.Run("expecting only watched products taken", takeWatchedOnly)
+	t.Run("expecting changes coalesced into the latest stock for slow subscriber", coalesceSlowSubscriber)
+	t.Run("expecting nothing received after close", nothingAfterClose)
+	t.Run("expecting publish on nil broadcaster does nothing", publishOnNil)
+}
+
+func takeWatchedOnly(t *testing.T) {
+	b := NewBroadcaster()
+	sub := b.Subscribe([]int64{1, 2})
+	defer sub.Close()
+
+	b.Publish(stock(3, 1))
+	select {
+	case <-sub.Ready():
+		t.Fatal("expecting not ready for unwatched product")
+	default:
+	}
+
+	b.Publish(stock(2, 5), stock(3, 1))
+	select {
+	case <-sub.Ready():
+	default:
+		t.Fatal("expecting ready for watched product")
+	}
+
+	if stocks := sub.Take(); !reflect.DeepEqual(stocks, []repositories.Inventory{stock(2, 5)}) {
+		t.Error("expecting stock of 2, got", stocks)
+	}
+}
+
+func coalesceSlowSubscriber(t *testing.T) {
+	b := NewBroadcaster()
+	sub := b.Subscribe([]int64{1, 2, 3})
+	defer sub.Close()
+
+	// publish more than any channel buffer would hold, must not block
+	for i := 0; i < 1000; i++ {
+		b.Publish(stock(int64(i%3+1), int64(i)))
+	}
+
+	<-sub.Ready()
+	expected := []repositories.Inventory{stock(1, 999), stock(2, 997), stock(3, 998)}
+	if stocks := sub.Take(); !reflect.DeepEqual(stocks, expected) {
+		t.Error("expecting latest stock of 1, 2 and 3, got", stocks)
+	}
+
+	if stocks := sub.Take(); len(stocks) != 0 {
+		t.Error("expecting nothing left, got", stocks)
+	}
+}
+
+func nothingAfterClose(t *testing.T) {
+	b := NewBroadcaster()
+	sub := b.Subscribe([]int64{1})
+	sub.Close()
+
+	b.Publish(stock(1, 1))
+	select {
+	case <-sub.Ready():
+		t.Error("expecting not ready after close")
+	default:
+	}
+}
+
+func publishOnNil(t *testing.T) {
+	var b *Broadcaster
+	b.Publish(stock(1, 1))
+}
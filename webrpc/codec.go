@@ -0,0 +1,44 @@
+package webrpc
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	// the gRPC server picks codecs by the content subtype, "application/grpc+json" is served by jsonCodec
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+var (
+	jsonMarshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	jsonUnmarshaler = protojson.UnmarshalOptions{}
+)
+
+// jsonCodec of messages in the proto3 JSON mapping, same as the REST gateway
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto.Message", v)
+	}
+
+	return jsonMarshaler.Marshal(msg)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+
+	return jsonUnmarshaler.Unmarshal(data, msg)
+}
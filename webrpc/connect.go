@@ -0,0 +1,233 @@
+package webrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// connectCodes are the Connect names of gRPC codes
+var connectCodes = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+	codes.Unauthenticated:    "unauthenticated",
+}
+
+// connectHTTPStatus of unary errors by the Connect protocol
+var connectHTTPStatus = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+type connectErrorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type connectError struct {
+	Code    string               `json:"code"`
+	Message string               `json:"message,omitempty"`
+	Details []connectErrorDetail `json:"details,omitempty"`
+}
+
+type connectEndStream struct {
+	Error    *connectError `json:"error,omitempty"`
+	Metadata http.Header   `json:"metadata,omitempty"`
+}
+
+func newConnectError(st grpcStatus) *connectError {
+	e := &connectError{Code: connectCodes[st.code], Message: st.message}
+	if e.Code == "" {
+		e.Code = connectCodes[codes.Unknown]
+	}
+
+	s := &spb.Status{}
+	if len(st.details) == 0 || proto.Unmarshal(st.details, s) != nil {
+		return e
+	}
+
+	for _, d := range s.Details {
+		e.Details = append(e.Details, connectErrorDetail{
+			Type:  d.TypeUrl[strings.LastIndexByte(d.TypeUrl, '/')+1:],
+			Value: base64.RawStdEncoding.EncodeToString(d.Value),
+		})
+	}
+	return e
+}
+
+func writeConnectError(w http.ResponseWriter, st grpcStatus) {
+	code, ok := connectHTTPStatus[st.code]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(newConnectError(st))
+}
+
+// grpcTimeout of the Connect timeout header, it is empty if r has no timeout
+func grpcTimeout(r *http.Request) (string, bool) {
+	v := r.Header.Get("Connect-Timeout-Ms")
+	if v == "" {
+		return "", true
+	}
+
+	// gRPC timeouts have at most 8 digits
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 || len(v) > 10 {
+		return "", false
+	}
+	if ms > 99999999 {
+		return strconv.FormatInt(ms/1000, 10) + "S", true
+	}
+	return strconv.FormatInt(ms, 10) + "m", true
+}
+
+// connectRequest of r translated to gRPC, Connect compression is not supported
+func connectRequest(w http.ResponseWriter, r *http.Request, codec, encodingHeader string, body io.Reader) (*http.Request, bool) {
+	if enc := r.Header.Get(encodingHeader); enc != "" && enc != "identity" {
+		writeConnectError(w, grpcStatus{code: codes.Unimplemented, message: "unsupported compression " + enc})
+		return nil, false
+	}
+
+	timeout, ok := grpcTimeout(r)
+	if !ok {
+		writeConnectError(w, grpcStatus{code: codes.InvalidArgument, message: "invalid Connect-Timeout-Ms"})
+		return nil, false
+	}
+
+	g := grpcRequest(r, codec, body)
+	g.Header.Del(encodingHeader)
+	g.Header.Del("Connect-Timeout-Ms")
+	if timeout != "" {
+		g.Header.Set("Grpc-Timeout", timeout)
+	}
+	return g, true
+}
+
+// serveConnect translates a Connect unary request to gRPC, the body is a single message without
+// frame. Trailers are sent as headers prefixed by "Trailer-", errors are sent as JSON with the
+// HTTP status of their code
+func (h *handler) serveConnect(w http.ResponseWriter, r *http.Request, codec string) {
+	msg, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize+1))
+	if err != nil {
+		writeConnectError(w, grpcStatus{code: codes.Canceled, message: err.Error()})
+		return
+	}
+
+	if len(msg) > maxMessageSize {
+		writeConnectError(w, grpcStatus{code: codes.ResourceExhausted, message: "message larger than " + strconv.Itoa(maxMessageSize) + " bytes"})
+		return
+	}
+
+	g, ok := connectRequest(w, r, codec, "Content-Encoding", bytes.NewReader(frame(0, msg)))
+	if !ok {
+		return
+	}
+
+	var md http.Header
+	var resp bytes.Buffer
+	gw := newResponseWriter(r)
+	gw.sendHeader = func(h http.Header) { md = h }
+	gw.write = func(p []byte) { resp.Write(p) }
+	h.grpc.ServeHTTP(gw, g)
+
+	st := gw.status()
+	for k, vv := range md {
+		w.Header()[k] = vv
+	}
+	for k, vv := range st.trailer {
+		w.Header()["Trailer-"+k] = vv
+	}
+
+	if st.code != codes.OK {
+		writeConnectError(w, st)
+		return
+	}
+
+	out := resp.Bytes()
+	if len(out) >= frameHeaderLen {
+		n := int(binary.BigEndian.Uint32(out[1:frameHeaderLen]))
+		if frameHeaderLen+n <= len(out) {
+			out = out[frameHeaderLen : frameHeaderLen+n]
+		}
+	}
+	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// serveConnectStream translates a Connect streaming request to gRPC, envelopes are the same as gRPC
+// frames but the status and trailers are sent as JSON in a last envelope flagged connectEndStreamFlag
+func (h *handler) serveConnectStream(w http.ResponseWriter, r *http.Request, codec string) {
+	g, ok := connectRequest(w, r, codec, "Connect-Content-Encoding", r.Body)
+	if !ok {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	gw := newResponseWriter(r)
+	gw.sendHeader = func(md http.Header) {
+		for k, vv := range md {
+			w.Header()[k] = vv
+		}
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusOK)
+	}
+	gw.write = func(p []byte) { w.Write(p) }
+	gw.flush = func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	h.grpc.ServeHTTP(gw, g)
+
+	st := gw.status()
+	end := connectEndStream{}
+	if len(st.trailer) > 0 {
+		end.Metadata = st.trailer
+	}
+	if st.code != codes.OK {
+		end.Error = newConnectError(st)
+	}
+
+	b, _ := json.Marshal(end)
+	w.Write(frame(connectEndStreamFlag, b))
+}
@@ -0,0 +1,158 @@
+package webrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	pb "tomshop/grpc"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestConnect(t *testing.T) {
+	t.Run("expecting unary JSON call with trailers in headers", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		req, _ := http.NewRequest(http.MethodPost, hs.URL+"/tomshop.v1.TomShop/ListInventories", strings.NewReader(`{"productIDs":["11",12]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Connect-Protocol-Version", "1")
+		req.Header.Set("Connect-Timeout-Ms", "5000")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			tt.Fatal("unexpected response", resp.StatusCode, string(body))
+		}
+
+		out := &pb.ListInventoriesResponse{}
+		if err := protojson.Unmarshal(body, out); err != nil || !proto.Equal(out, &pb.ListInventoriesResponse{Inventories: []*pb.Inventory{
+			{ProductID: 11, StockCount: 110},
+			{ProductID: 12, StockCount: 120},
+		}}) {
+			tt.Error("unexpected body", string(body))
+		}
+
+		if resp.Header.Get("Trailer-X-Served-By") != "stub" {
+			tt.Error("unexpected headers", resp.Header)
+		}
+	})
+
+	t.Run("expecting unary proto call", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{
+			makeOrder: func(_ context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
+				return &pb.OrderResponse{Successful: true, Total: in.Purchases[0].Quantity * 100}, nil
+			},
+		}, CORS{})
+		defer stop()
+
+		in, _ := proto.Marshal(&pb.OrderRequest{Purchases: []*pb.Order{{ProductID: 11, Quantity: 2}}})
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/MakeOrder", "application/proto", bytes.NewReader(in))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		out := &pb.OrderResponse{}
+		if err := proto.Unmarshal(body, out); err != nil || resp.StatusCode != http.StatusOK || !out.Successful || out.Total != 200 {
+			tt.Error("unexpected response", resp.StatusCode, out, err)
+		}
+	})
+
+	t.Run("expecting unary error as JSON with HTTP status", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{
+			makeOrder: func(context.Context, *pb.OrderRequest) (*pb.OrderResponse, error) {
+				return nil, status.Error(codes.FailedPrecondition, "out of stock: 100%")
+			},
+		}, CORS{})
+		defer stop()
+
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/MakeOrder", "application/json", strings.NewReader(`{}`))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		e := connectError{}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			tt.Fatal(err)
+		}
+
+		if resp.StatusCode != http.StatusBadRequest || e.Code != "failed_precondition" || e.Message != "out of stock: 100%" {
+			tt.Error("unexpected error", resp.StatusCode, e)
+		}
+	})
+
+	t.Run("expecting compressed request refused", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		req, _ := http.NewRequest(http.MethodPost, hs.URL+"/tomshop.v1.TomShop/ListInventories", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "br")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNotImplemented {
+			tt.Error("unexpected status", resp.StatusCode)
+		}
+	})
+
+	t.Run("expecting server stream ended by end stream envelope", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		in := frame(0, []byte(`{"productIDs":["11","12"]}`))
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/WatchInventory", "application/connect+json", bytes.NewReader(in))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		flags, msgs := readFrames(tt, body)
+		if resp.StatusCode != http.StatusOK || len(msgs) != 3 || flags[2] != connectEndStreamFlag {
+			tt.Fatal("unexpected response", resp.StatusCode, flags, string(body))
+		}
+
+		inv := &pb.Inventory{}
+		if err := protojson.Unmarshal(msgs[0], inv); err != nil || inv.ProductID != 11 || inv.StockCount != 110 || string(msgs[2]) != `{}` {
+			tt.Error("unexpected messages", string(msgs[0]), string(msgs[2]))
+		}
+	})
+
+	t.Run("expecting stream error in end stream envelope", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/WatchNothing", "application/connect+proto", bytes.NewReader(frame(0, nil)))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		flags, msgs := readFrames(tt, body)
+		end := connectEndStream{}
+		if len(msgs) != 1 || flags[0] != connectEndStreamFlag || json.Unmarshal(msgs[0], &end) != nil ||
+			end.Error == nil || end.Error.Code != "unimplemented" {
+			tt.Error("unexpected response", flags, string(body))
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package webrpc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// corsAllowedHeaders are sent by gRPC-Web and Connect clients
+	corsAllowedHeaders = []string{
+		"Content-Type", "Authorization", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout",
+		"Connect-Protocol-Version", "Connect-Timeout-Ms",
+	}
+	// corsExposedHeaders are read by gRPC-Web and Connect clients
+	corsExposedHeaders = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+)
+
+// CORS policy of browsers calling from other origins, no cross origin request is allowed by default
+type CORS struct {
+	// AllowedOrigins like "https://shop.example.com", "*" allows any origin
+	AllowedOrigins []string
+	// AllowedHeaders are allowed besides the headers of gRPC-Web and Connect
+	AllowedHeaders []string
+	// MaxAge preflight responses are cached for, browser default if 0
+	MaxAge time.Duration
+}
+
+func (c CORS) allowed(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handle sets CORS headers of r, it returns true if r is a preflight request and answered
+func (c CORS) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if !c.allowed(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	h.Set("Access-Control-Allow-Origin", origin)
+	if !preflight {
+		h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		return false
+	}
+
+	h.Set("Access-Control-Allow-Methods", http.MethodPost)
+	h.Set("Access-Control-Allow-Headers", strings.Join(append(corsAllowedHeaders, c.AllowedHeaders...), ", "))
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,67 @@
+package webrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// serveGRPCWeb translates gRPC-Web to gRPC, the frames are the same but trailers are sent in a last
+// frame flagged grpcWebTrailerFlag since browsers cannot read HTTP trailers. gRPC-Web text
+// frames are base64 encoded, every flush is encoded on its own
+func (h *handler) serveGRPCWeb(w http.ResponseWriter, r *http.Request, rt route) {
+	flusher, _ := w.(http.Flusher)
+	contentType := r.Header.Get("Content-Type")
+	text := rt.protocol == protocolGRPCWebText
+
+	var body io.Reader = r.Body
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+
+	var buf bytes.Buffer
+	send := func() {
+		if text {
+			fmt.Fprint(w, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		} else {
+			w.Write(buf.Bytes())
+		}
+		buf.Reset()
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	gw := newResponseWriter(r)
+	gw.sendHeader = func(md http.Header) {
+		for k, vv := range md {
+			w.Header()[k] = vv
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+	}
+	gw.write = func(p []byte) { buf.Write(p) }
+	gw.flush = send
+	h.grpc.ServeHTTP(gw, grpcRequest(r, rt.codec, body))
+
+	st := gw.status()
+	var trailer strings.Builder
+	fmt.Fprintf(&trailer, "grpc-status: %d\r\n", st.code)
+	if m := gw.header.Get("Grpc-Message"); m != "" {
+		fmt.Fprintf(&trailer, "grpc-message: %s\r\n", m)
+	}
+	if d := gw.header.Get("Grpc-Status-Details-Bin"); d != "" {
+		fmt.Fprintf(&trailer, "grpc-status-details-bin: %s\r\n", d)
+	}
+	for k, vv := range st.trailer {
+		for _, v := range vv {
+			fmt.Fprintf(&trailer, "%s: %s\r\n", strings.ToLower(k), v)
+		}
+	}
+
+	buf.Write(frame(grpcWebTrailerFlag, []byte(trailer.String())))
+	send()
+}
@@ -0,0 +1,128 @@
+package webrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	pb "tomshop/grpc"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// readFrames of a gRPC-Web or Connect response body
+func readFrames(t *testing.T, body []byte) (flags []byte, msgs [][]byte) {
+	for len(body) > 0 {
+		if len(body) < frameHeaderLen {
+			t.Fatal("truncated frame header", body)
+		}
+
+		n := int(binary.BigEndian.Uint32(body[1:frameHeaderLen]))
+		if len(body) < frameHeaderLen+n {
+			t.Fatal("truncated frame", body)
+		}
+		flags = append(flags, body[0])
+		msgs = append(msgs, body[frameHeaderLen:frameHeaderLen+n])
+		body = body[frameHeaderLen+n:]
+	}
+
+	return flags, msgs
+}
+
+// decodeText of gRPC-Web, every flush is padded so it is decoded quantum by quantum
+func decodeText(t *testing.T, text []byte) []byte {
+	var out []byte
+	for i := 0; i+4 <= len(text); i += 4 {
+		b, err := base64.StdEncoding.DecodeString(string(text[i : i+4]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, b...)
+	}
+
+	return out
+}
+
+func TestGRPCWeb(t *testing.T) {
+	t.Run("expecting unary call with trailers in last frame", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		in, _ := proto.Marshal(&pb.ListInventoriesRequest{ProductIDs: []int64{11, 12}})
+		req, _ := http.NewRequest(http.MethodPost, hs.URL+"/tomshop.v1.TomShop/ListInventories", bytes.NewReader(frame(0, in)))
+		req.Header.Set("Content-Type", "application/grpc-web+proto")
+		req.Header.Set("X-Grpc-Web", "1")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/grpc-web+proto" {
+			tt.Fatal("unexpected response", resp.StatusCode, resp.Header, string(body))
+		}
+
+		flags, msgs := readFrames(tt, body)
+		if len(msgs) != 2 || flags[0] != 0 || flags[1] != grpcWebTrailerFlag {
+			tt.Fatal("unexpected frames", flags)
+		}
+
+		out := &pb.ListInventoriesResponse{}
+		if err := proto.Unmarshal(msgs[0], out); err != nil || len(out.Inventories) != 2 || out.Inventories[1].StockCount != 120 {
+			tt.Error("unexpected message", out, err)
+		}
+
+		trailer := string(msgs[1])
+		if !strings.Contains(trailer, "grpc-status: 0\r\n") || !strings.Contains(trailer, "x-served-by: stub\r\n") {
+			tt.Error("unexpected trailer", trailer)
+		}
+	})
+
+	t.Run("expecting server stream in text", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		in, _ := proto.Marshal(&pb.WatchInventoryRequest{ProductIDs: []int64{11, 12}})
+		body := base64.StdEncoding.EncodeToString(frame(0, in))
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/WatchInventory", "application/grpc-web-text", strings.NewReader(body))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		text, _ := io.ReadAll(resp.Body)
+		flags, msgs := readFrames(tt, decodeText(tt, text))
+		if len(msgs) != 3 || flags[2] != grpcWebTrailerFlag || !strings.Contains(string(msgs[2]), "grpc-status: 0\r\n") {
+			tt.Fatal("unexpected frames", flags, string(text))
+		}
+
+		for i, id := range []int64{11, 12} {
+			inv := &pb.Inventory{}
+			if err := proto.Unmarshal(msgs[i], inv); err != nil || inv.ProductID != id || inv.StockCount != id*10 {
+				tt.Error("unexpected message", inv, err)
+			}
+		}
+	})
+
+	t.Run("expecting error status in trailers", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/ChangeNothing", "application/grpc-web", bytes.NewReader(frame(0, nil)))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		flags, msgs := readFrames(tt, body)
+		if len(msgs) != 1 || flags[0] != grpcWebTrailerFlag || !strings.Contains(string(msgs[0]), "grpc-status: 12\r\n") {
+			tt.Error("unexpected frames", flags, string(body))
+		}
+	})
+}
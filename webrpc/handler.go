@@ -0,0 +1,90 @@
+// Package webrpc serves a gRPC server to native gRPC clients over h2c and to browsers over
+// gRPC-Web and Connect on the same listener. Requests are routed by content type, gRPC-Web and
+// Connect requests are translated to gRPC and served by grpc.Server.ServeHTTP so interceptors
+// and services see them the same as native calls
+package webrpc
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// maxMessageSize read from a Connect unary request, same as the gRPC server default
+const maxMessageSize = 4 << 20
+
+// protocol a request is translated from
+type protocol int
+
+const (
+	protocolGRPC protocol = iota
+	protocolGRPCWeb
+	protocolGRPCWebText
+	protocolConnect
+	protocolConnectStream
+)
+
+// route of a content type to the protocol and the codec translated to, "proto" or "json"
+type route struct {
+	protocol protocol
+	codec    string
+}
+
+var routes = map[string]route{
+	"application/grpc":                route{protocolGRPC, "proto"},
+	"application/grpc+proto":          route{protocolGRPC, "proto"},
+	"application/grpc+json":           route{protocolGRPC, "json"},
+	"application/grpc-web":            route{protocolGRPCWeb, "proto"},
+	"application/grpc-web+proto":      route{protocolGRPCWeb, "proto"},
+	"application/grpc-web+json":       route{protocolGRPCWeb, "json"},
+	"application/grpc-web-text":       route{protocolGRPCWebText, "proto"},
+	"application/grpc-web-text+proto": route{protocolGRPCWebText, "proto"},
+	"application/proto":               route{protocolConnect, "proto"},
+	"application/json":                route{protocolConnect, "json"},
+	"application/connect+proto":       route{protocolConnectStream, "proto"},
+	"application/connect+json":        route{protocolConnectStream, "json"},
+}
+
+type handler struct {
+	grpc *grpc.Server
+	cors CORS
+}
+
+// NewHandler serves s by every protocol of the package, cross origin requests are allowed by cors
+func NewHandler(s *grpc.Server, cors CORS) http.Handler {
+	return h2c.NewHandler(&handler{grpc: s, cors: cors}, &http2.Server{})
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.cors.handle(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	rt, ok := routes[strings.ToLower(mediaType)]
+	if err != nil || !ok {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	switch rt.protocol {
+	case protocolGRPC:
+		h.grpc.ServeHTTP(w, r)
+	case protocolGRPCWeb, protocolGRPCWebText:
+		h.serveGRPCWeb(w, r, rt)
+	case protocolConnect:
+		h.serveConnect(w, r, rt.codec)
+	case protocolConnectStream:
+		h.serveConnectStream(w, r, rt.codec)
+	}
+}
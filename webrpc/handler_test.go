@@ -0,0 +1,156 @@
+package webrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pb "tomshop/grpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// stubServer serves only what the tests call
+type stubServer struct {
+	pb.UnimplementedTomShopServer
+	makeOrder func(context.Context, *pb.OrderRequest) (*pb.OrderResponse, error)
+}
+
+func (s *stubServer) MakeOrder(ctx context.Context, in *pb.OrderRequest) (*pb.OrderResponse, error) {
+	return s.makeOrder(ctx, in)
+}
+
+func (s *stubServer) ListInventories(ctx context.Context, in *pb.ListInventoriesRequest) (*pb.ListInventoriesResponse, error) {
+	grpc.SetTrailer(ctx, metadata.Pairs("x-served-by", "stub"))
+	resp := &pb.ListInventoriesResponse{}
+	for _, id := range in.ProductIDs {
+		resp.Inventories = append(resp.Inventories, &pb.Inventory{ProductID: id, StockCount: id * 10})
+	}
+	return resp, nil
+}
+
+func (s *stubServer) WatchInventory(in *pb.WatchInventoryRequest, stream pb.TomShop_WatchInventoryServer) error {
+	for _, id := range in.ProductIDs {
+		if err := stream.Send(&pb.Inventory{ProductID: id, StockCount: id * 10}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newServer(srv pb.TomShopServer, cors CORS) (*httptest.Server, func()) {
+	s := grpc.NewServer()
+	pb.RegisterTomShopServer(s, srv)
+	hs := httptest.NewServer(NewHandler(s, cors))
+
+	return hs, func() {
+		hs.Close()
+		s.Stop()
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("expecting native gRPC over h2c", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		cc, err := grpc.NewClient(strings.TrimPrefix(hs.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		defer cc.Close()
+
+		var trailer metadata.MD
+		resp, err := pb.NewTomShopClient(cc).ListInventories(context.Background(), &pb.ListInventoriesRequest{
+			ProductIDs: []int64{11},
+		}, grpc.Trailer(&trailer))
+		if err != nil {
+			tt.Fatal(err)
+		}
+
+		if len(resp.Inventories) != 1 || resp.Inventories[0].StockCount != 110 {
+			tt.Error("unexpected response", resp)
+		}
+
+		if v := trailer.Get("x-served-by"); len(v) != 1 || v[0] != "stub" {
+			tt.Error("unexpected trailer", trailer)
+		}
+	})
+
+	t.Run("expecting unsupported media type", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{})
+		defer stop()
+
+		resp, err := http.Post(hs.URL+"/tomshop.v1.TomShop/ListInventories", "text/plain", strings.NewReader("11"))
+		if err != nil {
+			tt.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			tt.Error("unexpected status", resp.StatusCode)
+		}
+	})
+}
+
+func TestCORS(t *testing.T) {
+	cors := CORS{AllowedOrigins: []string{"https://shop.example.com"}, AllowedHeaders: []string{"X-Customer"}}
+	preflight := func(hs *httptest.Server, origin string) *http.Response {
+		req, _ := http.NewRequest(http.MethodOptions, hs.URL+"/tomshop.v1.TomShop/ListInventories", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-customer")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("expecting preflight of allowed origin", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, cors)
+		defer stop()
+
+		resp := preflight(hs, "https://shop.example.com")
+		if resp.StatusCode != http.StatusNoContent ||
+			resp.Header.Get("Access-Control-Allow-Origin") != "https://shop.example.com" ||
+			!strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "X-Customer") {
+			tt.Error("unexpected preflight", resp.StatusCode, resp.Header)
+		}
+	})
+
+	t.Run("expecting preflight of other origin forbidden", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, cors)
+		defer stop()
+
+		resp := preflight(hs, "https://evil.example.com")
+		if resp.StatusCode != http.StatusForbidden || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+			tt.Error("unexpected preflight", resp.StatusCode, resp.Header)
+		}
+	})
+
+	t.Run("expecting any origin allowed by wildcard", func(tt *testing.T) {
+		hs, stop := newServer(&stubServer{}, CORS{AllowedOrigins: []string{"*"}})
+		defer stop()
+
+		req, _ := http.NewRequest(http.MethodPost, hs.URL+"/tomshop.v1.TomShop/ListInventories", strings.NewReader(`{"productIDs":["11"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", "https://other.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK ||
+			resp.Header.Get("Access-Control-Allow-Origin") != "https://other.example.com" ||
+			!strings.Contains(resp.Header.Get("Access-Control-Expose-Headers"), "Grpc-Status") {
+			tt.Error("unexpected response", resp.StatusCode, resp.Header)
+		}
+	})
+}
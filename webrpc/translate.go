@@ -0,0 +1,164 @@
+package webrpc
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// frameHeaderLen of gRPC messages and Connect envelopes, a flags byte then the message length
+	frameHeaderLen = 5
+	// grpcWebTrailerFlag marks the last gRPC-Web frame carrying trailers
+	grpcWebTrailerFlag = 0x80
+	// connectEndStreamFlag marks the last Connect envelope carrying the status and trailers
+	connectEndStreamFlag = 0x02
+)
+
+func frame(flags byte, msg []byte) []byte {
+	b := make([]byte, frameHeaderLen+len(msg))
+	b[0] = flags
+	binary.BigEndian.PutUint32(b[1:], uint32(len(msg)))
+	copy(b[frameHeaderLen:], msg)
+	return b
+}
+
+// grpcRequest of r sent to grpc.Server.ServeHTTP, it reads the gRPC frames of body
+func grpcRequest(r *http.Request, codec string, body io.Reader) *http.Request {
+	g := r.WithContext(r.Context())
+	g.ProtoMajor, g.ProtoMinor, g.Proto = 2, 0, "HTTP/2.0"
+	g.Header = http.Header{}
+	for k, vv := range r.Header {
+		g.Header[k] = vv
+	}
+	g.Header.Set("Content-Type", "application/grpc+"+codec)
+	g.Header.Del("Content-Length")
+	g.ContentLength = -1
+	g.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+
+	return g
+}
+
+// responseWriter receives the response of grpc.Server.ServeHTTP for translation, sendHeader is
+// called with the response metadata before the first message and write with every message.
+// The server writes status and trailers to the header after its last message, they are read by
+// status once ServeHTTP returns. Every call is made by the goroutine of ServeHTTP
+type responseWriter struct {
+	header     http.Header
+	headerSent bool
+	closed     chan bool
+
+	sendHeader func(md http.Header)
+	write      func(p []byte)
+	flush      func()
+}
+
+func newResponseWriter(r *http.Request) *responseWriter {
+	w := &responseWriter{header: http.Header{}, closed: make(chan bool, 1)}
+	go func() {
+		// the context of a request is canceled once it is served
+		<-r.Context().Done()
+		w.closed <- true
+	}()
+
+	return w
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(int) {
+	if w.headerSent {
+		return
+	}
+	w.headerSent = true
+
+	md := http.Header{}
+	for k, vv := range w.header {
+		switch k {
+		case "Content-Type", "Trailer", "Date", "Grpc-Encoding":
+			continue
+		}
+		md[k] = vv
+	}
+	if w.sendHeader != nil {
+		w.sendHeader(md)
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.write != nil {
+		w.write(p)
+	}
+	return len(p), nil
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	if w.flush != nil {
+		w.flush()
+	}
+}
+
+// CloseNotify is required by grpc.Server.ServeHTTP to cancel calls of gone clients
+func (w *responseWriter) CloseNotify() <-chan bool {
+	return w.closed
+}
+
+// grpcStatus written by the server after its last message
+type grpcStatus struct {
+	code    codes.Code
+	message string
+	// details is the serialized google.rpc.Status
+	details []byte
+	trailer http.Header
+}
+
+func (w *responseWriter) status() grpcStatus {
+	s := grpcStatus{code: codes.Unknown, trailer: http.Header{}}
+	if c, err := strconv.Atoi(w.header.Get("Grpc-Status")); err == nil {
+		s.code = codes.Code(c)
+	} else {
+		s.message = "missing grpc-status in response"
+	}
+
+	if m := w.header.Get("Grpc-Message"); m != "" {
+		// percent encoded by the server
+		if s.message, _ = url.PathUnescape(m); s.message == "" {
+			s.message = m
+		}
+	}
+
+	if d := w.header.Get("Grpc-Status-Details-Bin"); d != "" {
+		s.details, _ = decodeBinary(d)
+	}
+
+	for k, vv := range w.header {
+		if strings.HasPrefix(k, http2.TrailerPrefix) {
+			s.trailer[textproto.CanonicalMIMEHeaderKey(strings.TrimPrefix(k, http2.TrailerPrefix))] = vv
+		}
+	}
+
+	return s
+}
+
+// decodeBinary value of a -bin metadata, padded or not
+func decodeBinary(v string) ([]byte, error) {
+	if len(v)%4 == 0 {
+		return base64.StdEncoding.DecodeString(v)
+	}
+	return base64.RawStdEncoding.DecodeString(v)
+}